@@ -2,21 +2,99 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Define constants.
+const (
+	JSONRPC_VERSION_1 = "1.0"
+	JSONRPC_VERSION_2 = "2.0"
+)
+
+// These are the error codes abec reports for heights beyond the tip, unknown txs and rejected tx submissions.
+const (
+	RPC_ERROR_OUT_OF_RANGE        = -1
+	RPC_ERROR_NO_TX_INFO          = -5
+	RPC_ERROR_DESERIALIZATION     = -22
+	RPC_ERROR_TX_ERROR            = -25
+	RPC_ERROR_TX_REJECTED         = -26
+	RPC_ERROR_TX_ALREADY_IN_CHAIN = -27
+)
+
+// MAX_FEE_ESTIMATE_CONF_TARGET is the depth of the abec fee estimator, the most blocks it estimates fees for.
+const MAX_FEE_ESTIMATE_CONF_TARGET = 25
+
+type AuthStrategy int
+
+const (
+	BASIC_AUTH AuthStrategy = iota
+	BEARER_TOKEN_AUTH
+	HEADER_AUTH
+)
+
+func (strategy AuthStrategy) String() string {
+	switch strategy {
+	case BASIC_AUTH:
+		return "basic"
+	case BEARER_TOKEN_AUTH:
+		return "bearer-token"
+	case HEADER_AUTH:
+		return "header"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrUnauthorized is returned when the node rejects the credentials of a request.
+var ErrUnauthorized = errors.New("rpc request is not authorized")
+
+// ErrHeightNotFound is returned when a block is requested by a height beyond the current tip.
+var ErrHeightNotFound = errors.New("block height not found")
+
 // Define data types.
 type AbecRPCClient struct {
-	httpClient *http.Client
-	endpoint   string
-	username   string
-	password   string
+	// lastRequestID is accessed atomically, and kept first for 64-bit alignment on 32-bit platforms.
+	lastRequestID  uint64
+	httpClient     *http.Client
+	ownsTransport  bool
+	endpoint       string
+	username       string
+	password       string
+	authStrategy   AuthStrategy
+	authHeader     string
+	authValue      string
+	jsonRPCVersion string
+	requestSlots   chan struct{}
+	retryPolicy    RetryPolicy
+	clock          Clock
+	wsPingInterval time.Duration
+}
+
+// RetryPolicy controls how calls are retried after transport errors and 5xx HTTP statuses. The delay before
+// retry n is BaseDelay*2^(n-1) capped at MaxDelay, reduced by a random fraction of up to Jitter (0 to 1).
+// The zero value makes a single attempt.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
 }
 
+type AbecRPCClientOption func(client *AbecRPCClient)
+
 type AbecJSONRPCRequest struct {
 	JSONRPC string        `json:"jsonrpc"`
 	Method  string        `json:"method"`
@@ -25,9 +103,28 @@ type AbecJSONRPCRequest struct {
 }
 
 type AbecJSONRPCResponse struct {
-	Result json.RawMessage `json:"result"`
-	Error  json.RawMessage `json:"error"`
-	ID     string          `json:"id"`
+	JSONRPC string          `json:"jsonrpc,omitempty"`
+	Result  json.RawMessage `json:"result"`
+	Error   json.RawMessage `json:"error"`
+	ID      AbecJSONRPCID   `json:"id"`
+}
+
+// AbecJSONRPCID accepts both string and numeric ids, since some nodes echo back a numeric id.
+type AbecJSONRPCID string
+
+type AbecJSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// AbecRPCError is the error returned for a JSON-RPC error result, so that callers can tell rejections apart
+// by code with errors.As instead of matching the message. A bare string error has a zero Code.
+type AbecRPCError struct {
+	Method  string
+	Code    int
+	Message string
+	Data    json.RawMessage
 }
 
 type AbecChainInfo struct {
@@ -71,6 +168,25 @@ type AbecBlock struct {
 	RawTxs        []*AbecTx `json:"rawTx"`
 }
 
+// AbecBlockHeader is the verbose result of getblockheader. Nonce is the 32-bit nonce field of the header, as
+// getblockheader reports it, even for ethash blocks, whose proof of work uses a separate 64-bit nonce.
+// abec does not report the seal hash, so GetBlockHeader computes SealHash from the serialized header.
+type AbecBlockHeader struct {
+	Height        int64   `json:"height"`
+	Confirmations int64   `json:"confirmations"`
+	Version       int64   `json:"version"`
+	VersionHex    string  `json:"versionHex"`
+	Time          int64   `json:"time"`
+	Nonce         uint64  `json:"nonce"`
+	Difficulty    float64 `json:"difficulty"`
+	BlockHash     string  `json:"hash"`
+	PrevBlockHash string  `json:"previousblockhash"`
+	NextBlockHash string  `json:"nextblockhash"`
+	MerkleRoot    string  `json:"merkleroot"`
+	Bits          string  `json:"bits"`
+	SealHash      string  `json:"sealhash"`
+}
+
 type AbecTx struct {
 	Hex           string        `json:"hex"`
 	TxID          string        `json:"txid"`
@@ -95,12 +211,20 @@ type AbecTxVin struct {
 }
 
 type AbecUTXORing struct {
-	Version     int64    `json:"version"`
-	BlockHashes []string `json:"blockhashs"`
-	OutPoints   []struct {
-		TxHash string `json:"txid"`
-		Index  int64  `json:"index"`
-	} `json:"outpoints"`
+	Version     int64           `json:"version"`
+	BlockHashes []string        `json:"blockhashs"`
+	OutPoints   []*AbecOutPoint `json:"outpoints"`
+}
+
+type AbecUTXORingEntry struct {
+	RingBlockHeight       int64    `json:"ringblockheight"`
+	ConsumedSerialNumbers []string `json:"consumedserialnumbers"`
+	IsCoinbase            bool     `json:"coinbase"`
+}
+
+type AbecOutPoint struct {
+	TxHash string `json:"txid"`
+	Index  int64  `json:"index"`
 }
 
 type AbecTxVout struct {
@@ -108,76 +232,489 @@ type AbecTxVout struct {
 	Script string `json:"script"`
 }
 
+// Define methods for AbecRPCError.
+func (e *AbecRPCError) Error() string {
+	return fmt.Sprintf("abec.%s: %s", e.Method, e.description())
+}
+
+func (e *AbecRPCError) description() string {
+	if e.Code == 0 {
+		return e.Message
+	}
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("code %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+
+	return fmt.Sprintf("code %d: %s", e.Code, e.Message)
+}
+
+// IsTxAlreadyKnown reports whether the submission of a tx was rejected because the node already has it,
+// either in its mempool or in the chain, which a wallet resubmitting its own tx can treat as success.
+func (e *AbecRPCError) IsTxAlreadyKnown() bool {
+	return e.Code == RPC_ERROR_TX_ALREADY_IN_CHAIN ||
+		(e.Code == RPC_ERROR_TX_REJECTED && strings.Contains(e.Message, "already have transaction"))
+}
+
+// IsTxNotFound reports whether a tx lookup failed because the node has no information about the tx, neither in
+// its mempool nor in the chain.
+func (e *AbecRPCError) IsTxNotFound() bool {
+	return e.Code == RPC_ERROR_NO_TX_INFO
+}
+
+// IsHeightOutOfRange reports whether a block lookup by height failed because the height is beyond the tip.
+// RPC_ERROR_OUT_OF_RANGE is also the code of other failures, which only the description tells apart.
+func (e *AbecRPCError) IsHeightOutOfRange() bool {
+	return e.Code == RPC_ERROR_OUT_OF_RANGE && strings.Contains(e.Message, "Block number out of range")
+}
+
+// IsNoFeeEstimate reports whether estimatefee failed because the node cannot estimate fees at all, since fee
+// estimation is disabled or it has not yet observed enough blocks. Abec reports these like an invalid target,
+// as internal errors, and only tells them apart by the description.
+func (e *AbecRPCError) IsNoFeeEstimate() bool {
+	return strings.Contains(e.Message, "not enough blocks have been observed") ||
+		strings.Contains(e.Message, "Fee estimation disabled")
+}
+
+// IsRingRejection reports whether the node took a submitted tx for an orphan because the ring of an input is
+// unknown to it, as when the ring blocks left the main chain after the tx was built. abec reports orphans with
+// RPC_ERROR_TX_ERROR and only tells them apart from other tx errors by the description.
+func (e *AbecRPCError) IsRingRejection() bool {
+	return e.Code == RPC_ERROR_TX_ERROR && strings.Contains(e.Message, "orphan transaction")
+}
+
+// IsSerialNumberConflict reports whether the serial number of an input is already revealed by another tx in
+// the node's mempool, that is, the coin is already being spent.
+func (e *AbecRPCError) IsSerialNumberConflict() bool {
+	return e.Code == RPC_ERROR_TX_REJECTED && strings.Contains(e.Message, "already spent by transaction")
+}
+
+// Define methods for RetryPolicy.
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < attempt && (policy.MaxDelay <= 0 || delay < policy.MaxDelay); i++ {
+		delay *= 2
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if policy.Jitter > 0 {
+		delay -= time.Duration(float64(delay) * math.Min(policy.Jitter, 1) * rand.Float64())
+	}
+
+	return delay
+}
+
+// Define methods for AbecOutPoint.
+func (outPoint *AbecOutPoint) CoinID() (*CoinID, error) {
+	txHash, err := NewTxidFromHex(outPoint.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCoinIDFromRPCIndex(txHash, outPoint.Index)
+}
+
+// Define methods for AbecJSONRPCID.
+func (id *AbecJSONRPCID) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		*id = ""
+		return nil
+	}
+
+	if trimmed[0] == '"' {
+		var idStr string
+		err := json.Unmarshal(trimmed, &idStr)
+		if err != nil {
+			return err
+		}
+		*id = AbecJSONRPCID(idStr)
+		return nil
+	}
+
+	var idNum json.Number
+	err := json.Unmarshal(trimmed, &idNum)
+	if err != nil {
+		return fmt.Errorf("json-rpc id %s is neither a string nor a number", trimmed)
+	}
+	*id = AbecJSONRPCID(idNum.String())
+	return nil
+}
+
+func (id AbecJSONRPCID) Matches(requestID string) bool {
+	return string(id) == requestID
+}
+
+// Define options for AbecRPCClient.
+func WithJSONRPCVersion(version string) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.jsonRPCVersion = version
+	}
+}
+
+// WithBearerToken authenticates with "Authorization: Bearer <token>" instead of basic auth.
+func WithBearerToken(token string) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.authStrategy = BEARER_TOKEN_AUTH
+		client.authHeader = "Authorization"
+		client.authValue = "Bearer " + token
+	}
+}
+
+// WithAuthHeader authenticates by setting the given header, such as an API key header, instead of basic auth.
+func WithAuthHeader(name string, value string) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.authStrategy = HEADER_AUTH
+		client.authHeader = name
+		client.authValue = value
+	}
+}
+
+func WithClock(clock Clock) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.clock = clock
+	}
+}
+
+// WithHTTPClient makes the client send its requests through a copy of httpClient, such as one with a custom
+// transport. Options like WithTimeout and WithDialTimeout change only the copy, never httpClient itself, so
+// passing http.DefaultClient is safe.
+func WithHTTPClient(httpClient *http.Client) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		if httpClient != nil {
+			httpClientCopy := *httpClient
+			client.httpClient = &httpClientCopy
+			client.ownsTransport = false
+		}
+	}
+}
+
+// WithTimeout bounds how long a whole request may take, including reading the response body. Calls that
+// fetch large blocks need a timeout long enough to download them. Zero means no timeout.
+func WithTimeout(timeout time.Duration) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.httpClient.Timeout = timeout
+	}
+}
+
+// WithDialTimeout bounds how long establishing a connection, including the TLS handshake, may take.
+// Unlike a timeout on the whole request, it does not limit how long a large block takes to download.
+// It has no effect on a custom round-tripper given by WithHTTPClient.
+func WithDialTimeout(timeout time.Duration) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		transport := client.transport()
+		if transport == nil {
+			return
+		}
+		transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout bounds how long to wait for the response headers after the request is written.
+// Reading the response body is not limited by it. It has no effect on a custom round-tripper given by
+// WithHTTPClient.
+func WithResponseHeaderTimeout(timeout time.Duration) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		transport := client.transport()
+		if transport == nil {
+			return
+		}
+		transport.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithWebSocketPingInterval sets how often a websocket connection to the node is pinged. A connection that
+// answers no ping for two intervals is dropped and reconnected. Zero or less keeps WEBSOCKET_PING_INTERVAL.
+func WithWebSocketPingInterval(interval time.Duration) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		if interval > 0 {
+			client.wsPingInterval = interval
+		}
+	}
+}
+
+// WithRetryPolicy retries failed calls according to policy. A JSON-RPC error result is never retried, since
+// the node would give the same answer again, and a tx submission is only retried when the connection could
+// not be made, so that it cannot be sent twice.
+func WithRetryPolicy(policy RetryPolicy) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		client.retryPolicy = policy
+	}
+}
+
+// WithMaxConcurrentRequests bounds the number of in-flight requests across all callers of the client.
+func WithMaxConcurrentRequests(n int) AbecRPCClientOption {
+	return func(client *AbecRPCClient) {
+		if n > 0 {
+			client.requestSlots = make(chan struct{}, n)
+		} else {
+			client.requestSlots = nil
+		}
+	}
+}
+
 // Define methods for AbecRPCClient.
-func NewAbecRPCClient(endpoint string, username string, password string) *AbecRPCClient {
-	return &AbecRPCClient{
-		httpClient: &http.Client{},
-		endpoint:   endpoint,
-		username:   username,
-		password:   password,
+func NewAbecRPCClient(endpoint string, username string, password string, options ...AbecRPCClientOption) *AbecRPCClient {
+	client := &AbecRPCClient{
+		httpClient:     &http.Client{},
+		endpoint:       endpoint,
+		username:       username,
+		password:       password,
+		jsonRPCVersion: JSONRPC_VERSION_1,
+		clock:          SystemClock{},
+		wsPingInterval: WEBSOCKET_PING_INTERVAL,
 	}
+
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+// transport returns the *http.Transport owned by the client, cloning the configured one on first use so that
+// a transport shared with other clients is never modified. It returns nil for a custom round-tripper, which
+// is left alone.
+func (client *AbecRPCClient) transport() *http.Transport {
+	if client.ownsTransport {
+		return client.httpClient.Transport.(*http.Transport)
+	}
+
+	var transport *http.Transport
+	switch roundTripper := client.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = roundTripper.Clone()
+	default:
+		return nil
+	}
+	client.httpClient.Transport = transport
+	client.ownsTransport = true
+
+	return transport
 }
 
-func (client *AbecRPCClient) newRequest(id string, method string, params []interface{}) (*http.Request, error) {
-	jsonReq := &AbecJSONRPCRequest{
-		JSONRPC: "1.0",
+func (client *AbecRPCClient) newJSONRPCRequest(id string, method string, params []interface{}) *AbecJSONRPCRequest {
+	// JSON-RPC 2.0 does not allow null params, so send an empty array instead.
+	if params == nil && client.jsonRPCVersion == JSONRPC_VERSION_2 {
+		params = []interface{}{}
+	}
+
+	return &AbecJSONRPCRequest{
+		JSONRPC: client.jsonRPCVersion,
 		Method:  method,
 		Params:  params,
 		ID:      id,
 	}
-	jsonBody, err := json.Marshal(jsonReq)
+}
+
+// newRequest posts payload, which is either a single JSON-RPC request or a batch of them.
+func (client *AbecRPCClient) newRequest(ctx context.Context, payload interface{}) (*http.Request, error) {
+	jsonBody, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPost, client.endpoint, bytes.NewBuffer(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, client.endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.SetBasicAuth(client.username, client.password)
+	client.setAuth(httpReq)
 
 	return httpReq, nil
 }
 
-func (client *AbecRPCClient) callForBytes(method string, params []interface{}) (Bytes, error) {
-	id := fmt.Sprintf("%d", time.Now().UnixMilli())
-	req, err := client.newRequest(id, method, params)
+func (client *AbecRPCClient) AuthStrategy() AuthStrategy {
+	return client.authStrategy
+}
+
+func (client *AbecRPCClient) setAuth(httpReq *http.Request) {
+	// Exactly one scheme is applied, and the last auth option given at construction wins.
+	switch client.authStrategy {
+	case BEARER_TOKEN_AUTH, HEADER_AUTH:
+		httpReq.Header.Set(client.authHeader, client.authValue)
+	default:
+		httpReq.SetBasicAuth(client.username, client.password)
+	}
+}
+
+func (client *AbecRPCClient) acquireRequestSlot(ctx context.Context) (func(), error) {
+	if client.requestSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case client.requestSlots <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-client.requestSlots
+			})
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// newRequestID returns an id that is unique among the requests of the client, even when they are sent
+// concurrently within the same millisecond.
+func (client *AbecRPCClient) newRequestID() string {
+	return fmt.Sprintf("%d", atomic.AddUint64(&client.lastRequestID, 1))
+}
+
+func (client *AbecRPCClient) send(ctx context.Context, method string, params []interface{}) (string, *http.Response, error) {
+	id := client.newRequestID()
+	LOG.debug("Request(%s): %s(%s)\n", id, method, LOG.value(params))
+	resp, err := client.post(ctx, id, client.newJSONRPCRequest(id, method, params))
+
+	return id, resp, err
+}
+
+func (client *AbecRPCClient) post(ctx context.Context, id string, payload interface{}) (*http.Response, error) {
+	req, err := client.newRequest(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := client.acquireRequestSlot(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	LOG.debug("Request(%s): %s(%+v)\n", id, method, params)
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
+		release()
 		LOG.debug("Response(%s): ERROR(%s)\n", id, err)
 		return nil, err
 	}
+
+	// The request slot is held until the caller is done reading the body.
+	resp.Body = &releasingReadCloser{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (client *AbecRPCClient) callForBytes(ctx context.Context, method string, params []interface{}) (Bytes, error) {
+	var result Bytes
+	err := client.retry(ctx, "abec."+method, func() (bool, error) {
+		var retryable bool
+		var err error
+		result, retryable, err = client.callOnce(ctx, method, params)
+		return retryable, err
+	})
+
+	return result, err
+}
+
+// retry calls attempt until it succeeds, fails with an error it does not report as retryable, or the attempts
+// allowed by the retry policy are used up.
+func (client *AbecRPCClient) retry(ctx context.Context, description string, attempt func() (bool, error)) error {
+	for n := 1; ; n++ {
+		retryable, err := attempt()
+		if err == nil || !retryable || n >= client.retryPolicy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := client.retryPolicy.delay(n)
+		LOG.debug("Retrying %s in %s after attempt %d failed: %s\n", description, delay, n, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-client.clock.After(delay):
+		}
+	}
+}
+
+// callOnce makes a single call and reports whether its error is transient and the call may be retried.
+func (client *AbecRPCClient) callOnce(ctx context.Context, method string, params []interface{}) (Bytes, bool, error) {
+	id, resp, err := client.send(ctx, method, params)
+	if err != nil {
+		// A tx submission that may have reached the node is not retried, so that it is never sent twice.
+		return nil, !isSubmissionMethod(method) || isDialError(err), err
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		LOG.debug("Response(%s): ERROR(%s)\n", id, resp.Status)
+		return nil, false, fmt.Errorf("%w: abec.%s: %s", ErrUnauthorized, method, resp.Status)
+	}
+	retryable := resp.StatusCode >= http.StatusInternalServerError && !isSubmissionMethod(method)
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		LOG.debug("Response(%s): ERROR(%s)\n", id, err)
-		return nil, err
+		return nil, retryable, err
 	}
-	LOG.debug("Response(%s): %s\n", id, body)
+	LOG.debug("Response(%s): %s\n", id, LOG.value(string(body)))
 
 	respObj := &AbecJSONRPCResponse{}
 	err = json.Unmarshal(body, respObj)
 	if err != nil {
-		return nil, err
+		if retryable {
+			return nil, true, fmt.Errorf("abec.%s: %s", method, resp.Status)
+		}
+		return nil, false, err
 	}
 
-	errorStr := string(respObj.Error)
-	if len(errorStr) > 0 && errorStr != "null" {
-		return nil, fmt.Errorf("abec.%s: %s", method, respObj.Error)
+	if isJSONRPCError(respObj.Error) {
+		return nil, false, newJSONRPCError(method, respObj.Error)
 	}
 
-	return AsBytes(respObj.Result), nil
+	return AsBytes(respObj.Result), false, nil
+}
+
+func isSubmissionMethod(method string) bool {
+	return method == "sendrawtransactionabe" || method == "sendrawtransaction"
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isJSONRPCError(rawError json.RawMessage) bool {
+	errorStr := string(rawError)
+	return len(errorStr) > 0 && errorStr != "null"
+}
+
+func newJSONRPCError(method string, rawError json.RawMessage) error {
+	rpcError := parseJSONRPCError(rawError)
+	rpcError.Method = method
+	return rpcError
+}
+
+func parseJSONRPCError(rawError json.RawMessage) *AbecRPCError {
+	// Both JSON-RPC 1.0 (as implemented by abec) and 2.0 use an error object with code and message,
+	// where 2.0 may also carry an optional data member. Some 1.0 servers report a bare string instead.
+	jsonError := &AbecJSONRPCError{}
+	err := json.Unmarshal(rawError, jsonError)
+	if err == nil && (jsonError.Code != 0 || len(jsonError.Message) > 0) {
+		rpcError := &AbecRPCError{Code: jsonError.Code, Message: jsonError.Message}
+		if len(jsonError.Data) > 0 && string(jsonError.Data) != "null" {
+			rpcError.Data = jsonError.Data
+		}
+		return rpcError
+	}
+
+	var errorMessage string
+	err = json.Unmarshal(rawError, &errorMessage)
+	if err == nil {
+		return &AbecRPCError{Message: errorMessage}
+	}
+
+	return &AbecRPCError{Message: string(rawError)}
 }
 
 func AbecRPCClientCallForResult[ResultType any](client *AbecRPCClient, result *ResultType, method string, params []interface{}) (Bytes, *ResultType, error) {
-	resultBytes, err := client.callForBytes(method, params)
+	return AbecRPCClientCallForResultContext(context.Background(), client, result, method, params)
+}
+
+// AbecRPCClientCallForResultContext is AbecRPCClientCallForResult with a ctx that cancels the call, including
+// its retries.
+func AbecRPCClientCallForResultContext[ResultType any](ctx context.Context, client *AbecRPCClient, result *ResultType, method string, params []interface{}) (Bytes, *ResultType, error) {
+	resultBytes, err := client.callForBytes(ctx, method, params)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -195,33 +732,128 @@ func AbecRPCClientCallForResult[ResultType any](client *AbecRPCClient, result *R
 }
 
 func (client *AbecRPCClient) GetChainInfo() (Bytes, *AbecChainInfo, error) {
-	return AbecRPCClientCallForResult(client, &AbecChainInfo{}, "getinfo", nil)
+	return client.GetChainInfoContext(context.Background())
+}
+
+func (client *AbecRPCClient) GetChainInfoContext(ctx context.Context) (Bytes, *AbecChainInfo, error) {
+	return AbecRPCClientCallForResultContext(ctx, client, &AbecChainInfo{}, "getinfo", nil)
 }
 
 func (client *AbecRPCClient) GetMempool() (Bytes, *AbecMempool, error) {
 	return AbecRPCClientCallForResult(client, &AbecMempool{}, "getrawmempool", []interface{}{true})
 }
 
+// GetBlockCount returns the height of the tip, without the other chain info that getinfo collects.
+func (client *AbecRPCClient) GetBlockCount() (Bytes, *int64, error) {
+	return client.GetBlockCountContext(context.Background())
+}
+
+func (client *AbecRPCClient) GetBlockCountContext(ctx context.Context) (Bytes, *int64, error) {
+	return AbecRPCClientCallForResultContext(ctx, client, new(int64), "getblockcount", nil)
+}
+
+func (client *AbecRPCClient) GetBestBlockHash() (Bytes, *string, error) {
+	return AbecRPCClientCallForResult(client, new(string), "getbestblockhash", nil)
+}
+
 func (client *AbecRPCClient) GetBlockHash(height int64) (Bytes, *string, error) {
-	return AbecRPCClientCallForResult(client, new(string), "getblockhash", []interface{}{height})
+	return client.GetBlockHashContext(context.Background(), height)
+}
+
+func (client *AbecRPCClient) GetBlockHashContext(ctx context.Context, height int64) (Bytes, *string, error) {
+	if err := validateHeightParam(height); err != nil {
+		return nil, nil, err
+	}
+
+	return AbecRPCClientCallForResultContext(ctx, client, new(string), "getblockhash", []interface{}{height})
 }
 
 func (client *AbecRPCClient) GetBlock(hash string) (Bytes, *AbecBlock, error) {
-	return AbecRPCClientCallForResult(client, &AbecBlock{}, "getblockabe", []interface{}{hash, 1})
+	return client.GetBlockContext(context.Background(), hash)
 }
 
-func (client *AbecRPCClient) GetBlockBytes(hash string) (Bytes, error) {
+func (client *AbecRPCClient) GetBlockContext(ctx context.Context, hash string) (Bytes, *AbecBlock, error) {
+	if err := validateHashParam(hash); err != nil {
+		return nil, nil, err
+	}
+
+	return AbecRPCClientCallForResultContext(ctx, client, &AbecBlock{}, "getblockabe", []interface{}{hash, 1})
+}
+
+// GetBlockHeader returns the verbose header of the block. Its seal hash is computed from the serialized
+// header, which takes a second request.
+func (client *AbecRPCClient) GetBlockHeader(hash string) (Bytes, *AbecBlockHeader, error) {
+	if err := validateHashParam(hash); err != nil {
+		return nil, nil, err
+	}
+
+	resultBytes, header, err := AbecRPCClientCallForResult(client, &AbecBlockHeader{}, "getblockheader", []interface{}{hash, true})
+	if err != nil {
+		return resultBytes, nil, err
+	}
+
+	headerBytes, err := client.GetBlockHeaderBytes(hash)
+	if err != nil {
+		return resultBytes, nil, err
+	}
+	decodedHeader, err := DecodeAbecBlockHeader(headerBytes)
+	if err != nil {
+		return resultBytes, nil, err
+	}
+	if decodedHeader.BlockHash != header.BlockHash {
+		return resultBytes, nil, fmt.Errorf("block header %s returned by the node hashes to %s", header.BlockHash, decodedHeader.BlockHash)
+	}
+	header.SealHash = decodedHeader.SealHash
+
+	return resultBytes, header, nil
+}
+
+// GetBlockHeaderBytes returns the serialized header of the block.
+func (client *AbecRPCClient) GetBlockHeaderBytes(hash string) (Bytes, error) {
+	if err := validateHashParam(hash); err != nil {
+		return nil, err
+	}
+
 	var data string
-	_, result, err := AbecRPCClientCallForResult(client, &data, "getblockabe", []interface{}{hash, 0})
+	_, result, err := AbecRPCClientCallForResult(client, &data, "getblockheader", []interface{}{hash, false})
 	if err != nil {
 		return nil, err
 	}
 
-	blockBytes := MakeBytesFromHexString(*result)
-	return blockBytes, nil
+	return MakeBytesFromHexString(*result), nil
+}
+
+func (client *AbecRPCClient) GetBlockBytes(hash string) (Bytes, error) {
+	return client.GetBlockBytesContext(context.Background(), hash)
+}
+
+func (client *AbecRPCClient) GetBlockBytesContext(ctx context.Context, hash string) (Bytes, error) {
+	buffer := &bytes.Buffer{}
+	_, err := client.GetBlockBytesToContext(ctx, hash, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return AsBytes(buffer.Bytes()), nil
+}
+
+func (client *AbecRPCClient) GetBlockBytesTo(hash string, w io.Writer) (int64, error) {
+	return client.GetBlockBytesToContext(context.Background(), hash, w)
+}
+
+func (client *AbecRPCClient) GetBlockBytesToContext(ctx context.Context, hash string, w io.Writer) (int64, error) {
+	if err := validateHashParam(hash); err != nil {
+		return 0, err
+	}
+
+	return client.callForHexStream(ctx, "getblockabe", []interface{}{hash, 0}, w)
 }
 
 func (client *AbecRPCClient) GetTxBytes(hash string) (Bytes, error) {
+	if err := validateHashParam(hash); err != nil {
+		return nil, err
+	}
+
 	var data string
 	_, result, err := AbecRPCClientCallForResult(client, &data, "getrawtransaction", []interface{}{hash, false})
 	if err != nil {
@@ -233,31 +865,170 @@ func (client *AbecRPCClient) GetTxBytes(hash string) (Bytes, error) {
 }
 
 func (client *AbecRPCClient) GetRawTx(hash string) (Bytes, *AbecTx, error) {
-	return AbecRPCClientCallForResult(client, &AbecTx{}, "getrawtransaction", []interface{}{hash, true})
+	return client.GetRawTxContext(context.Background(), hash)
+}
+
+func (client *AbecRPCClient) GetRawTxContext(ctx context.Context, hash string) (Bytes, *AbecTx, error) {
+	if err := validateHashParam(hash); err != nil {
+		return nil, nil, err
+	}
+
+	return AbecRPCClientCallForResultContext(ctx, client, &AbecTx{}, "getrawtransaction", []interface{}{hash, true})
+}
+
+// GetUTXORing returns the spent status of a ring, or a nil entry if every coin in the ring has been spent.
+func (client *AbecRPCClient) GetUTXORing(ring *AbecUTXORing) (Bytes, *AbecUTXORingEntry, error) {
+	resultBytes, entry, err := AbecRPCClientCallForResult(client, &AbecUTXORingEntry{}, "getutxoring", []interface{}{ring})
+	if err != nil {
+		return resultBytes, nil, err
+	}
+	if string(bytes.TrimSpace(resultBytes)) == "null" {
+		return resultBytes, nil, nil
+	}
+
+	return resultBytes, entry, nil
 }
 
 func (client *AbecRPCClient) GetBlockByHeight(height int64) (Bytes, *AbecBlock, error) {
-	_, hash, err := client.GetBlockHash(height)
+	return client.GetBlockByHeightContext(context.Background(), height)
+}
+
+func (client *AbecRPCClient) GetBlockByHeightContext(ctx context.Context, height int64) (Bytes, *AbecBlock, error) {
+	_, hash, err := client.GetBlockHashContext(ctx, height)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, wrapHeightNotFound(height, err)
 	}
 
-	return client.GetBlock(*hash)
+	return client.GetBlockContext(ctx, *hash)
 }
 
 func (client *AbecRPCClient) GetBlockBytesByHeight(height int64) (Bytes, error) {
+	return client.GetBlockBytesByHeightContext(context.Background(), height)
+}
+
+func (client *AbecRPCClient) GetBlockBytesByHeightContext(ctx context.Context, height int64) (Bytes, error) {
+	_, hash, err := client.GetBlockHashContext(ctx, height)
+	if err != nil {
+		return nil, wrapHeightNotFound(height, err)
+	}
+
+	return client.GetBlockBytesContext(ctx, *hash)
+}
+
+func (client *AbecRPCClient) GetBlockHeaderByHeight(height int64) (Bytes, *AbecBlockHeader, error) {
 	_, hash, err := client.GetBlockHash(height)
 	if err != nil {
-		return nil, err
+		return nil, nil, wrapHeightNotFound(height, err)
 	}
 
-	return client.GetBlockBytes(*hash)
+	return client.GetBlockHeader(*hash)
 }
 
-func (client *AbecRPCClient) GetEstimatedTxFee() int64 {
-	return AbelToNeutrino(0.1)
+func (client *AbecRPCClient) EstimateFee(confTarget int64) (Bytes, *float64, error) {
+	if confTarget <= 0 || confTarget > MAX_FEE_ESTIMATE_CONF_TARGET {
+		return nil, nil, fmt.Errorf("confirmation target %d is not in range [1, %d]", confTarget, MAX_FEE_ESTIMATE_CONF_TARGET)
+	}
+
+	return AbecRPCClientCallForResult(client, new(float64), "estimatefee", []interface{}{confTarget})
+}
+
+// GetEstimatedTxFee returns the fee for a typical transfer, spending one input to a payment and a change output,
+// to confirm within confTarget blocks. The fee rate is the node's estimate floored at its relay fee, and
+// DEFAULT_TX_FEE is only returned when the node has no estimate. A confTarget outside
+// [1, MAX_FEE_ESTIMATE_CONF_TARGET] is an error.
+func (client *AbecRPCClient) GetEstimatedTxFee(confTarget int64) (int64, error) {
+	txSize, err := estimateTxSize(1, 2, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	feeRate, ok, err := client.estimateFeeRatePerKB(confTarget)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return DEFAULT_TX_FEE, nil
+	}
+
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	return ComputeFee(txSize, feeRate, chainInfo.RelayFeeNeutrinoPerKB()), nil
+}
+
+// estimateFeeRatePerKB returns the node's fee rate estimate in neutrino per kB, and false if the node has no
+// estimate, e.g. because it has not yet observed enough blocks or fee estimation is disabled.
+func (client *AbecRPCClient) estimateFeeRatePerKB(confTarget int64) (int64, bool, error) {
+	_, feeRate, err := client.EstimateFee(confTarget)
+	var rpcErr *AbecRPCError
+	if errors.As(err, &rpcErr) && rpcErr.IsNoFeeEstimate() {
+		LOG.debug("No fee estimate for %d blocks: %s\n", confTarget, err)
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// The node reports the rate in ABEL per kB.
+	feeRatePerKB := AbelToNeutrino(*feeRate)
+	if feeRatePerKB <= 0 {
+		return 0, false, nil
+	}
+
+	return feeRatePerKB, true, nil
 }
 
 func (client *AbecRPCClient) SendRawTx(txStr string) (Bytes, *string, error) {
+	if len(txStr) == 0 {
+		return nil, nil, fmt.Errorf("raw tx string is empty")
+	}
+	if _, err := hex.DecodeString(txStr); err != nil {
+		return nil, nil, fmt.Errorf("raw tx string is not valid hex: %s", err)
+	}
+
 	return AbecRPCClientCallForResult(client, new(string), "sendrawtransactionabe", []interface{}{txStr})
 }
+
+func (client *AbecRPCClient) SendSignedRawTx(tx *SignedRawTx) (Bytes, *string, error) {
+	if tx == nil || tx.Len() == 0 {
+		return nil, nil, fmt.Errorf("signed raw tx is empty")
+	}
+
+	return client.SendRawTx(tx.HexString())
+}
+
+// Define util functions.
+type releasingReadCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releasingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+func validateHashParam(hash string) error {
+	if len(hash) == 0 {
+		return fmt.Errorf("hash param is empty")
+	}
+	if len(hash) != 64 {
+		return fmt.Errorf("hash param %q is not 64 hex characters", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return fmt.Errorf("hash param %q is not valid hex: %s", hash, err)
+	}
+
+	return nil
+}
+
+func validateHeightParam(height int64) error {
+	if height < 0 {
+		return fmt.Errorf("height param %d is negative", height)
+	}
+
+	return nil
+}
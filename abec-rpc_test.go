@@ -0,0 +1,547 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHangingServer returns a node that never answers until the request is cancelled or the test ends.
+func newHangingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+
+	return server
+}
+
+func TestBlockGettersHonorContext(t *testing.T) {
+	server := newHangingServer(t)
+	client := NewAbecRPCClient(server.URL, "", "")
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"GetBlockByHeightContext", func(ctx context.Context) error {
+			_, _, err := client.GetBlockByHeightContext(ctx, 1)
+			return err
+		}},
+		{"GetBlockBytesByHeightContext", func(ctx context.Context) error {
+			_, err := client.GetBlockBytesByHeightContext(ctx, 1)
+			return err
+		}},
+		{"GetBlockWhenAvailable", func(ctx context.Context) error {
+			_, _, err := client.GetBlockByHeightWhenAvailable(ctx, 1, time.Minute)
+			return err
+		}},
+		{"RescanWallet", func(ctx context.Context) error {
+			return RescanWallet(ctx, client, NewWallet(), keys, 0, nil)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := test.call(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got error %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("call returned after %s, long after ctx was done", elapsed)
+			}
+		})
+	}
+}
+
+// countingRoundTripper counts the requests it forwards to http.DefaultTransport.
+type countingRoundTripper struct {
+	requests int
+}
+
+func (roundTripper *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	roundTripper.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTransportOptionsDoNotModifySharedTransport(t *testing.T) {
+	shared := &http.Transport{ResponseHeaderTimeout: time.Minute}
+	httpClient := &http.Client{Transport: shared}
+
+	client := NewAbecRPCClient("http://127.0.0.1:1", "", "", WithHTTPClient(httpClient), WithResponseHeaderTimeout(time.Second), WithDialTimeout(time.Second))
+
+	if shared.ResponseHeaderTimeout != time.Minute || shared.DialContext != nil {
+		t.Errorf("transport options modified the transport given by WithHTTPClient")
+	}
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok || transport == shared {
+		t.Fatalf("client does not use its own clone of the transport")
+	}
+	if transport.ResponseHeaderTimeout != time.Second || transport.TLSHandshakeTimeout != time.Second {
+		t.Errorf("got timeouts %s and %s on the cloned transport, want 1s", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestTransportOptionsKeepCustomRoundTripper(t *testing.T) {
+	node := newMockNode(t, map[string]mockMethod{})
+	roundTripper := &countingRoundTripper{}
+
+	client := NewAbecRPCClient(node.endpoint, "", "", WithHTTPClient(&http.Client{Transport: roundTripper}), WithResponseHeaderTimeout(time.Second))
+	if client.httpClient.Transport != roundTripper {
+		t.Fatalf("transport options replaced the custom round-tripper")
+	}
+
+	client.GetChainInfo()
+	if roundTripper.requests != 1 {
+		t.Errorf("custom round-tripper forwarded %d requests, want 1", roundTripper.requests)
+	}
+}
+
+func TestOptionsDoNotModifyDefaultClient(t *testing.T) {
+	defaultClient := *http.DefaultClient
+
+	client := NewAbecRPCClient("http://127.0.0.1:1", "", "", WithHTTPClient(http.DefaultClient), WithTimeout(time.Second), WithDialTimeout(time.Second))
+
+	if client.httpClient == http.DefaultClient {
+		t.Fatalf("client uses http.DefaultClient itself instead of a copy")
+	}
+	if http.DefaultClient.Timeout != defaultClient.Timeout || http.DefaultClient.Transport != defaultClient.Transport {
+		t.Errorf("options modified http.DefaultClient")
+	}
+	if client.httpClient.Timeout != time.Second {
+		t.Errorf("got timeout %s, want 1s", client.httpClient.Timeout)
+	}
+	if _, ok := client.httpClient.Transport.(*http.Transport); !ok || client.httpClient.Transport == http.DefaultTransport {
+		t.Errorf("client does not use its own clone of the default transport")
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	const limit = 3
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		fmt.Fprint(w, `{"result":1,"error":null,"id":"1"}`)
+	}))
+	t.Cleanup(server.Close)
+	client := NewAbecRPCClient(server.URL, "", "", WithMaxConcurrentRequests(limit))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.GetBlockCount()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("got error %s", err)
+		}
+	}
+	mutex.Lock()
+	if maxInFlight > limit || maxInFlight == 0 {
+		t.Errorf("got at most %d requests in flight, want 1 to %d", maxInFlight, limit)
+	}
+	mutex.Unlock()
+}
+
+func TestMaxConcurrentRequestsWaitHonorsContext(t *testing.T) {
+	var requests int32
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+		fmt.Fprint(w, `{"result":1,"error":null,"id":"1"}`)
+	}))
+	t.Cleanup(server.Close)
+	client := NewAbecRPCClient(server.URL, "", "", WithMaxConcurrentRequests(1))
+
+	// The first call takes the only slot until the node is unblocked.
+	firstDone := make(chan error, 1)
+	go func() {
+		_, _, err := client.GetBlockCount()
+		firstDone <- err
+	}()
+	waitFor(t, "the first request", func() bool { return atomic.LoadInt32(&requests) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := client.GetBlockCountContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v while waiting for a slot, want context.DeadlineExceeded", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests to the node, want the waiting one never sent", n)
+	}
+
+	close(unblock)
+	if err := <-firstDone; err != nil {
+		t.Fatalf("got error %s for the first call", err)
+	}
+	_, _, err = client.GetBlockCount()
+	if err != nil {
+		t.Errorf("got error %s after the slot was released", err)
+	}
+}
+
+func TestNewRequestIDIsUnique(t *testing.T) {
+	// A stopped clock would give every request of a time-based id the same id.
+	client := NewAbecRPCClient("http://127.0.0.1:1", "", "", WithClock(NewFakeClock(time.Unix(1700000000, 0))))
+
+	const numGoroutines = 8
+	const numIDs = 1000
+	ids := make(chan string, numGoroutines*numIDs)
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numIDs; j++ {
+				ids <- client.newRequestID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool, numGoroutines*numIDs)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("request id %s was given out twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestAuthStrategies(t *testing.T) {
+	var mutex sync.Mutex
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		header = r.Header.Clone()
+		mutex.Unlock()
+		w.Write([]byte(`{"result":1,"error":null,"id":"1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	for _, tc := range []struct {
+		name          string
+		options       []AbecRPCClientOption
+		strategy      AuthStrategy
+		authorization string
+		apiKey        string
+	}{
+		{"basic", nil, BASIC_AUTH, basic, ""},
+		{"bearer token", []AbecRPCClientOption{WithBearerToken("token")}, BEARER_TOKEN_AUTH, "Bearer token", ""},
+		{"auth header", []AbecRPCClientOption{WithAuthHeader("X-Api-Key", "key")}, HEADER_AUTH, "", "key"},
+		// The last auth option wins, and the scheme it replaces is not sent along.
+		{"bearer token then auth header", []AbecRPCClientOption{WithBearerToken("token"), WithAuthHeader("X-Api-Key", "key")}, HEADER_AUTH, "", "key"},
+		{"auth header then bearer token", []AbecRPCClientOption{WithAuthHeader("X-Api-Key", "key"), WithBearerToken("token")}, BEARER_TOKEN_AUTH, "Bearer token", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewAbecRPCClient(server.URL, "user", "secret", tc.options...)
+			if client.AuthStrategy() != tc.strategy {
+				t.Errorf("got auth strategy %d, want %d", client.AuthStrategy(), tc.strategy)
+			}
+			_, _, err := client.GetBlockCount()
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if got := header.Get("Authorization"); got != tc.authorization {
+				t.Errorf("got Authorization %q, want %q", got, tc.authorization)
+			}
+			if got := header.Get("X-Api-Key"); got != tc.apiKey {
+				t.Errorf("got X-Api-Key %q, want %q", got, tc.apiKey)
+			}
+		})
+	}
+}
+
+func TestGetBlockCountAndBestBlockHash(t *testing.T) {
+	// The node answers getblockcount with a bare JSON number, here one that a float64 cannot hold exactly.
+	const blockCount = "9007199254740993"
+	bestBlockHash := testBlockHash("ab")
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockcount": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return json.RawMessage(blockCount), nil
+		},
+		"getbestblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return bestBlockHash, nil
+		},
+	})
+
+	resultBytes, count, err := client.GetBlockCount()
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if *count != 9007199254740993 || string(resultBytes) != blockCount {
+		t.Errorf("got block count %d from %q, want %s", *count, resultBytes, blockCount)
+	}
+
+	resultBytes, hash, err := client.GetBestBlockHash()
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if *hash != bestBlockHash || string(resultBytes) != `"`+bestBlockHash+`"` {
+		t.Errorf("got best block hash %s from %q, want %s", *hash, resultBytes, bestBlockHash)
+	}
+}
+
+func TestParamValidationBeforeSending(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	client := NewAbecRPCClient(server.URL, "", "")
+
+	for _, hash := range []string{"", "ab", strings.Repeat("zz", 32), "0x" + strings.Repeat("ab", 31)} {
+		calls := map[string]func() error{
+			"GetBlock":            func() error { _, _, err := client.GetBlock(hash); return err },
+			"GetBlockHeader":      func() error { _, _, err := client.GetBlockHeader(hash); return err },
+			"GetBlockHeaderBytes": func() error { _, err := client.GetBlockHeaderBytes(hash); return err },
+			"GetBlockBytes":       func() error { _, err := client.GetBlockBytes(hash); return err },
+			"GetTxBytes":          func() error { _, err := client.GetTxBytes(hash); return err },
+			"GetRawTx":            func() error { _, _, err := client.GetRawTx(hash); return err },
+		}
+		for name, call := range calls {
+			if err := call(); err == nil {
+				t.Errorf("%s(%q): got no error", name, hash)
+			}
+		}
+	}
+
+	if _, _, err := client.GetBlockHash(-1); err == nil {
+		t.Errorf("GetBlockHash(-1): got no error")
+	}
+	if _, err := client.GetBlocksByHeightRange(-1, 2); err == nil {
+		t.Errorf("GetBlocksByHeightRange(-1, 2): got no error")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("got %d requests to the node, want invalid params rejected before sending", n)
+	}
+}
+
+func TestJSONRPCVersions(t *testing.T) {
+	tests := []struct {
+		version     string
+		wantParams  string
+		result      string
+		errorObject string
+		errorString string
+	}{
+		{
+			version:     JSONRPC_VERSION_1,
+			wantParams:  "null",
+			result:      `{"result":1234,"error":null,"id":%s}`,
+			errorObject: `{"result":null,"error":{"code":-5,"message":"Block not found"},"id":%s}`,
+			errorString: `{"result":null,"error":"Block not found","id":%s}`,
+		},
+		{
+			// A 2.0 response has either a result or an error member, and may carry data in the error.
+			version:     JSONRPC_VERSION_2,
+			wantParams:  "[]",
+			result:      `{"jsonrpc":"2.0","result":1234,"id":%s}`,
+			errorObject: `{"jsonrpc":"2.0","error":{"code":-5,"message":"Block not found","data":{"hash":"ab"}},"id":%s}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			var response string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					JSONRPC string          `json:"jsonrpc"`
+					Params  json.RawMessage `json:"params"`
+					ID      json.RawMessage `json:"id"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				if req.JSONRPC != test.version || string(req.Params) != test.wantParams {
+					t.Errorf("got a request of version %q with params %s, want %q with %s", req.JSONRPC, req.Params, test.version, test.wantParams)
+				}
+				fmt.Fprintf(w, response, req.ID)
+			}))
+			defer server.Close()
+			client := NewAbecRPCClient(server.URL, "", "", WithJSONRPCVersion(test.version))
+
+			response = test.result
+			_, count, err := client.GetBlockCount()
+			if err != nil || *count != 1234 {
+				t.Errorf("got block count %v and error %v, want 1234", count, err)
+			}
+
+			response = test.errorObject
+			_, _, err = client.GetBlockCount()
+			var rpcErr *AbecRPCError
+			if !errors.As(err, &rpcErr) || rpcErr.Code != -5 || rpcErr.Message != "Block not found" {
+				t.Errorf("got error %v, want code -5 and the message of the error object", err)
+			}
+			if test.version == JSONRPC_VERSION_2 && string(rpcErr.Data) != `{"hash":"ab"}` {
+				t.Errorf("got error data %s, want the data member", rpcErr.Data)
+			}
+
+			if test.errorString != "" {
+				response = test.errorString
+				_, _, err = client.GetBlockCount()
+				if !errors.As(err, &rpcErr) || rpcErr.Code != 0 || rpcErr.Message != "Block not found" {
+					t.Errorf("got error %v, want the message of the error string", err)
+				}
+			}
+		})
+	}
+}
+
+func TestAbecJSONRPCID(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    AbecJSONRPCID
+		wantErr bool
+	}{
+		{"string", `"12"`, "12", false},
+		{"string with a suffix", `"12-3"`, "12-3", false},
+		{"number", `12`, "12", false},
+		{"null", `null`, "", false},
+		{"boolean", `true`, "", true},
+		{"object", `{"id":12}`, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &AbecJSONRPCResponse{}
+			err := json.Unmarshal([]byte(`{"result":1,"error":null,"id":`+test.data+`}`), resp)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("got id %q, want an error", resp.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if resp.ID != test.want || !resp.ID.Matches(string(test.want)) {
+				t.Errorf("got id %q, want %q", resp.ID, test.want)
+			}
+		})
+	}
+
+	// A node that echoes the request id back as a number still gets its result through.
+	for _, numeric := range []bool{false, true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := &struct {
+				ID string `json:"id"`
+			}{}
+			json.NewDecoder(r.Body).Decode(req)
+			id := fmt.Sprintf("%q", req.ID)
+			if numeric {
+				id = req.ID
+			}
+			fmt.Fprintf(w, `{"result":42,"error":null,"id":%s}`, id)
+		}))
+		client := NewAbecRPCClient(server.URL, "", "")
+
+		_, count, err := client.GetBlockCount()
+		if err != nil || *count != 42 {
+			t.Errorf("got count %v and error %v with a numeric id %t, want 42", count, err, numeric)
+		}
+		server.Close()
+	}
+}
+
+func TestSendRawTxValidatesHex(t *testing.T) {
+	var mutex sync.Mutex
+	sent := make([]string, 0)
+	client := newMockNode(t, map[string]mockMethod{
+		"sendrawtransactionabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var txStr string
+			json.Unmarshal(params[0], &txStr)
+			mutex.Lock()
+			defer mutex.Unlock()
+			sent = append(sent, txStr)
+			return testBlockHash("cd"), nil
+		},
+	})
+
+	for name, txStr := range map[string]string{
+		"empty":         "",
+		"not hex":       "zz00",
+		"odd length":    "abc",
+		"prefixed":      "0xab",
+		"space in data": "ab cd",
+	} {
+		_, _, err := client.SendRawTx(txStr)
+		if err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+	for name, tx := range map[string]*SignedRawTx{
+		"nil":   nil,
+		"empty": NewSignedRawTx(nil, Txid{}),
+	} {
+		_, _, err := client.SendSignedRawTx(tx)
+		if err == nil {
+			t.Errorf("%s signed raw tx: got no error", name)
+		}
+	}
+	mutex.Lock()
+	if len(sent) != 0 {
+		t.Errorf("got %d txs sent to the node, want the invalid ones rejected before sending", len(sent))
+	}
+	mutex.Unlock()
+
+	// The typed overload sends the hex of the tx's bytes.
+	_, txid, err := client.SendSignedRawTx(NewSignedRawTx(Bytes{0x01, 0xab, 0xff}, Txid{}))
+	if err != nil || *txid != testBlockHash("cd") {
+		t.Fatalf("got txid %v and error %v", txid, err)
+	}
+	mutex.Lock()
+	if len(sent) != 1 || sent[0] != "01abff" {
+		t.Errorf("got %q sent to the node, want [01abff]", sent)
+	}
+	mutex.Unlock()
+}
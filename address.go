@@ -2,7 +2,12 @@ package core
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	api "github.com/abesuite/abec/sdkapi/v1"
 	abeAddr "github.com/abesuite/abeutil/address/instanceaddress"
@@ -43,11 +48,56 @@ func (addressType AddressType) String() string {
 	}
 }
 
+func ParseAddressType(s string) (AddressType, error) {
+	for _, addressType := range []AddressType{ANY_ADDRESS_TYPE, COIN_ADDRESS_TYPE, CRYPTO_ADDRESS_TYPE, ABEL_ADDRESS_TYPE, SHORT_ABEL_ADDRESS_TYPE} {
+		if addressType.String() == s {
+			return addressType, nil
+		}
+	}
+
+	return ANY_ADDRESS_TYPE, fmt.Errorf("address type %q is unknown", s)
+}
+
+// Define the AddressError data type.
+// AddressError is returned when untrusted input is not a valid address of the expected type.
+type AddressError struct {
+	Type   AddressType
+	Reason string
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("%s is not valid: %s", e.Type, e.Reason)
+}
+
 // Define the Address data type.
 type Address struct {
-	data        Bytes
-	addressType AddressType
-	fingerprint Bytes
+	data            Bytes
+	addressType     AddressType
+	fingerprint     Bytes
+	lazyFingerprint *lazyBytes
+}
+
+type addressJSON struct {
+	Type string `json:"type"`
+	Data Bytes  `json:"data"`
+}
+
+// Define the lazyBytes data type, which computes its value on first use and caches it.
+type lazyBytes struct {
+	once    sync.Once
+	compute func() Bytes
+	value   Bytes
+}
+
+func newLazyBytes(compute func() Bytes) *lazyBytes {
+	return &lazyBytes{compute: compute}
+}
+
+func (l *lazyBytes) get() Bytes {
+	l.once.Do(func() {
+		l.value = l.compute()
+	})
+	return l.value
 }
 
 // Define methods for Address.
@@ -68,7 +118,24 @@ func NewAddress(data Bytes, addressType AddressType, fingerprint ...Bytes) Addre
 }
 
 func (a Address) String() string {
-	return fmt.Sprintf("%s{%s|fp:%s}", a.addressType.String(), a.data.Summary(1, 8), a.fingerprint.Summary(0, 2))
+	return fmt.Sprintf("%s{%s|fp:%s}", a.addressType.String(), a.data.Summary(1, 8), a.Fingerprint().Summary(0, 2))
+}
+
+// MarshalJSON encodes the address as its type name and the hex of its data. The fingerprint is not included,
+// since it is derived from the data on unmarshal.
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&addressJSON{Type: a.addressType.String(), Data: a.data})
+}
+
+// UnmarshalJSON decodes an address of any type encoded by MarshalJSON, restoring its fingerprint.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	address, err := unmarshalAddressJSON(data, ANY_ADDRESS_TYPE)
+	if err != nil {
+		return err
+	}
+
+	*a = address
+	return nil
 }
 
 func (a *Address) Type() AddressType {
@@ -80,9 +147,25 @@ func (a *Address) Data() Bytes {
 }
 
 func (a *Address) Fingerprint() Bytes {
+	if a.fingerprint == nil && a.lazyFingerprint != nil {
+		return a.lazyFingerprint.get()
+	}
+
 	return a.fingerprint
 }
 
+// Equal reports whether both addresses have the same type, data and fingerprint. The data is compared first,
+// so that a lazily derived fingerprint is only computed for addresses that are otherwise equal.
+func (a *Address) Equal(other *Address) bool {
+	if a == nil || other == nil {
+		return a == nil && other == nil
+	}
+
+	return a.addressType == other.addressType &&
+		bytes.Equal(a.data, other.data) &&
+		bytes.Equal(a.Fingerprint(), other.Fingerprint())
+}
+
 func (a *Address) Hash() Bytes {
 	return a.data.Sha256()
 }
@@ -96,7 +179,8 @@ func (a *Address) Validate() error {
 		return fmt.Errorf("address data is empty")
 	}
 
-	if a.fingerprint == nil || a.fingerprint.Len() == 0 {
+	fingerprint := a.Fingerprint()
+	if fingerprint == nil || fingerprint.Len() == 0 {
 		return fmt.Errorf("address fingerprint is empty")
 	}
 
@@ -113,6 +197,39 @@ func NewCoinAddress(data Bytes) *CoinAddress {
 	return &CoinAddress{Address: NewAddress(data, COIN_ADDRESS_TYPE, data.Sha256())}
 }
 
+func NewCoinAddressFromHex(s string) (*CoinAddress, error) {
+	data, err := decodeAddressHex(s, COIN_ADDRESS_TYPE, COIN_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	coinAddress := NewCoinAddress(data)
+	err = coinAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return coinAddress, nil
+}
+
+func (a *CoinAddress) UnmarshalJSON(data []byte) error {
+	address, err := unmarshalAddressJSON(data, COIN_ADDRESS_TYPE)
+	if err != nil {
+		return err
+	}
+
+	a.Address = address
+	return nil
+}
+
+func (a *CoinAddress) Equal(other *CoinAddress) bool {
+	if a == nil || other == nil {
+		return a == nil && other == nil
+	}
+
+	return a.Address.Equal(&other.Address)
+}
+
 func (a *CoinAddress) Validate() error {
 	err := a.Address.Validate()
 	if err != nil {
@@ -133,11 +250,53 @@ type CryptoAddress struct {
 
 // Define methods for CryptoAddress.
 func NewCryptoAddress(data Bytes) *CryptoAddress {
+	// The fingerprint requires deriving the coin address, so it is computed on first use.
+	// Malformed data leaves the fingerprint empty, which Validate reports.
 	cryptoAddress := &CryptoAddress{Address: NewAddress(data, CRYPTO_ADDRESS_TYPE, nil)}
-	cryptoAddress.fingerprint = cryptoAddress.GetCoinAddress().fingerprint
+	cryptoAddress.lazyFingerprint = newLazyBytes(func() Bytes {
+		coinAddress, err := cryptoAddress.GetCoinAddress()
+		if err != nil {
+			LOG.debug("Crypto address has no fingerprint: %s\n", err)
+			return nil
+		}
+		return coinAddress.Fingerprint()
+	})
 	return cryptoAddress
 }
 
+func NewCryptoAddressFromHex(s string) (*CryptoAddress, error) {
+	data, err := decodeAddressHex(s, CRYPTO_ADDRESS_TYPE, CRYPTO_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	cryptoAddress := NewCryptoAddress(data)
+	err = cryptoAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cryptoAddress, nil
+}
+
+func (a *CryptoAddress) UnmarshalJSON(data []byte) error {
+	address, err := unmarshalAddressJSON(data, CRYPTO_ADDRESS_TYPE)
+	if err != nil {
+		return err
+	}
+
+	a.Address = address
+	return nil
+}
+
+func (a *CryptoAddress) Equal(other *CryptoAddress) bool {
+	if a == nil || other == nil {
+		return a == nil && other == nil
+	}
+
+	return a.Address.Equal(&other.Address)
+}
+
 func (a *CryptoAddress) Validate() error {
 	err := a.Address.Validate()
 	if err != nil {
@@ -148,16 +307,16 @@ func (a *CryptoAddress) Validate() error {
 		return fmt.Errorf("crypto address data length is not %d", CRYPTO_ADDRESS_LENGTH)
 	}
 
-	return nil
+	return ValidateCryptoAddress(a.data)
 }
 
-func (a *CryptoAddress) GetCoinAddress() *CoinAddress {
+func (a *CryptoAddress) GetCoinAddress() (*CoinAddress, error) {
 	coinAddressData, err := api.ExtractCoinAddressFromCryptoAddress(a.data)
 	if err != nil {
-		LOG.Panicf("Failed to extract coin address from crypto address: %s", err)
+		return nil, fmt.Errorf("coin address cannot be extracted from crypto address: %s", err)
 	}
 
-	return NewCoinAddress(coinAddressData)
+	return NewCoinAddress(coinAddressData), nil
 }
 
 // Define the AbelAddress data type.
@@ -167,11 +326,29 @@ type AbelAddress struct {
 
 // Define methods for AbelAddress.
 func NewAbelAddress(data Bytes) *AbelAddress {
+	// The fingerprint requires deriving the coin address, so it is computed on first use.
 	abelAddress := &AbelAddress{Address: NewAddress(data, ABEL_ADDRESS_TYPE, nil)}
-	abelAddress.fingerprint = abelAddress.GetCryptoAddress().fingerprint
+	abelAddress.lazyFingerprint = newLazyBytes(func() Bytes {
+		return abelAddress.GetCryptoAddress().Fingerprint()
+	})
 	return abelAddress
 }
 
+func NewAbelAddressFromHex(s string) (*AbelAddress, error) {
+	data, err := decodeAddressHex(s, ABEL_ADDRESS_TYPE, ABEL_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	abelAddress := NewAbelAddress(data)
+	err = abelAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return abelAddress, nil
+}
+
 func NewAbelAddressFromCryptoAddress(cryptoAddress *CryptoAddress, chainID ...int8) *AbelAddress {
 	if len(chainID) == 0 {
 		chainID = []int8{DEFAULT_CHAIN_ID}
@@ -182,16 +359,40 @@ func NewAbelAddressFromCryptoAddress(cryptoAddress *CryptoAddress, chainID ...in
 	abelAddressData := append(serializedInstanceAddress, checkSum...)
 
 	abelAddress := &AbelAddress{Address: NewAddress(abelAddressData, ABEL_ADDRESS_TYPE, nil)}
-	abelAddress.fingerprint = cryptoAddress.fingerprint
+	abelAddress.lazyFingerprint = newLazyBytes(cryptoAddress.Fingerprint)
 	return abelAddress
 }
 
-func (a *AbelAddress) Validate() error {
-	err := a.Address.Validate()
+func (a *AbelAddress) UnmarshalJSON(data []byte) error {
+	address, err := unmarshalAddressJSON(data, ABEL_ADDRESS_TYPE)
 	if err != nil {
 		return err
 	}
 
+	a.Address = address
+	return nil
+}
+
+func (a *AbelAddress) Equal(other *AbelAddress) bool {
+	if a == nil || other == nil {
+		return a == nil && other == nil
+	}
+
+	return a.Address.Equal(&other.Address)
+}
+
+func (a *AbelAddress) Validate() error {
+	return a.ValidateFull()
+}
+
+// ValidateShallow only checks the length, chain id and checksum of the address. It is cheap enough for bulk
+// checks, but it does not guarantee that the embedded crypto address is usable, so a shallowly validated
+// address must still pass ValidateFull before any coin is sent to it.
+func (a *AbelAddress) ValidateShallow() error {
+	if a.data == nil || a.data.Len() == 0 {
+		return fmt.Errorf("address data is empty")
+	}
+
 	if a.data.Len() != ABEL_ADDRESS_LENGTH {
 		return fmt.Errorf("abel address data length is not %d", ABEL_ADDRESS_LENGTH)
 	}
@@ -201,14 +402,9 @@ func (a *AbelAddress) Validate() error {
 		return fmt.Errorf("abel address chain id is not in range [0, 14]")
 	}
 
-	cryptoAddress := a.GetCryptoAddress()
-	bl, _ := api.CheckCryptoAddress(cryptoAddress.Data())
-	if !bl {
-		return fmt.Errorf("abel address crypto address is not cryptographically valid")
-	}
-
 	checksum := a.GetChecksum()
-	calculatedChecksum := abeAddr.CheckSum(append([]byte{byte(chainID)}, cryptoAddress.Data()...))
+	checksummedData, _ := a.data.SubBytes(0, a.data.Len()-abeAddr.CheckSumLength())
+	calculatedChecksum := abeAddr.CheckSum(checksummedData)
 	if !bytes.Equal(checksum, calculatedChecksum) {
 		return fmt.Errorf("abel address checksum is not valid")
 	}
@@ -216,20 +412,55 @@ func (a *AbelAddress) Validate() error {
 	return nil
 }
 
+// ValidateFull performs the shallow checks plus the cryptographic check of the embedded crypto address.
+func (a *AbelAddress) ValidateFull() error {
+	err := a.ValidateShallow()
+	if err != nil {
+		return err
+	}
+
+	err = a.Address.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = ValidateCryptoAddress(a.GetCryptoAddress().Data())
+	if err != nil {
+		return fmt.Errorf("abel address is invalid: %s", err)
+	}
+
+	return nil
+}
+
 func (a *AbelAddress) GetChainID() int8 {
-	return int8(a.data.Slice()[0])
+	chainID, err := a.data.At(0)
+	if err != nil {
+		return -1
+	}
+
+	return int8(chainID)
 }
 
 func (a *AbelAddress) GetCryptoAddress() *CryptoAddress {
-	return NewCryptoAddress(a.data.Slice()[1 : a.data.Len()-abeAddr.CheckSumLength()])
+	cryptoAddressData, err := a.data.SubBytes(1, a.data.Len()-abeAddr.CheckSumLength())
+	if err != nil {
+		return NewCryptoAddress(nil)
+	}
+
+	return NewCryptoAddress(cryptoAddressData)
 }
 
 func (a *AbelAddress) GetChecksum() Bytes {
-	return a.data.Slice()[a.data.Len()-abeAddr.CheckSumLength():]
+	checksum, err := a.data.SubBytes(a.data.Len()-abeAddr.CheckSumLength(), a.data.Len())
+	if err != nil {
+		return nil
+	}
+
+	return checksum
 }
 
 func (a *AbelAddress) GetShortAbelAddress() *ShortAbelAddress {
-	return MakeShortAbelAddress(a.fingerprint, a.Hash(), a.GetChainID())
+	return MakeShortAbelAddress(a.Fingerprint(), a.Hash(), a.GetChainID())
 }
 
 // Define the ShortAbelAddress data type.
@@ -240,10 +471,28 @@ type ShortAbelAddress struct {
 // Define methods for ShortAbelAddress.
 func NewShortAbelAddress(data Bytes) *ShortAbelAddress {
 	shortAddress := &ShortAbelAddress{Address: NewAddress(data, SHORT_ABEL_ADDRESS_TYPE, nil)}
-	shortAddress.fingerprint = data.Slice()[2:34]
+	fingerprint, err := data.SubBytes(2, 34)
+	if err == nil {
+		shortAddress.fingerprint = fingerprint
+	}
 	return shortAddress
 }
 
+func NewShortAbelAddressFromHex(s string) (*ShortAbelAddress, error) {
+	data, err := decodeAddressHex(s, SHORT_ABEL_ADDRESS_TYPE, SHORT_ABEL_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	shortAddress := NewShortAbelAddress(data)
+	err = shortAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return shortAddress, nil
+}
+
 func MakeShortAbelAddress(fingerprint Bytes, cryptoAddressHash Bytes, chainID ...int8) *ShortAbelAddress {
 	if len(chainID) == 0 {
 		chainID = []int8{DEFAULT_CHAIN_ID}
@@ -257,6 +506,24 @@ func MakeShortAbelAddress(fingerprint Bytes, cryptoAddressHash Bytes, chainID ..
 	return NewShortAbelAddress(saData)
 }
 
+func (a *ShortAbelAddress) UnmarshalJSON(data []byte) error {
+	address, err := unmarshalAddressJSON(data, SHORT_ABEL_ADDRESS_TYPE)
+	if err != nil {
+		return err
+	}
+
+	a.Address = address
+	return nil
+}
+
+func (a *ShortAbelAddress) Equal(other *ShortAbelAddress) bool {
+	if a == nil || other == nil {
+		return a == nil && other == nil
+	}
+
+	return a.Address.Equal(&other.Address)
+}
+
 func (a *ShortAbelAddress) Validate() error {
 	err := a.Address.Validate()
 	if err != nil {
@@ -267,7 +534,7 @@ func (a *ShortAbelAddress) Validate() error {
 		return fmt.Errorf("short abel address data length is not %d", SHORT_ABEL_ADDRESS_LENGTH)
 	}
 
-	if a.data.Slice()[0] != 0xab {
+	if prefix, _ := a.data.At(0); prefix != 0xab {
 		return fmt.Errorf("short abel address data is not prefixed with 0xab")
 	}
 
@@ -280,5 +547,164 @@ func (a *ShortAbelAddress) Validate() error {
 }
 
 func (a *ShortAbelAddress) GetChainID() int8 {
-	return int8(a.data.Slice()[1] - 0xe1)
+	chainIDByte, err := a.data.At(1)
+	if err != nil {
+		return -1
+	}
+
+	return int8(chainIDByte - 0xe1)
+}
+
+// Define util functions.
+// ParseAbelAddress parses an abel address from untrusted input, encoded in hex (with or without a 0x prefix)
+// or in base64. The length, checksum and embedded crypto address are validated, and any failure is returned
+// as an *AddressError.
+func ParseAbelAddress(s string) (abelAddress *AbelAddress, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			abelAddress, err = nil, newPanicAddressError(ABEL_ADDRESS_TYPE, r)
+		}
+	}()
+
+	data, err := decodeAddressString(s, ABEL_ADDRESS_TYPE, ABEL_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	abelAddress = NewAbelAddress(data)
+	// The crypto address is checked before anything derives the fingerprint from it.
+	err = abelAddress.ValidateShallow()
+	if err == nil {
+		err = ValidateCryptoAddress(abelAddress.GetCryptoAddress().Data())
+	}
+	if err == nil {
+		err = abelAddress.Address.Validate()
+	}
+	if err != nil {
+		return nil, &AddressError{Type: ABEL_ADDRESS_TYPE, Reason: err.Error()}
+	}
+
+	return abelAddress, nil
+}
+
+// ParseShortAbelAddress parses a short abel address from untrusted input, encoded like for ParseAbelAddress.
+func ParseShortAbelAddress(s string) (shortAddress *ShortAbelAddress, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			shortAddress, err = nil, newPanicAddressError(SHORT_ABEL_ADDRESS_TYPE, r)
+		}
+	}()
+
+	data, err := decodeAddressString(s, SHORT_ABEL_ADDRESS_TYPE, SHORT_ABEL_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	shortAddress = NewShortAbelAddress(data)
+	err = shortAddress.Validate()
+	if err != nil {
+		return nil, &AddressError{Type: SHORT_ABEL_ADDRESS_TYPE, Reason: err.Error()}
+	}
+
+	return shortAddress, nil
+}
+
+func decodeAddressString(s string, addressType AddressType, expectedLength int) (Bytes, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, &AddressError{Type: addressType, Reason: "input is empty"}
+	}
+
+	data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		data, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return nil, &AddressError{Type: addressType, Reason: "input is neither hex nor base64"}
+	}
+	if len(data) != expectedLength {
+		return nil, &AddressError{Type: addressType, Reason: fmt.Sprintf("data length %d is not %d", len(data), expectedLength)}
+	}
+
+	return AsBytes(data), nil
+}
+
+func newPanicAddressError(addressType AddressType, recovered interface{}) error {
+	// The crypto API panics on some malformed inputs, which must surface as errors for untrusted input.
+	return &AddressError{Type: addressType, Reason: fmt.Sprint(recovered)}
+}
+
+// unmarshalAddressJSON decodes an address encoded by Address.MarshalJSON through the constructor of its type,
+// so that its fingerprint is derived as for a new address. Unless expectedType is ANY_ADDRESS_TYPE, the
+// encoded type must match it.
+func unmarshalAddressJSON(data []byte, expectedType AddressType) (Address, error) {
+	encoded := &addressJSON{}
+	err := json.Unmarshal(data, encoded)
+	if err != nil {
+		return Address{}, err
+	}
+
+	addressType, err := ParseAddressType(encoded.Type)
+	if err != nil {
+		return Address{}, err
+	}
+	if expectedType != ANY_ADDRESS_TYPE && addressType != expectedType {
+		return Address{}, fmt.Errorf("address is a %s, not a %s", addressType, expectedType)
+	}
+
+	switch addressType {
+	case COIN_ADDRESS_TYPE:
+		return NewCoinAddress(encoded.Data).Address, nil
+	case CRYPTO_ADDRESS_TYPE:
+		return NewCryptoAddress(encoded.Data).Address, nil
+	case ABEL_ADDRESS_TYPE:
+		return NewAbelAddress(encoded.Data).Address, nil
+	case SHORT_ABEL_ADDRESS_TYPE:
+		return NewShortAbelAddress(encoded.Data).Address, nil
+	default:
+		return Address{}, fmt.Errorf("address type %s cannot be unmarshaled", addressType)
+	}
+}
+
+func decodeAddressHex(s string, addressType AddressType, expectedLength int) (Bytes, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%s hex is not valid: %s", addressType, err)
+	}
+	if len(data) != expectedLength {
+		return nil, fmt.Errorf("%s data length %d is not %d", addressType, len(data), expectedLength)
+	}
+
+	return AsBytes(data), nil
+}
+
+func ShortAddresses(addrs []*AbelAddress) ([]*ShortAbelAddress, error) {
+	shortAddresses := make([]*ShortAbelAddress, 0, len(addrs))
+	for i, addr := range addrs {
+		if addr == nil {
+			return nil, fmt.Errorf("abel address %d is nil", i)
+		}
+
+		err := addr.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("abel address %d is invalid: %s", i, err)
+		}
+
+		shortAddresses = append(shortAddresses, addr.GetShortAbelAddress())
+	}
+
+	return shortAddresses, nil
+}
+
+func SameOwner(coinAddress *CoinAddress, abelAddress *AbelAddress) bool {
+	if coinAddress == nil || abelAddress == nil || abelAddress.Data().Len() != ABEL_ADDRESS_LENGTH {
+		return false
+	}
+
+	coinAddressData, err := api.ExtractCoinAddressFromCryptoAddress(abelAddress.GetCryptoAddress().Data())
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(coinAddressData, coinAddress.Data())
 }
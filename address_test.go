@@ -0,0 +1,420 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestAbelAddress returns the abel address of fresh keys on chainID.
+func newTestAbelAddress(t testing.TB, chainID int8) *AbelAddress {
+	t.Helper()
+
+	keys := newTestKeys(t)
+	return NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, chainID)
+}
+
+// expectAddressError fails t unless err is an *AddressError for addressType.
+func expectAddressError(t *testing.T, err error, addressType AddressType) {
+	t.Helper()
+
+	var addressErr *AddressError
+	if !errors.As(err, &addressErr) {
+		t.Fatalf("got error %v, want an *AddressError", err)
+	}
+	if addressErr.Type != addressType {
+		t.Errorf("got an error for a %s, want one for a %s", addressErr.Type, addressType)
+	}
+}
+
+func TestParseAbelAddress(t *testing.T) {
+	abelAddress := newTestAbelAddress(t, 2)
+	data := abelAddress.Data().Slice()
+
+	for _, tc := range []struct {
+		name  string
+		input string
+	}{
+		{"hex", abelAddress.HexString()},
+		{"hex with prefix", "0x" + abelAddress.HexString()},
+		{"hex with spaces", "  " + abelAddress.HexString() + "\n"},
+		{"base64", base64.StdEncoding.EncodeToString(data)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := ParseAbelAddress(tc.input)
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if !parsed.Equal(abelAddress) || parsed.GetChainID() != 2 {
+				t.Errorf("got address %s on chain %d, want the original", parsed.HexString(), parsed.GetChainID())
+			}
+		})
+	}
+
+	badChecksum := append([]byte(nil), data...)
+	badChecksum[len(badChecksum)-1] ^= 0xff
+	badChainID := append([]byte(nil), data...)
+	badChainID[0] = 0xff
+	badCryptoAddress := append([]byte(nil), abelAddress.GetCryptoAddress().Data().Slice()...)
+	for i := 0; i < 4; i++ {
+		badCryptoAddress[i] = 0xff
+	}
+	for _, tc := range []struct {
+		name   string
+		input  string
+		reason string
+	}{
+		{"empty", " ", "input is empty"},
+		{"neither hex nor base64", "not an address", "neither hex nor base64"},
+		{"too short", abelAddress.HexString()[2:], "data length"},
+		{"too long", abelAddress.HexString() + "00", "data length"},
+		{"bad checksum", AsBytes(badChecksum).HexString(), "checksum"},
+		{"bad chain id", AsBytes(badChainID).HexString(), "chain id"},
+		{"bad crypto address", NewAbelAddressFromCryptoAddress(NewCryptoAddress(AsBytes(badCryptoAddress)), 2).HexString(), "crypto address"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := ParseAbelAddress(tc.input)
+			if parsed != nil {
+				t.Errorf("got address %s, want none", parsed.HexString())
+			}
+			expectAddressError(t, err, ABEL_ADDRESS_TYPE)
+			if err != nil && !strings.Contains(err.Error(), tc.reason) {
+				t.Errorf("got error %q, want it to mention %q", err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestParseShortAbelAddress(t *testing.T) {
+	shortAddress := newTestAbelAddress(t, 3).GetShortAbelAddress()
+
+	for _, input := range []string{
+		shortAddress.HexString(),
+		"0x" + shortAddress.HexString(),
+		base64.StdEncoding.EncodeToString(shortAddress.Data().Slice()),
+	} {
+		parsed, err := ParseShortAbelAddress(input)
+		if err != nil {
+			t.Fatalf("%s: got error %s", input, err)
+		}
+		if !parsed.Equal(shortAddress) || parsed.GetChainID() != 3 {
+			t.Errorf("%s: got address %s on chain %d, want the original", input, parsed.HexString(), parsed.GetChainID())
+		}
+	}
+
+	badPrefix := append([]byte(nil), shortAddress.Data().Slice()...)
+	badPrefix[0] = 0x00
+	for _, input := range []string{"", "0xzz", shortAddress.HexString()[2:], AsBytes(badPrefix).HexString()} {
+		parsed, err := ParseShortAbelAddress(input)
+		if parsed != nil {
+			t.Errorf("%q: got address %s, want none", input, parsed.HexString())
+		}
+		expectAddressError(t, err, SHORT_ABEL_ADDRESS_TYPE)
+	}
+}
+
+func TestNewAddressFromHex(t *testing.T) {
+	keys := newTestKeys(t)
+	abelAddress := NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, 0)
+	coinAddress, err := keys.CryptoAddress.GetCoinAddress()
+	if err != nil {
+		t.Fatalf("cannot get the coin address: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		hex     string
+		fromHex func(s string) (interface{ HexString() string }, error)
+	}{
+		{"coin address", coinAddress.HexString(), func(s string) (interface{ HexString() string }, error) {
+			return NewCoinAddressFromHex(s)
+		}},
+		{"crypto address", keys.CryptoAddress.HexString(), func(s string) (interface{ HexString() string }, error) {
+			return NewCryptoAddressFromHex(s)
+		}},
+		{"abel address", abelAddress.HexString(), func(s string) (interface{ HexString() string }, error) {
+			return NewAbelAddressFromHex(s)
+		}},
+		{"short abel address", abelAddress.GetShortAbelAddress().HexString(), func(s string) (interface{ HexString() string }, error) {
+			return NewShortAbelAddressFromHex(s)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			address, err := tc.fromHex(" 0x" + tc.hex + " ")
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if address.HexString() != tc.hex {
+				t.Errorf("got %s, want %s", address.HexString(), tc.hex)
+			}
+
+			for _, input := range []string{"zz" + tc.hex[2:], tc.hex[2:], tc.hex + "00"} {
+				_, err := tc.fromHex(input)
+				if err == nil {
+					t.Errorf("got no error for %.16s...", input)
+				}
+			}
+		})
+	}
+}
+
+func TestMalformedCryptoAddress(t *testing.T) {
+	keys := newTestKeys(t)
+	unknownScheme := make([]byte, CRYPTO_ADDRESS_LENGTH)
+	copy(unknownScheme, keys.CryptoAddress.Data())
+	copy(unknownScheme, []byte{0xff, 0xff, 0xff, 0xff})
+
+	for name, data := range map[string][]byte{
+		"unknown crypto scheme": unknownScheme,
+		"truncated":             keys.CryptoAddress.Data()[:CRYPTO_ADDRESS_LENGTH-1],
+		"scheme only":           keys.CryptoAddress.Data()[:4],
+		"shorter than a scheme": {0x00, 0x00},
+		"empty":                 {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cryptoAddress := NewCryptoAddress(data)
+			if _, err := cryptoAddress.GetCoinAddress(); err == nil {
+				t.Errorf("got a coin address")
+			}
+			if err := cryptoAddress.Validate(); err == nil {
+				t.Errorf("malformed crypto address is valid")
+			}
+			if fingerprint := cryptoAddress.Fingerprint(); fingerprint.Len() != 0 {
+				t.Errorf("got fingerprint %s", fingerprint)
+			}
+
+			// An abel address around it is just as invalid, and has no fingerprint either.
+			abelAddress := NewAbelAddressFromCryptoAddress(cryptoAddress, 0)
+			if err := abelAddress.Validate(); err == nil {
+				t.Errorf("abel address of a malformed crypto address is valid")
+			}
+			if fingerprint := abelAddress.Fingerprint(); fingerprint.Len() != 0 {
+				t.Errorf("got abel address fingerprint %s", fingerprint)
+			}
+		})
+	}
+}
+
+func TestAddressJSONRoundTrip(t *testing.T) {
+	keys := newTestKeys(t)
+	abelAddress := NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, 1)
+	coinAddress, err := keys.CryptoAddress.GetCoinAddress()
+	if err != nil {
+		t.Fatalf("cannot get the coin address: %s", err)
+	}
+	fingerprint := coinAddress.Fingerprint()
+
+	// Each concrete type unmarshals into itself, and into a bare Address, with the data and fingerprint intact.
+	for _, tc := range []struct {
+		name      string
+		address   *Address
+		unmarshal func(data []byte) (*Address, error)
+	}{
+		{"coin address", &coinAddress.Address, func(data []byte) (*Address, error) {
+			address := &CoinAddress{}
+			return &address.Address, json.Unmarshal(data, address)
+		}},
+		{"crypto address", &keys.CryptoAddress.Address, func(data []byte) (*Address, error) {
+			address := &CryptoAddress{}
+			return &address.Address, json.Unmarshal(data, address)
+		}},
+		{"abel address", &abelAddress.Address, func(data []byte) (*Address, error) {
+			address := &AbelAddress{}
+			return &address.Address, json.Unmarshal(data, address)
+		}},
+		{"short abel address", &abelAddress.GetShortAbelAddress().Address, func(data []byte) (*Address, error) {
+			address := &ShortAbelAddress{}
+			return &address.Address, json.Unmarshal(data, address)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.address)
+			if err != nil {
+				t.Fatalf("cannot marshal: %s", err)
+			}
+
+			for name, unmarshal := range map[string]func(data []byte) (*Address, error){
+				"concrete": tc.unmarshal,
+				"bare": func(data []byte) (*Address, error) {
+					address := &Address{}
+					return address, json.Unmarshal(data, address)
+				},
+			} {
+				address, err := unmarshal(data)
+				if err != nil {
+					t.Fatalf("%s: cannot unmarshal: %s", name, err)
+				}
+				if !address.Equal(tc.address) {
+					t.Errorf("%s: got a %s that is not equal to the marshaled one", name, address.Type())
+				}
+				if !bytes.Equal(address.Fingerprint(), fingerprint) {
+					t.Errorf("%s: got fingerprint %s, want %s", name, address.Fingerprint(), fingerprint)
+				}
+			}
+		})
+	}
+
+	// A struct field of a concrete type round trips as well.
+	type payee struct {
+		Address *AbelAddress `json:"address"`
+	}
+	data, err := json.Marshal(&payee{Address: abelAddress})
+	if err != nil {
+		t.Fatalf("cannot marshal the payee: %s", err)
+	}
+	decoded := &payee{}
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatalf("cannot unmarshal the payee: %s", err)
+	}
+	if !decoded.Address.Equal(abelAddress) || decoded.Address.GetChainID() != 1 {
+		t.Errorf("payee address did not round trip")
+	}
+}
+
+func TestAddressJSONTypeMismatch(t *testing.T) {
+	keys := newTestKeys(t)
+	data, err := json.Marshal(&keys.CryptoAddress)
+	if err != nil {
+		t.Fatalf("cannot marshal: %s", err)
+	}
+
+	for name, address := range map[string]json.Unmarshaler{
+		"coin address":       &CoinAddress{},
+		"abel address":       &AbelAddress{},
+		"short abel address": &ShortAbelAddress{},
+	} {
+		if err := json.Unmarshal(data, address); err == nil {
+			t.Errorf("unmarshaled a crypto address into a %s", name)
+		}
+	}
+
+	for _, data := range []string{`{"type":"NoSuchAddress","data":"00"}`, `{"type":"CryptoAddress","data":"zz"}`, `"00"`} {
+		if err := json.Unmarshal([]byte(data), &CryptoAddress{}); err == nil {
+			t.Errorf("unmarshaled %s", data)
+		}
+	}
+}
+
+func BenchmarkNewAbelAddress(b *testing.B) {
+	data := newTestAbelAddress(b, 0).Data()
+
+	// The fingerprint is derived on first use, so constructing an address without using it stays cheap.
+	b.Run("fingerprint unused", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewAbelAddress(data)
+		}
+	})
+	b.Run("fingerprint used", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewAbelAddress(data).Fingerprint()
+		}
+	})
+}
+
+func BenchmarkAbelAddressValidate(b *testing.B) {
+	address := newTestAbelAddress(b, 0)
+
+	b.Run("shallow", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := address.ValidateShallow()
+			if err != nil {
+				b.Fatalf("got error %s", err)
+			}
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := address.ValidateFull()
+			if err != nil {
+				b.Fatalf("got error %s", err)
+			}
+		}
+	})
+}
+
+func TestShortAddresses(t *testing.T) {
+	addrs := []*AbelAddress{newTestAbelAddress(t, 0), newTestAbelAddress(t, 1), newTestAbelAddress(t, 14)}
+
+	shortAddresses, err := ShortAddresses(addrs)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(shortAddresses) != len(addrs) {
+		t.Fatalf("got %d short addresses for %d addresses", len(shortAddresses), len(addrs))
+	}
+	for i, shortAddress := range shortAddresses {
+		if shortAddress.GetChainID() != addrs[i].GetChainID() {
+			t.Errorf("address %d: got chain id %d, want %d", i, shortAddress.GetChainID(), addrs[i].GetChainID())
+		}
+		if !bytes.Equal(shortAddress.Fingerprint(), addrs[i].Fingerprint()) {
+			t.Errorf("address %d: got another fingerprint", i)
+		}
+		if !bytes.Equal(shortAddress.Data(), addrs[i].GetShortAbelAddress().Data()) {
+			t.Errorf("address %d: got %s, want %s", i, shortAddress.HexString(), addrs[i].GetShortAbelAddress().HexString())
+		}
+	}
+
+	corrupted := NewAbelAddress(append(Bytes(nil), addrs[1].Data()...))
+	corrupted.Data()[corrupted.Data().Len()-1] ^= 0xff
+	for name, invalid := range map[string][]*AbelAddress{
+		"nil address":      {addrs[0], nil},
+		"invalid checksum": {addrs[0], corrupted},
+		"truncated":        {NewAbelAddress(addrs[0].Data()[:100])},
+	} {
+		_, err := ShortAddresses(invalid)
+		if err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+
+	shortAddresses, err = ShortAddresses(nil)
+	if err != nil || len(shortAddresses) != 0 {
+		t.Errorf("got %d short addresses and error %v without addresses", len(shortAddresses), err)
+	}
+}
+
+func TestSameOwner(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	_, coin := newTestBuilderCoin(t, keys, 5000)
+
+	// The coin address of a received output is matched against the address it was paid to.
+	outputCoinAddress, err := coin.ToTxInDesc().GetCoinAddress()
+	if err != nil {
+		t.Fatalf("cannot decode the coin address of the output: %s", err)
+	}
+	derivedCoinAddress, err := keys.CryptoAddress.GetCoinAddress()
+	if err != nil {
+		t.Fatalf("cannot derive the coin address: %s", err)
+	}
+	address := NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, 0)
+	otherAddress := NewAbelAddressFromCryptoAddress(&otherKeys.CryptoAddress, 0)
+
+	tests := []struct {
+		name        string
+		coinAddress *CoinAddress
+		abelAddress *AbelAddress
+		want        bool
+	}{
+		{"output paid to the address", outputCoinAddress, address, true},
+		{"derived from the same keys", derivedCoinAddress, address, true},
+		{"same keys on another chain", outputCoinAddress, NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, 1), true},
+		{"output paid to another address", outputCoinAddress, otherAddress, false},
+		{"truncated abel address", outputCoinAddress, NewAbelAddress(address.Data()[:100]), false},
+		{"nil coin address", nil, address, false},
+		{"nil abel address", outputCoinAddress, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SameOwner(test.coinAddress, test.abelAddress); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Define constants.
+const (
+	DEFAULT_BATCH_CONCURRENCY = 4
+)
+
+// ErrBatchAborted is the error of the batch items that were not attempted because an earlier item failed
+// with an error that every other item would fail with as well, such as ErrUnauthorized.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// ErrBatchNotSupported is returned by BatchCall when the node rejects a JSON-RPC batch request as a whole,
+// as abec nodes that only parse single requests do.
+var ErrBatchNotSupported = errors.New("json-rpc batch requests are not supported by the node")
+
+// Define the batch call data types.
+type AbecRPCCall struct {
+	Method string
+	Params []interface{}
+}
+
+type AbecRPCCallResult struct {
+	Result Bytes
+	Err    error
+}
+
+// Define the batch result data types.
+// Batch fetches do not abort on the failure of a single key. Each requested key is paired with either its
+// value or the error that occurred for it, in request order, so that callers can retry just the failed keys.
+// Only a fatal error, one that is not about the key, stops the batch: requests in flight are cancelled, and
+// they and the keys that were not yet requested fail with ErrBatchAborted.
+type BlockFetchResult struct {
+	Height int64
+	Bytes  Bytes
+	Err    error
+}
+
+type BlockByHeightResult struct {
+	Height int64
+	Block  *AbecBlock
+	Err    error
+}
+
+type TxFetchResult struct {
+	TxID string
+	Tx   *AbecTx
+	Err  error
+}
+
+// BatchError reports which keys of a batch failed. It wraps the error of the first failed key.
+type BatchError struct {
+	FailedKeys []string
+	Errs       []error
+}
+
+func (e *BatchError) Error() string {
+	if len(e.FailedKeys) == 1 {
+		return fmt.Sprintf("batch item %s failed: %s", e.FailedKeys[0], e.Errs[0])
+	}
+
+	return fmt.Sprintf("%d batch items failed, first %s: %s", len(e.FailedKeys), e.FailedKeys[0], e.Errs[0])
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Errs[0]
+}
+
+// Define methods for AbecRPCClient.
+func (client *AbecRPCClient) GetRawTxs(txids []string, concurrency ...int) []*TxFetchResult {
+	return client.GetRawTxsContext(context.Background(), txids, concurrency...)
+}
+
+func (client *AbecRPCClient) GetRawTxsContext(ctx context.Context, txids []string, concurrency ...int) []*TxFetchResult {
+	results := make([]*TxFetchResult, len(txids))
+	fatalErr := runBatch(ctx, len(txids), concurrency, func(ctx context.Context, i int) error {
+		_, tx, err := client.GetRawTxContext(ctx, txids[i])
+		results[i] = &TxFetchResult{TxID: txids[i], Tx: tx, Err: err}
+		return err
+	})
+
+	for i, result := range results {
+		if result == nil || isAbortedByBatch(result.Err, fatalErr) {
+			results[i] = &TxFetchResult{TxID: txids[i], Err: abortedBatchError(fatalErr)}
+		}
+	}
+
+	return results
+}
+
+// BatchCall posts calls as a single JSON-RPC batch request. The results are in the order of calls, matched to
+// the responses by id whatever order the node answers in, and a call that failed, or got no response, has its
+// own error. The returned error is only set when the batch as a whole failed.
+func (client *AbecRPCClient) BatchCall(calls []*AbecRPCCall) ([]*AbecRPCCallResult, error) {
+	return client.BatchCallContext(context.Background(), calls)
+}
+
+func (client *AbecRPCClient) BatchCallContext(ctx context.Context, calls []*AbecRPCCall) ([]*AbecRPCCallResult, error) {
+	if len(calls) == 0 {
+		return make([]*AbecRPCCallResult, 0), nil
+	}
+
+	id := client.newRequestID()
+	jsonReqs := make([]*AbecJSONRPCRequest, len(calls))
+	indexes := make(map[string]int, len(calls))
+	retryable := true
+	for i, call := range calls {
+		callID := fmt.Sprintf("%s-%d", id, i)
+		jsonReqs[i] = client.newJSONRPCRequest(callID, call.Method, call.Params)
+		indexes[callID] = i
+		retryable = retryable && !isSubmissionMethod(call.Method)
+	}
+
+	var respObjs []*AbecJSONRPCResponse
+	err := client.retry(ctx, "batch", func() (bool, error) {
+		var err error
+		var transient bool
+		respObjs, transient, err = client.batchCallOnce(ctx, id, jsonReqs)
+		return transient && retryable, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*AbecRPCCallResult, len(calls))
+	for _, respObj := range respObjs {
+		i, ok := indexes[string(respObj.ID)]
+		if !ok || results[i] != nil {
+			LOG.debug("Response(%s): ignored unexpected id %q\n", id, respObj.ID)
+			continue
+		}
+
+		if isJSONRPCError(respObj.Error) {
+			results[i] = &AbecRPCCallResult{Err: newJSONRPCError(calls[i].Method, respObj.Error)}
+		} else {
+			results[i] = &AbecRPCCallResult{Result: AsBytes(respObj.Result)}
+		}
+	}
+	for i, result := range results {
+		if result == nil {
+			results[i] = &AbecRPCCallResult{Err: fmt.Errorf("abec.%s: no response in batch", calls[i].Method)}
+		}
+	}
+
+	return results, nil
+}
+
+func (client *AbecRPCClient) batchCallOnce(ctx context.Context, id string, jsonReqs []*AbecJSONRPCRequest) ([]*AbecJSONRPCResponse, bool, error) {
+	LOG.debug("Request(%s): batch of %d calls\n", id, len(jsonReqs))
+	resp, err := client.post(ctx, id, jsonReqs)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		LOG.debug("Response(%s): ERROR(%s)\n", id, resp.Status)
+		return nil, false, fmt.Errorf("%w: batch: %s", ErrUnauthorized, resp.Status)
+	}
+	transient := resp.StatusCode >= http.StatusInternalServerError
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		LOG.debug("Response(%s): ERROR(%s)\n", id, err)
+		return nil, transient, err
+	}
+	LOG.debug("Response(%s): %s\n", id, LOG.value(string(body)))
+
+	// A node without batch support answers the whole array with a single error response.
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		respObj := &AbecJSONRPCResponse{}
+		if err := json.Unmarshal(trimmed, respObj); err == nil && isJSONRPCError(respObj.Error) {
+			return nil, false, fmt.Errorf("%w: %s", ErrBatchNotSupported, parseJSONRPCError(respObj.Error).description())
+		}
+	}
+
+	respObjs := make([]*AbecJSONRPCResponse, 0, len(jsonReqs))
+	err = json.Unmarshal(trimmed, &respObjs)
+	if err != nil {
+		if transient {
+			return nil, true, fmt.Errorf("batch: %s", resp.Status)
+		}
+		return nil, false, fmt.Errorf("batch response is not a json array: %s", err)
+	}
+
+	return respObjs, false, nil
+}
+
+// batchCallOrEach makes calls as a batch, or as concurrent single calls if the node does not support batches.
+func (client *AbecRPCClient) batchCallOrEach(ctx context.Context, calls []*AbecRPCCall) ([]*AbecRPCCallResult, error) {
+	results, err := client.BatchCallContext(ctx, calls)
+	if !errors.Is(err, ErrBatchNotSupported) {
+		return results, err
+	}
+
+	LOG.debug("Falling back to single calls: %s\n", err)
+	results = make([]*AbecRPCCallResult, len(calls))
+	fatalErr := runBatch(ctx, len(calls), nil, func(ctx context.Context, i int) error {
+		result, err := client.callForBytes(ctx, calls[i].Method, calls[i].Params)
+		results[i] = &AbecRPCCallResult{Result: result, Err: err}
+		return err
+	})
+	for i, result := range results {
+		if result == nil || isAbortedByBatch(result.Err, fatalErr) {
+			results[i] = &AbecRPCCallResult{Err: abortedBatchError(fatalErr)}
+		}
+	}
+
+	return results, nil
+}
+
+// GetBlocksByHeightRange fetches the blocks from start to end inclusive with two batch requests, one for the
+// hashes and one for the blocks. Each height has its own result, so that the failed ones can be retried.
+func (client *AbecRPCClient) GetBlocksByHeightRange(start int64, end int64) ([]*BlockByHeightResult, error) {
+	return client.GetBlocksByHeightRangeContext(context.Background(), start, end)
+}
+
+func (client *AbecRPCClient) GetBlocksByHeightRangeContext(ctx context.Context, start int64, end int64) ([]*BlockByHeightResult, error) {
+	if err := validateHeightParam(start); err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("height range %d to %d is empty", start, end)
+	}
+
+	results := make([]*BlockByHeightResult, 0, end-start+1)
+	hashCalls := make([]*AbecRPCCall, 0, end-start+1)
+	for height := start; height <= end; height++ {
+		results = append(results, &BlockByHeightResult{Height: height})
+		hashCalls = append(hashCalls, &AbecRPCCall{Method: "getblockhash", Params: []interface{}{height}})
+	}
+	hashResults, err := client.batchCallOrEach(ctx, hashCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	blockCalls := make([]*AbecRPCCall, 0, len(hashResults))
+	blockResults := make([]*BlockByHeightResult, 0, len(hashResults))
+	for i, hashResult := range hashResults {
+		if hashResult.Err != nil {
+			results[i].Err = wrapHeightNotFound(results[i].Height, hashResult.Err)
+			continue
+		}
+
+		var hash string
+		if err := hashResult.Result.JSONUnmarshal(&hash); err != nil {
+			results[i].Err = err
+			continue
+		}
+		blockCalls = append(blockCalls, &AbecRPCCall{Method: "getblockabe", Params: []interface{}{hash, 1}})
+		blockResults = append(blockResults, results[i])
+	}
+
+	callResults, err := client.batchCallOrEach(ctx, blockCalls)
+	if err != nil {
+		return nil, err
+	}
+	for i, callResult := range callResults {
+		if callResult.Err != nil {
+			blockResults[i].Err = callResult.Err
+			continue
+		}
+
+		block := &AbecBlock{}
+		if err := callResult.Result.JSONUnmarshal(block); err != nil {
+			blockResults[i].Err = err
+			continue
+		}
+		blockResults[i].Block = block
+	}
+
+	return results, nil
+}
+
+// Define util functions.
+func FetchBlocksConcurrent(client *AbecRPCClient, heights []int64, concurrency ...int) []*BlockFetchResult {
+	return FetchBlocksConcurrentContext(context.Background(), client, heights, concurrency...)
+}
+
+func FetchBlocksConcurrentContext(ctx context.Context, client *AbecRPCClient, heights []int64, concurrency ...int) []*BlockFetchResult {
+	results := make([]*BlockFetchResult, len(heights))
+	fatalErr := runBatch(ctx, len(heights), concurrency, func(ctx context.Context, i int) error {
+		blockBytes, err := client.GetBlockBytesByHeightContext(ctx, heights[i])
+		results[i] = &BlockFetchResult{Height: heights[i], Bytes: blockBytes, Err: err}
+		return err
+	})
+
+	for i, result := range results {
+		if result == nil || isAbortedByBatch(result.Err, fatalErr) {
+			results[i] = &BlockFetchResult{Height: heights[i], Err: abortedBatchError(fatalErr)}
+		}
+	}
+
+	return results
+}
+
+func FailedHeights(results []*BlockFetchResult) []int64 {
+	heights := make([]int64, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			heights = append(heights, result.Height)
+		}
+	}
+
+	return heights
+}
+
+// BlockFetchError returns a *BatchError listing the failed heights, or nil if every fetch succeeded.
+func BlockFetchError(results []*BlockFetchResult) error {
+	batchErr := &BatchError{}
+	for _, result := range results {
+		if result.Err != nil {
+			batchErr.FailedKeys = append(batchErr.FailedKeys, fmt.Sprintf("height %d", result.Height))
+			batchErr.Errs = append(batchErr.Errs, result.Err)
+		}
+	}
+	if len(batchErr.Errs) == 0 {
+		return nil
+	}
+
+	return batchErr
+}
+
+// TxFetchError returns a *BatchError listing the failed txids, or nil if every fetch succeeded.
+func TxFetchError(results []*TxFetchResult) error {
+	batchErr := &BatchError{}
+	for _, result := range results {
+		if result.Err != nil {
+			batchErr.FailedKeys = append(batchErr.FailedKeys, fmt.Sprintf("tx %s", result.TxID))
+			batchErr.Errs = append(batchErr.Errs, result.Err)
+		}
+	}
+	if len(batchErr.Errs) == 0 {
+		return nil
+	}
+
+	return batchErr
+}
+
+func isFatalBatchError(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+func abortedBatchError(fatalErr error) error {
+	return fmt.Errorf("%w: %s", ErrBatchAborted, fatalErr)
+}
+
+// isAbortedByBatch reports whether err is the cancellation of an item in flight when fatalErr stopped the batch.
+func isAbortedByBatch(err error, fatalErr error) bool {
+	return fatalErr != nil && errors.Is(err, context.Canceled)
+}
+
+// runBatch calls run for the indexes 0 to n-1 on a bounded number of workers. Once run returns a fatal error,
+// no further indexes are started and the ctx given to the running calls is cancelled, and runBatch returns
+// that error once they have returned.
+func runBatch(ctx context.Context, n int, concurrency []int, run func(ctx context.Context, i int) error) error {
+	workers := DEFAULT_BATCH_CONCURRENCY
+	if len(concurrency) > 0 && concurrency[0] > 0 {
+		workers = concurrency[0]
+	}
+	if workers > n {
+		workers = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var fatalErr error
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+
+				err := run(ctx, index)
+				if isFatalBatchError(err) {
+					stopOnce.Do(func() {
+						fatalErr = err
+						close(stop)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-stop:
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return fatalErr
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchNodeRequest is a request to a batch node.
+type batchNodeRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     string            `json:"id"`
+}
+
+// newBatchNode returns a client of a node with blocks at heights 0 to tip, except for missingHeight, whose
+// hash lookup fails. If batches is true, it answers a batch in reverse order, and otherwise it rejects
+// batches like an abec node that only parses single requests.
+func newBatchNode(t *testing.T, tip int64, missingHeight int64, batches bool) *AbecRPCClient {
+	t.Helper()
+
+	answer := func(req *batchNodeRequest) map[string]interface{} {
+		resp := map[string]interface{}{"id": req.ID, "result": nil, "error": nil}
+		switch req.Method {
+		case "getblockhash":
+			var height int64
+			json.Unmarshal(req.Params[0], &height)
+			if height < 0 || height > tip || height == missingHeight {
+				resp["error"] = &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+			} else {
+				resp["result"] = testBlockHash(fmt.Sprintf("%02x", height))
+			}
+		case "getblockabe":
+			var hash string
+			json.Unmarshal(req.Params[0], &hash)
+			var height int64
+			fmt.Sscanf(hash[:2], "%02x", &height)
+			resp["result"] = &AbecBlock{Height: height, BlockHash: hash}
+		default:
+			resp["error"] = &AbecJSONRPCError{Code: -32601, Message: "Method not found"}
+		}
+		return resp
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			if !batches {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"id": nil, "result": nil, "error": &AbecJSONRPCError{Code: -32700, Message: "Parse error"},
+				})
+				return
+			}
+
+			var reqs []*batchNodeRequest
+			json.Unmarshal(body, &reqs)
+			resps := make([]map[string]interface{}, 0, len(reqs))
+			for i := len(reqs) - 1; i >= 0; i-- {
+				resps = append(resps, answer(reqs[i]))
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+
+		req := &batchNodeRequest{}
+		json.Unmarshal(body, req)
+		json.NewEncoder(w).Encode(answer(req))
+	}))
+	t.Cleanup(server.Close)
+
+	return NewAbecRPCClient(server.URL, "", "")
+}
+
+func TestFetchBlocksConcurrentCancelsOnAuthError(t *testing.T) {
+	const unauthorizedHeight = 3
+
+	var mutex sync.Mutex
+	inFlight := 0
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var height int64
+		json.Unmarshal(req.Params[0], &height)
+		if height == unauthorizedHeight {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// The other heights hang until their request is cancelled.
+		mutex.Lock()
+		inFlight++
+		mutex.Unlock()
+		defer func() {
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+		}()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+	client := NewAbecRPCClient(server.URL, "", "")
+
+	heights := []int64{0, 1, 2, unauthorizedHeight, 4, 5, 6, 7, 8, 9}
+	start := time.Now()
+	results := FetchBlocksConcurrentContext(context.Background(), client, heights, 4)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("fetch returned after %s, long after the auth error", elapsed)
+	}
+
+	for i, result := range results {
+		if result.Height != heights[i] {
+			t.Errorf("result %d is for height %d, want %d", i, result.Height, heights[i])
+		}
+		if result.Height == unauthorizedHeight {
+			if !errors.Is(result.Err, ErrUnauthorized) {
+				t.Errorf("got error %v for height %d, want ErrUnauthorized", result.Err, result.Height)
+			}
+		} else if !errors.Is(result.Err, ErrBatchAborted) {
+			t.Errorf("got error %v for height %d, want ErrBatchAborted", result.Err, result.Height)
+		}
+	}
+
+	// The cancelled requests do not outlive the fetch.
+	waitFor(t, "the cancelled requests", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return inFlight == 0
+	})
+}
+
+func TestBatchCallMatchesResponsesByID(t *testing.T) {
+	client := newBatchNode(t, 5, 2, true)
+
+	calls := make([]*AbecRPCCall, 0)
+	for height := 0; height <= 3; height++ {
+		calls = append(calls, &AbecRPCCall{Method: "getblockhash", Params: []interface{}{height}})
+	}
+	results, err := client.BatchCallContext(context.Background(), calls)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("got %d results, want %d", len(results), len(calls))
+	}
+	for height, result := range results {
+		if height == 2 {
+			var rpcErr *AbecRPCError
+			if !errors.As(result.Err, &rpcErr) || !rpcErr.IsHeightOutOfRange() {
+				t.Errorf("got error %v for height 2, want the node's out of range error", result.Err)
+			}
+			continue
+		}
+
+		var hash string
+		if result.Err != nil || result.Result.JSONUnmarshal(&hash) != nil || hash != testBlockHash(fmt.Sprintf("%02x", height)) {
+			t.Errorf("got hash %q and error %v for height %d, want its own hash", hash, result.Err, height)
+		}
+	}
+
+	_, err = client.BatchCallContext(context.Background(), []*AbecRPCCall{{Method: "getblockhash", Params: []interface{}{0}}})
+	if err != nil {
+		t.Errorf("got error %s for a batch of one call", err)
+	}
+}
+
+func TestBatchCallIgnoresUnexpectedIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*batchNodeRequest
+		json.NewDecoder(r.Body).Decode(&reqs)
+
+		// Answer in reverse order, with a response for an id that was never sent, a numeric id and a
+		// second response for the first call.
+		resps := []string{`{"result":"unknown","error":null,"id":"no-such-id"}`, `{"result":"numeric","error":null,"id":0}`}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			resps = append(resps, fmt.Sprintf(`{"result":%s,"error":null,"id":%q}`, reqs[i].Params[0], reqs[i].ID))
+		}
+		resps = append(resps, fmt.Sprintf(`{"result":"duplicate","error":null,"id":%q}`, reqs[0].ID))
+		fmt.Fprintf(w, "[%s]", strings.Join(resps, ","))
+	}))
+	t.Cleanup(server.Close)
+	client := NewAbecRPCClient(server.URL, "", "")
+
+	calls := make([]*AbecRPCCall, 0)
+	for i := 0; i < 3; i++ {
+		calls = append(calls, &AbecRPCCall{Method: "echo", Params: []interface{}{fmt.Sprintf("call%d", i)}})
+	}
+	results, err := client.BatchCall(calls)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	for i, result := range results {
+		var value string
+		if result.Err != nil || result.Result.JSONUnmarshal(&value) != nil || value != fmt.Sprintf("call%d", i) {
+			t.Errorf("got %q and error %v for call %d, want its own result", value, result.Err, i)
+		}
+	}
+}
+
+func TestBatchCallNotSupported(t *testing.T) {
+	client := newBatchNode(t, 5, 2, false)
+
+	_, err := client.BatchCall([]*AbecRPCCall{{Method: "getblockhash", Params: []interface{}{0}}})
+	if !errors.Is(err, ErrBatchNotSupported) {
+		t.Errorf("got error %v, want ErrBatchNotSupported", err)
+	}
+}
+
+func TestGetBlocksByHeightRange(t *testing.T) {
+	for _, batches := range []bool{true, false} {
+		t.Run(fmt.Sprintf("batches %t", batches), func(t *testing.T) {
+			client := newBatchNode(t, 4, 2, batches)
+
+			results, err := client.GetBlocksByHeightRangeContext(context.Background(), 0, 5)
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if len(results) != 6 {
+				t.Fatalf("got %d results, want 6", len(results))
+			}
+			for i, result := range results {
+				height := int64(i)
+				if result.Height != height {
+					t.Errorf("result %d is for height %d", i, result.Height)
+				}
+				switch height {
+				case 2, 5:
+					if !errors.Is(result.Err, ErrHeightNotFound) {
+						t.Errorf("got error %v for height %d, want ErrHeightNotFound", result.Err, height)
+					}
+				default:
+					if result.Err != nil || result.Block == nil || result.Block.Height != height {
+						t.Errorf("got block %+v and error %v for height %d, want its block", result.Block, result.Err, height)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBatchCallContextCancellation(t *testing.T) {
+	client := NewAbecRPCClient(newHangingServer(t).URL, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.BatchCallContext(ctx, []*AbecRPCCall{{Method: "getblockcount"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFetchBlocksConcurrentMarksOnlyTheFailedHeight(t *testing.T) {
+	const failedHeight = 3
+
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var height int64
+			json.Unmarshal(params[0], &height)
+			return testBlockHash(fmt.Sprintf("%02x", height)), nil
+		},
+		"getblockabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+			if hash == testBlockHash(fmt.Sprintf("%02x", failedHeight)) {
+				return nil, &AbecJSONRPCError{Code: -32603, Message: "Internal error"}
+			}
+			return hash[:2], nil
+		},
+	})
+
+	heights := []int64{0, 1, 2, failedHeight, 4, 5, 6}
+	results := FetchBlocksConcurrent(client, heights, 3)
+	for i, result := range results {
+		if result.Height != heights[i] {
+			t.Errorf("result %d is for height %d, want %d", i, result.Height, heights[i])
+		}
+		if result.Height == failedHeight {
+			if result.Err == nil {
+				t.Errorf("got no error for height %d", result.Height)
+			}
+			continue
+		}
+		if result.Err != nil || len(result.Bytes) != 1 || int64(result.Bytes[0]) != result.Height {
+			t.Errorf("got bytes %x and error %v for height %d, want its block", []byte(result.Bytes), result.Err, result.Height)
+		}
+	}
+
+	failed := FailedHeights(results)
+	if len(failed) != 1 || failed[0] != failedHeight {
+		t.Errorf("got failed heights %v, want [%d]", failed, failedHeight)
+	}
+	var batchErr *BatchError
+	if err := BlockFetchError(results); !errors.As(err, &batchErr) || len(batchErr.FailedKeys) != 1 {
+		t.Errorf("got error %v, want a batch error for height %d alone", err, failedHeight)
+	}
+}
+
+func TestGetRawTxsMarksOnlyTheFailedTx(t *testing.T) {
+	failedTxID := testBlockHash("33")
+
+	client := newMockNode(t, map[string]mockMethod{
+		"getrawtransaction": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var txid string
+			json.Unmarshal(params[0], &txid)
+			if txid == failedTxID {
+				return nil, &AbecJSONRPCError{Code: -5, Message: "No information available about transaction"}
+			}
+			return &AbecTx{TxID: txid}, nil
+		},
+	})
+
+	txids := []string{testBlockHash("11"), testBlockHash("22"), failedTxID, testBlockHash("44"), testBlockHash("55")}
+	results := client.GetRawTxs(txids, 2)
+	for i, result := range results {
+		if result.TxID != txids[i] {
+			t.Errorf("result %d is for tx %s, want %s", i, result.TxID, txids[i])
+		}
+		if result.TxID == failedTxID {
+			var rpcErr *AbecRPCError
+			if !errors.As(result.Err, &rpcErr) || !rpcErr.IsTxNotFound() {
+				t.Errorf("got error %v for the failed tx, want the node's not found error", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || result.Tx == nil || result.Tx.TxID != result.TxID {
+			t.Errorf("got tx %+v and error %v for tx %s, want its tx", result.Tx, result.Err, result.TxID)
+		}
+	}
+
+	var batchErr *BatchError
+	if err := TxFetchError(results); !errors.As(err, &batchErr) || len(batchErr.FailedKeys) != 1 || batchErr.FailedKeys[0] != "tx "+failedTxID {
+		t.Errorf("got error %v, want a batch error for the failed tx alone", err)
+	}
+}
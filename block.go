@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/abesuite/abec/abeutil"
+	"github.com/abesuite/abec/consensus/ethash"
+	api "github.com/abesuite/abec/sdkapi/v1"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define methods for AbecRPCClient.
+// GetBlockParsed fetches the raw block and decodes it locally instead of trusting the node's JSON rendering.
+// The decoded block hash is checked against the requested hash.
+//
+// Decoding costs CPU on the client: every transaction is deserialized and rehashed, which for a full block
+// is noticeably more work than unmarshalling the node's JSON. Fields that depend on the node's view of the
+// chain (Confirmations, NextBlockHash, Difficulty) and on witnesses, which raw blocks do not carry (FullSize
+// and the transactions' Witness), are left empty.
+func (client *AbecRPCClient) GetBlockParsed(hash string) (*AbecBlock, error) {
+	return client.GetBlockParsedContext(context.Background(), hash)
+}
+
+func (client *AbecRPCClient) GetBlockParsedContext(ctx context.Context, hash string) (*AbecBlock, error) {
+	blockBytes, err := client.GetBlockBytesContext(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	if block.BlockHash != hash {
+		return nil, fmt.Errorf("block %s returned by the node hashes to %s", hash, block.BlockHash)
+	}
+
+	return block, nil
+}
+
+// Define util functions.
+func DecodeAbecBlock(data Bytes) (*AbecBlock, error) {
+	parsedBlock, err := abeutil.NewBlockFromBytesAbe(data)
+	if err != nil {
+		return nil, fmt.Errorf("block cannot be parsed: %s", err)
+	}
+	err = api.GetAndSetHeight(parsedBlock)
+	if err != nil {
+		return nil, fmt.Errorf("block cannot be parsed: %s", err)
+	}
+
+	header := &parsedBlock.MsgBlock().Header
+	block := &AbecBlock{
+		Height:        int64(parsedBlock.Height()),
+		Version:       int64(header.Version),
+		VersionHex:    fmt.Sprintf("%08x", header.Version),
+		Time:          header.Timestamp.Unix(),
+		Nonce:         uint64(header.Nonce),
+		Size:          int64(data.Len()),
+		BlockHash:     parsedBlock.Hash().String(),
+		PrevBlockHash: header.PrevBlock.String(),
+		ContentHash:   header.ContentHash().String(),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Bits:          strconv.FormatInt(int64(header.Bits), 16),
+		SealHash:      ethash.SealHash(header).String(),
+		Mixdigest:     header.MixDigest.String(),
+	}
+	if header.Version >= int32(wire.BlockVersionEthashPow) {
+		block.Nonce = header.NonceExt
+	}
+
+	for _, tx := range parsedBlock.Transactions() {
+		rawTx, err := decodeAbecTx(tx.MsgTx(), block)
+		if err != nil {
+			return nil, err
+		}
+		block.TxHashes = append(block.TxHashes, rawTx.TxID)
+		block.RawTxs = append(block.RawTxs, rawTx)
+	}
+
+	return block, nil
+}
+
+// DecodeAbecBlockHeader decodes a serialized block header, as returned by getblockheader with verbose set to
+// false. Fields that depend on the node's view of the chain (Height, Confirmations, NextBlockHash and
+// Difficulty) are left empty.
+func DecodeAbecBlockHeader(data Bytes) (*AbecBlockHeader, error) {
+	header := &wire.BlockHeader{}
+	err := header.Deserialize(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("block header cannot be parsed: %s", err)
+	}
+
+	return &AbecBlockHeader{
+		Version:       int64(header.Version),
+		VersionHex:    fmt.Sprintf("%08x", header.Version),
+		Time:          header.Timestamp.Unix(),
+		Nonce:         uint64(header.Nonce),
+		BlockHash:     header.BlockHash().String(),
+		PrevBlockHash: header.PrevBlock.String(),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Bits:          strconv.FormatInt(int64(header.Bits), 16),
+		SealHash:      ethash.SealHash(header).String(),
+	}, nil
+}
+
+func decodeAbecTx(msgTx *wire.MsgTxAbe, block *AbecBlock) (*AbecTx, error) {
+	buffer := bytes.NewBuffer(make([]byte, 0, msgTx.SerializeSize()))
+	err := msgTx.Serialize(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	txid := msgTx.TxHash().String()
+	tx := &AbecTx{
+		Hex:       hex.EncodeToString(buffer.Bytes()),
+		TxID:      txid,
+		TxHash:    txid,
+		Time:      block.Time,
+		BlockHash: block.BlockHash,
+		BlockTime: block.Time,
+		Version:   int64(msgTx.Version),
+		Size:      int64(msgTx.SerializeSize()),
+		Memo:      hex.EncodeToString(msgTx.TxMemo),
+		Fee:       NeutrinoToAbel(int64(msgTx.TxFee)),
+	}
+
+	for _, txIn := range msgTx.TxIns {
+		vin := &AbecTxVin{SerialNumber: hex.EncodeToString(txIn.SerialNumber)}
+		for _, blockHash := range txIn.PreviousOutPointRing.BlockHashs {
+			vin.UTXORing.BlockHashes = append(vin.UTXORing.BlockHashes, blockHash.String())
+		}
+		for _, outPoint := range txIn.PreviousOutPointRing.OutPoints {
+			vin.UTXORing.OutPoints = append(vin.UTXORing.OutPoints, &AbecOutPoint{
+				TxHash: outPoint.TxHash.String(),
+				Index:  int64(outPoint.Index),
+			})
+		}
+		tx.Vin = append(tx.Vin, vin)
+	}
+
+	for i, txOut := range msgTx.TxOuts {
+		buffer := bytes.NewBuffer(make([]byte, 0, txOut.SerializeSize()))
+		err := wire.WriteTxOutAbe(buffer, 0, msgTx.Version, txOut)
+		if err != nil {
+			return nil, err
+		}
+		tx.Vout = append(tx.Vout, &AbecTxVout{N: int64(i), Script: hex.EncodeToString(buffer.Bytes())})
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abesuite/abec/abeutil"
+	"github.com/abesuite/abec/chainhash"
+	"github.com/abesuite/abec/consensus/ethash"
+	"github.com/abesuite/abec/wire"
+)
+
+// newHeaderNode returns a client of a node that serves header under the hash reportedHash.
+func newHeaderNode(t *testing.T, header *wire.BlockHeader, reportedHash string) *AbecRPCClient {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := header.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("cannot serialize the header: %s", err)
+	}
+
+	return newMockNode(t, map[string]mockMethod{
+		"getblockheader": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			if len(params) > 1 && string(params[1]) == "false" {
+				return hex.EncodeToString(buf.Bytes()), nil
+			}
+			return &AbecBlockHeader{Height: 5, Nonce: uint64(header.Nonce), BlockHash: reportedHash}, nil
+		},
+	})
+}
+
+func TestGetBlockHeaderComputesSealHash(t *testing.T) {
+	header := &wire.BlockHeader{
+		Version:    int32(wire.BlockVersionEthashPow),
+		PrevBlock:  chainhash.Hash{1},
+		MerkleRoot: chainhash.Hash{2},
+		Timestamp:  time.Unix(1700000000, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      7,
+		NonceExt:   0x0102030405060708,
+		MixDigest:  chainhash.Hash{3},
+	}
+	hash := header.BlockHash().String()
+
+	_, result, err := newHeaderNode(t, header, hash).GetBlockHeader(hash)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if want := ethash.SealHash(header).String(); result.SealHash != want {
+		t.Errorf("got seal hash %q, want %s", result.SealHash, want)
+	}
+	if result.Nonce != 7 {
+		t.Errorf("got nonce %d, want the 32-bit header nonce 7", result.Nonce)
+	}
+
+	otherHash := strings.Repeat("ab", 32)
+	_, _, err = newHeaderNode(t, header, otherHash).GetBlockHeader(otherHash)
+	if err == nil {
+		t.Errorf("got no error for a serialized header that does not hash to the requested block")
+	}
+}
+
+func TestAbecBlockHeaderFields(t *testing.T) {
+	chain, err := LoadSimulationChainFile(filepath.Join("testdata", "simulation_chain.json"))
+	if err != nil {
+		t.Fatalf("cannot load the fixture: %s", err)
+	}
+	client := NewSimulationClient(chain)
+	_, block, err := client.GetBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("cannot get block 1: %s", err)
+	}
+	blockBytes, err := client.GetBlockBytesByHeight(1)
+	if err != nil {
+		t.Fatalf("cannot get block 1: %s", err)
+	}
+	parsedBlock, err := abeutil.NewBlockFromBytesAbe(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot parse block 1: %s", err)
+	}
+	header := &parsedBlock.MsgBlock().Header
+
+	bits, err := block.BitsValue()
+	if err != nil || bits != header.Bits {
+		t.Errorf("got bits %08x and error %v, want %08x", bits, err, header.Bits)
+	}
+	if version := block.VersionValue(); version != int64(header.Version) {
+		t.Errorf("got version %d, want %d", version, header.Version)
+	}
+
+	// The hash accessors are in display order, the reverse of the internal order of the header.
+	blockHash := header.BlockHash()
+	for name, test := range map[string]struct {
+		get  func() (Bytes, error)
+		want chainhash.Hash
+	}{
+		"block hash":      {block.BlockHashBytes, blockHash},
+		"prev block hash": {block.PrevBlockHashBytes, header.PrevBlock},
+		"merkle root":     {block.MerkleRootBytes, header.MerkleRoot},
+		"content hash":    {block.ContentHashBytes, header.ContentHash()},
+		"seal hash":       {block.SealHashBytes, ethash.SealHash(header)},
+	} {
+		got, err := test.get()
+		if err != nil {
+			t.Errorf("%s: got error %s", name, err)
+			continue
+		}
+		if !bytes.Equal(got, reversedBytes(test.want[:])) {
+			t.Errorf("%s: got %s, want %s", name, got, test.want)
+		}
+	}
+}
+
+func TestGetBlockParsedMatchesGetBlock(t *testing.T) {
+	chain, err := LoadSimulationChainFile(filepath.Join("testdata", "simulation_chain.json"))
+	if err != nil {
+		t.Fatalf("cannot load the fixture: %s", err)
+	}
+	blockBytes, err := NewSimulationClient(chain).GetBlockBytesByHeight(1)
+	if err != nil {
+		t.Fatalf("cannot get block 1: %s", err)
+	}
+	parsedBlock, err := abeutil.NewBlockFromBytesAbe(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot parse block 1: %s", err)
+	}
+	header := &parsedBlock.MsgBlock().Header
+	hash := header.BlockHash().String()
+
+	// The node renders the block from its own view of the chain, with the fields a raw block does not carry.
+	nonce := uint64(header.Nonce)
+	if header.Version >= int32(wire.BlockVersionEthashPow) {
+		nonce = header.NonceExt
+	}
+	txHashes := make([]string, 0)
+	for _, tx := range parsedBlock.Transactions() {
+		txHashes = append(txHashes, tx.MsgTx().TxHash().String())
+	}
+	rendered := map[string]interface{}{
+		"hash":              hash,
+		"confirmations":     7,
+		"size":              blockBytes.Len(),
+		"fullsize":          blockBytes.Len() + 4096,
+		"height":            1,
+		"version":           header.Version,
+		"versionHex":        fmt.Sprintf("%08x", header.Version),
+		"merkleroot":        header.MerkleRoot.String(),
+		"contenthash":       header.ContentHash().String(),
+		"sealhash":          ethash.SealHash(header).String(),
+		"mixdigest":         header.MixDigest.String(),
+		"time":              header.Timestamp.Unix(),
+		"nonce":             nonce,
+		"bits":              fmt.Sprintf("%x", header.Bits),
+		"difficulty":        1.5,
+		"previousblockhash": header.PrevBlock.String(),
+		"nextblockhash":     testBlockHash("ee"),
+		"tx":                txHashes,
+	}
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			if string(params[1]) == "0" {
+				return blockBytes.HexString(), nil
+			}
+			return rendered, nil
+		},
+	})
+
+	parsed, err := client.GetBlockParsed(hash)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	_, fromNode, err := client.GetBlock(hash)
+	if err != nil {
+		t.Fatalf("cannot get the block: %s", err)
+	}
+	if len(parsed.RawTxs) != len(fromNode.TxHashes) {
+		t.Errorf("got %d decoded txs, want %d", len(parsed.RawTxs), len(fromNode.TxHashes))
+	}
+
+	// Apart from the node's view of the chain, the witness size and the txs, which the verbose block does not
+	// include, the decoded block equals the node's field for field.
+	fromNode.Confirmations, fromNode.NextBlockHash, fromNode.Difficulty, fromNode.FullSize = 0, "", 0, 0
+	parsed.RawTxs = nil
+	got, want := reflect.ValueOf(*parsed), reflect.ValueOf(*fromNode)
+	for i := 0; i < got.NumField(); i++ {
+		if !reflect.DeepEqual(got.Field(i).Interface(), want.Field(i).Interface()) {
+			t.Errorf("%s: got %v, want %v", got.Type().Field(i).Name, got.Field(i).Interface(), want.Field(i).Interface())
+		}
+	}
+
+	_, err = client.GetBlockParsed(testBlockHash("ee"))
+	if err == nil {
+		t.Errorf("got no error for a block served under another hash")
+	}
+}
+
+func TestAbecBlockVersionAndBits(t *testing.T) {
+	tests := []struct {
+		name        string
+		block       AbecBlock
+		wantBits    uint32
+		wantBitsErr bool
+		wantVersion int64
+	}{
+		{"as rendered by the node", AbecBlock{Bits: "1d00ffff", VersionHex: "20000000", Version: 536870912}, 0x1d00ffff, false, 0x20000000},
+		{"negative version", AbecBlock{Bits: "207fffff", VersionHex: "ffffffff", Version: -1}, 0x207fffff, false, -1},
+		{"short bits", AbecBlock{Bits: "ffff", VersionHex: "00000001"}, 0xffff, false, 1},
+		{"malformed", AbecBlock{Bits: "xyz", VersionHex: "xyz", Version: 3}, 0, true, 3},
+		{"bits out of range", AbecBlock{Bits: "100000000", Version: 4}, 0, true, 4},
+	}
+	for _, test := range tests {
+		bits, err := test.block.BitsValue()
+		if bits != test.wantBits || (err != nil) != test.wantBitsErr {
+			t.Errorf("%s: got bits %08x and error %v, want %08x", test.name, bits, err, test.wantBits)
+		}
+		if version := test.block.VersionValue(); version != test.wantVersion {
+			t.Errorf("%s: got version %d, want %d", test.name, version, test.wantVersion)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Define the BroadcastTracker data type.
+// BroadcastTracker remembers recently broadcast transactions, so that a retrying sender does not broadcast
+// the same transaction twice or a second transaction spending the same coin within the window.
+type BroadcastTracker struct {
+	mutex   sync.Mutex
+	window  time.Duration
+	clock   Clock
+	txids   map[string]time.Time
+	coinIDs map[string]time.Time
+}
+
+// Define methods for BroadcastTracker.
+func NewBroadcastTracker(window time.Duration, clock ...Clock) *BroadcastTracker {
+	if len(clock) == 0 {
+		clock = append(clock, SystemClock{})
+	}
+
+	return &BroadcastTracker{
+		window:  window,
+		clock:   clock[0],
+		txids:   make(map[string]time.Time),
+		coinIDs: make(map[string]time.Time),
+	}
+}
+
+func (t *BroadcastTracker) CheckBeforeSend(signedTx *SignedRawTx, txDesc *TxDesc) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.checkLocked(signedTx, txDesc)
+}
+
+func (t *BroadcastTracker) Record(signedTx *SignedRawTx, txDesc *TxDesc) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.recordLocked(signedTx, txDesc)
+}
+
+// Forget removes a transaction from the tracker, e.g. after the node rejected it, so that it can be retried.
+func (t *BroadcastTracker) Forget(signedTx *SignedRawTx, txDesc *TxDesc) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.txids, signedTx.Txid.HexString())
+	for _, txInDesc := range txDesc.TxInDescs {
+		coinID, err := txInDesc.GetCoinID()
+		if err == nil {
+			delete(t.coinIDs, coinID.String())
+		}
+	}
+}
+
+func (t *BroadcastTracker) checkAndRecord(signedTx *SignedRawTx, txDesc *TxDesc) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	err := t.checkLocked(signedTx, txDesc)
+	if err != nil {
+		return err
+	}
+
+	t.recordLocked(signedTx, txDesc)
+	return nil
+}
+
+func (t *BroadcastTracker) checkLocked(signedTx *SignedRawTx, txDesc *TxDesc) error {
+	t.pruneLocked()
+
+	txid := signedTx.Txid.HexString()
+	if broadcastTime, ok := t.txids[txid]; ok {
+		return fmt.Errorf("tx %s was already broadcast at %s", txid, broadcastTime.Format(time.RFC3339))
+	}
+
+	for i, txInDesc := range txDesc.TxInDescs {
+		coinID, err := txInDesc.GetCoinID()
+		if err != nil {
+			return fmt.Errorf("input %d is not valid: %s", i, err)
+		}
+		if broadcastTime, ok := t.coinIDs[coinID.String()]; ok {
+			return fmt.Errorf("input %d spends coin %s which was already spent by a tx broadcast at %s", i, coinID, broadcastTime.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+func (t *BroadcastTracker) recordLocked(signedTx *SignedRawTx, txDesc *TxDesc) {
+	now := t.clock.Now()
+	t.txids[signedTx.Txid.HexString()] = now
+	for _, txInDesc := range txDesc.TxInDescs {
+		coinID, err := txInDesc.GetCoinID()
+		if err == nil {
+			t.coinIDs[coinID.String()] = now
+		}
+	}
+}
+
+func (t *BroadcastTracker) pruneLocked() {
+	cutoff := t.clock.Now().Add(-t.window)
+	for txid, broadcastTime := range t.txids {
+		if broadcastTime.Before(cutoff) {
+			delete(t.txids, txid)
+		}
+	}
+	for coinID, broadcastTime := range t.coinIDs {
+		if broadcastTime.Before(cutoff) {
+			delete(t.coinIDs, coinID)
+		}
+	}
+}
+
+// Define util functions.
+// SendSignedRawTxTracked checks and records the tx in the tracker before sending it. The tx is forgotten
+// again if the node does not accept it, so that it can be retried.
+func SendSignedRawTxTracked(client *AbecRPCClient, tracker *BroadcastTracker, signedTx *SignedRawTx, txDesc *TxDesc) (Bytes, *string, error) {
+	err := tracker.checkAndRecord(signedTx, txDesc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resultBytes, txid, err := client.SendSignedRawTx(signedTx)
+	if err != nil {
+		tracker.Forget(signedTx, txDesc)
+		return resultBytes, txid, err
+	}
+
+	return resultBytes, txid, nil
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBroadcast returns a signed tx with the given txid and a tx desc spending the given coins.
+func newTestBroadcast(txid byte, coinIDs ...*CoinID) (*SignedRawTx, *TxDesc) {
+	txInDescs := make([]*TxInDesc, len(coinIDs))
+	for i, coinID := range coinIDs {
+		txInDescs[i] = &TxInDesc{TxHash: coinID.TxHash.Bytes(), TxOutIndex: coinID.Index}
+	}
+
+	return NewSignedRawTx(Bytes{txid}, Txid{txid}), &TxDesc{TxInDescs: txInDescs}
+}
+
+func TestBroadcastTrackerCheckBeforeSend(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	tracker := NewBroadcastTracker(10*time.Minute, clock)
+
+	sent, sentDesc := newTestBroadcast(0xaa, NewCoinID(Txid{0x01}, 0), NewCoinID(Txid{0x02}, 1))
+	if err := tracker.CheckBeforeSend(sent, sentDesc); err != nil {
+		t.Fatalf("got error %s before anything was sent", err)
+	}
+	tracker.Record(sent, sentDesc)
+
+	tests := []struct {
+		name    string
+		txid    byte
+		coinIDs []*CoinID
+		wantErr string
+	}{
+		{"duplicate txid", 0xaa, []*CoinID{NewCoinID(Txid{0x03}, 0)}, "already broadcast"},
+		{"conflicting input", 0xbb, []*CoinID{NewCoinID(Txid{0x03}, 0), NewCoinID(Txid{0x02}, 1)}, "input 1 spends coin"},
+		{"other output of the same tx", 0xbb, []*CoinID{NewCoinID(Txid{0x02}, 0)}, ""},
+		{"unrelated", 0xcc, []*CoinID{NewCoinID(Txid{0x03}, 0)}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := tracker.CheckBeforeSend(newTestBroadcast(test.txid, test.coinIDs...))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("got error %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+
+	conflicting, conflictingDesc := newTestBroadcast(0xbb, NewCoinID(Txid{0x02}, 1))
+	tracker.Forget(sent, sentDesc)
+	if err := tracker.CheckBeforeSend(conflicting, conflictingDesc); err != nil {
+		t.Errorf("got error %s after the sent tx was forgotten", err)
+	}
+
+	tracker.Record(sent, sentDesc)
+	clock.Advance(10*time.Minute + time.Second)
+	if err := tracker.CheckBeforeSend(conflicting, conflictingDesc); err != nil {
+		t.Errorf("got error %s after the window passed", err)
+	}
+	if err := tracker.CheckBeforeSend(sent, sentDesc); err != nil {
+		t.Errorf("got error %s for the same tx after the window passed", err)
+	}
+
+	invalid, invalidDesc := newTestBroadcast(0xdd)
+	invalidDesc.TxInDescs = []*TxInDesc{{TxHash: Bytes{0x01}}}
+	if err := tracker.CheckBeforeSend(invalid, invalidDesc); err == nil {
+		t.Errorf("got no error for an input with a truncated tx hash")
+	}
+}
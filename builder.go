@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+)
+
+// Define the TxBuilder data type.
+// TxBuilder collects the coins a transfer may spend and its recipients. Build selects among the coins as
+// SelectCoins does, adds a change output for change of at least DUST_THRESHOLD and fetches the ring blocks
+// of the selected coins. An error in a chained method is kept and returned by Build.
+type TxBuilder struct {
+	client        *AbecRPCClient
+	coins         []*Coin
+	recipients    []*TxOutDesc
+	feePolicy     *FeePolicy
+	memo          Bytes
+	changeAddress *AbelAddress
+	strategy      []CoinSelectionStrategy
+	err           error
+}
+
+// Define methods for TxBuilder.
+func NewTxBuilder(client *AbecRPCClient) *TxBuilder {
+	return &TxBuilder{
+		client:     client,
+		coins:      make([]*Coin, 0),
+		recipients: make([]*TxOutDesc, 0),
+	}
+}
+
+// AddInput adds coins that Build may spend. Build spends only as many of them as the transfer needs.
+func (builder *TxBuilder) AddInput(coins ...*Coin) *TxBuilder {
+	for _, coin := range coins {
+		if coin.Spent {
+			builder.setErr(fmt.Errorf("coin %s is already spent", coin.ID))
+			continue
+		}
+		builder.coins = append(builder.coins, coin)
+	}
+
+	return builder
+}
+
+func (builder *TxBuilder) AddRecipient(addr *AbelAddress, value int64) *TxBuilder {
+	if addr == nil {
+		builder.setErr(fmt.Errorf("recipient %d has no address", len(builder.recipients)))
+	} else if value <= 0 {
+		builder.setErr(fmt.Errorf("recipient %d value %d is not positive", len(builder.recipients), value))
+	}
+	builder.recipients = append(builder.recipients, NewTxOutDesc(addr, value))
+
+	return builder
+}
+
+func (builder *TxBuilder) SetFeeRate(feeRatePerKB int64, minFeeRatePerKB ...int64) *TxBuilder {
+	if feeRatePerKB < 0 {
+		builder.setErr(fmt.Errorf("fee rate %d is negative", feeRatePerKB))
+	}
+	builder.feePolicy = NewFeePolicy(feeRatePerKB, minFeeRatePerKB...)
+
+	return builder
+}
+
+func (builder *TxBuilder) SetMemo(memo Bytes) *TxBuilder {
+	if memo.Len() > MAX_TX_MEMO_SIZE {
+		builder.setErr(fmt.Errorf("memo of %d bytes is longer than %d bytes", memo.Len(), MAX_TX_MEMO_SIZE))
+	}
+	builder.memo = memo
+
+	return builder
+}
+
+func (builder *TxBuilder) SetChangeAddress(addr *AbelAddress) *TxBuilder {
+	builder.changeAddress = addr
+
+	return builder
+}
+
+func (builder *TxBuilder) SetStrategy(strategy CoinSelectionStrategy) *TxBuilder {
+	builder.strategy = []CoinSelectionStrategy{strategy}
+
+	return builder
+}
+
+// Build returns a validated tx desc, whose inputs equal its outputs plus the fee. A change address is only
+// needed when there is change.
+func (builder *TxBuilder) Build() (*TxDesc, error) {
+	if builder.err != nil {
+		return nil, builder.err
+	}
+	if len(builder.recipients) == 0 {
+		return nil, fmt.Errorf("tx builder has no recipients")
+	}
+	if builder.feePolicy == nil {
+		return nil, fmt.Errorf("tx builder has no fee rate")
+	}
+
+	targetValue := int64(0)
+	for _, recipient := range builder.recipients {
+		targetValue += recipient.CoinValue
+	}
+
+	selectedCoins, change, fee, err := selectCoins(builder.coins, targetValue, len(builder.recipients)+1, builder.memo.Len(), builder.feePolicy, builder.strategy...)
+	if err != nil {
+		return nil, err
+	}
+	if change > 0 && builder.changeAddress == nil {
+		return nil, fmt.Errorf("change of %d neutrino needs a change address", change)
+	}
+
+	txDesc, err := buildTransferTxDesc(builder.client, selectedCoins, builder.recipients, fee, builder.changeAddress, change, builder.memo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dust change is moved into the fee, so the fee of the tx desc is not necessarily the one first estimated.
+	totalIn, totalOut := txDesc.TotalInputValue(), txDesc.TotalOutputValue()
+	if totalIn != totalOut+txDesc.TxFee {
+		return nil, fmt.Errorf("built tx desc inputs %d do not equal outputs %d plus fee %d", totalIn, totalOut, txDesc.TxFee)
+	}
+
+	return txDesc, nil
+}
+
+// setErr keeps the first error, since later ones are often caused by it.
+func (builder *TxBuilder) setErr(err error) {
+	if builder.err == nil {
+		builder.err = err
+	}
+}
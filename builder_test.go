@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// newTestBuilderCoin returns a client of a simulation chain and a mature coin of value owned by keys in it,
+// with its ring group complete.
+func newTestBuilderCoin(t *testing.T, keys *CryptoKeysAndAddress, value int64) (*AbecRPCClient, *Coin) {
+	t.Helper()
+
+	client := NewSimulationClient(newTestChain(t, 3, map[int64][]testPayment{0: {{keys, value}}}))
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	coins := w.Coins()
+	if len(coins) != 1 {
+		t.Fatalf("got %d coins, want 1", len(coins))
+	}
+
+	return client, coins[0]
+}
+
+func TestTxBuilderBuild(t *testing.T) {
+	keys := newTestKeys(t)
+	client, coin := newTestBuilderCoin(t, keys, 5000000)
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+
+	t.Run("dust change", func(t *testing.T) {
+		// No change address is needed, since the change is below DUST_THRESHOLD and goes to the fee.
+		txDesc, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 4995000).SetFeeRate(0).Build()
+		if err != nil {
+			t.Fatalf("got error %s", err)
+		}
+		if len(txDesc.TxOutDescs) != 1 || txDesc.TxFee != 5000 {
+			t.Errorf("got %d outputs and fee %d, want 1 output and fee 5000", len(txDesc.TxOutDescs), txDesc.TxFee)
+		}
+		if txDesc.TotalInputValue() != txDesc.TotalOutputValue()+txDesc.TxFee {
+			t.Errorf("inputs %d do not equal outputs %d plus fee %d", txDesc.TotalInputValue(), txDesc.TotalOutputValue(), txDesc.TxFee)
+		}
+	})
+
+	t.Run("change", func(t *testing.T) {
+		txDesc, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 3000000).SetFeeRate(0).SetChangeAddress(recipient).Build()
+		if err != nil {
+			t.Fatalf("got error %s", err)
+		}
+		if len(txDesc.TxOutDescs) != 2 || txDesc.TxOutDescs[1].CoinValue != 2000000 || txDesc.TxFee != 0 {
+			t.Errorf("got %d outputs and fee %d, want a change output of 2000000 and no fee", len(txDesc.TxOutDescs), txDesc.TxFee)
+		}
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		_, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 6000000).SetFeeRate(0).Build()
+
+		var insufficientFunds *InsufficientFundsError
+		if !errors.As(err, &insufficientFunds) || insufficientFunds.Shortfall != 1000000 {
+			t.Errorf("got error %v, want a shortfall of 1000000", err)
+		}
+	})
+
+	t.Run("missing fee rate", func(t *testing.T) {
+		_, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 1000000).Build()
+		if err == nil {
+			t.Errorf("got no error without a fee rate")
+		}
+	})
+
+	t.Run("negative fee rate", func(t *testing.T) {
+		_, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 1000000).SetFeeRate(-1).Build()
+		if err == nil {
+			t.Errorf("got no error for a negative fee rate")
+		}
+	})
+}
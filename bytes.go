@@ -4,13 +4,20 @@ import (
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"time"
 )
 
+// Define constants.
+const (
+	MAX_FRAMED_BYTES_LENGTH = 1 << 30
+)
+
 // Define data types.
 type Bytes []byte
 
@@ -34,6 +41,31 @@ func MakeBytesFromHexString(hexString string) Bytes {
 	return AsBytes(b)
 }
 
+// ReadBytesFrom reads one frame written by Bytes.WriteTo.
+func ReadBytesFrom(r io.Reader) (Bytes, error) {
+	var header [4]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length > MAX_FRAMED_BYTES_LENGTH {
+		return nil, fmt.Errorf("frame length %d exceeds the limit %d", length, MAX_FRAMED_BYTES_LENGTH)
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return AsBytes(data), nil
+}
+
 func MakeRandomBytes(length int, seed ...int64) Bytes {
 	if len(seed) == 0 {
 		seed = []int64{-1}
@@ -51,6 +83,40 @@ func (b Bytes) Len() int {
 	return len(b.Slice())
 }
 
+func (b Bytes) At(i int) (byte, error) {
+	if i < 0 || i >= b.Len() {
+		return 0, fmt.Errorf("index %d out of range [0, %d)", i, b.Len())
+	}
+
+	return b[i], nil
+}
+
+func (b Bytes) SubBytes(start int, end int) (Bytes, error) {
+	if start < 0 || end > b.Len() || start > end {
+		return nil, fmt.Errorf("range [%d, %d) out of range [0, %d]", start, end, b.Len())
+	}
+
+	return b[start:end], nil
+}
+
+// WriteTo writes b as a frame: a 4-byte big-endian length followed by the data. Frames can be written back
+// to back on one stream and read again with ReadBytesFrom.
+func (b Bytes) WriteTo(w io.Writer) (int64, error) {
+	if b.Len() > MAX_FRAMED_BYTES_LENGTH {
+		return 0, fmt.Errorf("bytes length %d exceeds the frame limit %d", b.Len(), MAX_FRAMED_BYTES_LENGTH)
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(b.Len()))
+	n, err := w.Write(header[:])
+	if err != nil {
+		return int64(n), err
+	}
+
+	m, err := w.Write(b)
+	return int64(n + m), err
+}
+
 func (b Bytes) HexString() string {
 	return hex.EncodeToString(b.Slice())
 }
@@ -130,6 +196,30 @@ func (b Bytes) FillRandomData(seed int64, alphabet string) {
 	}
 }
 
+// MarshalText encodes b as hex, so that Bytes fields marshal as hex strings in JSON and other text formats.
+// Structs that embed Bytes inherit it and marshal as the hex of the bytes alone, so an embedding struct with
+// other fields, like SignedRawTx, needs its own MarshalJSON.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(b.HexString()), nil
+}
+
+// UnmarshalText decodes hex. An empty string decodes to nil, so nil Bytes round-trips.
+func (b *Bytes) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*b = nil
+		return nil
+	}
+
+	data := make([]byte, hex.DecodedLen(len(text)))
+	_, err := hex.Decode(data, text)
+	if err != nil {
+		return fmt.Errorf("bytes are not valid hex: %s", err)
+	}
+
+	*b = AsBytes(data)
+	return nil
+}
+
 func (b Bytes) JSONUnmarshal(v any) error {
 	return json.Unmarshal(b.Slice(), v)
 }
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestBytesJSONRoundTrip(t *testing.T) {
+	type embedding struct {
+		Bytes
+	}
+	type withField struct {
+		Data Bytes `json:"data"`
+	}
+
+	for _, data := range []Bytes{nil, AsBytes([]byte{0x00, 0xab, 0xff})} {
+		encoded, err := json.Marshal(embedding{data})
+		if err != nil {
+			t.Fatalf("cannot marshal: %s", err)
+		}
+		if want := `"` + data.HexString() + `"`; string(encoded) != want {
+			t.Errorf("got %s, want %s", encoded, want)
+		}
+		decoded := embedding{}
+		err = json.Unmarshal(encoded, &decoded)
+		if err != nil {
+			t.Fatalf("cannot unmarshal %s: %s", encoded, err)
+		}
+		if decoded.HexString() != data.HexString() || (data == nil) != (decoded.Bytes == nil) {
+			t.Errorf("got %v after a round trip, want %v", decoded.Bytes, data)
+		}
+
+		encoded, err = json.Marshal(withField{data})
+		if err != nil {
+			t.Fatalf("cannot marshal: %s", err)
+		}
+		decodedField := withField{}
+		err = json.Unmarshal(encoded, &decodedField)
+		if err != nil || decodedField.Data.HexString() != data.HexString() {
+			t.Errorf("got %v and error %v after a round trip of %s, want %v", decodedField.Data, err, encoded, data)
+		}
+	}
+
+	err := json.Unmarshal([]byte(`"0xzz"`), &embedding{})
+	if err == nil {
+		t.Errorf("got no error for invalid hex")
+	}
+}
+
+func TestBytesAtAndSubBytes(t *testing.T) {
+	data := AsBytes([]byte{0x0a, 0x0b, 0x0c})
+
+	for i, want := range data {
+		got, err := data.At(i)
+		if err != nil || got != want {
+			t.Errorf("At(%d) = %x, %v, want %x", i, got, err, want)
+		}
+	}
+	for _, i := range []int{-1, 3, math.MaxInt} {
+		if _, err := data.At(i); err == nil {
+			t.Errorf("At(%d): got no error", i)
+		}
+	}
+	if _, err := Bytes(nil).At(0); err == nil {
+		t.Errorf("At(0) of nil bytes: got no error")
+	}
+
+	tests := []struct {
+		start   int
+		end     int
+		want    Bytes
+		wantErr bool
+	}{
+		{0, 3, data, false},
+		{1, 2, Bytes{0x0b}, false},
+		{3, 3, Bytes{}, false},
+		{0, 0, Bytes{}, false},
+		{-1, 2, nil, true},
+		{0, 4, nil, true},
+		{2, 1, nil, true},
+		{4, 4, nil, true},
+	}
+	for _, test := range tests {
+		got, err := data.SubBytes(test.start, test.end)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("SubBytes(%d, %d) = %x, want an error", test.start, test.end, got)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, test.want) {
+			t.Errorf("SubBytes(%d, %d) = %x, %v, want %x", test.start, test.end, got, err, test.want)
+		}
+	}
+}
+
+func TestRawTxJSONRoundTrip(t *testing.T) {
+	shortAddress := NewAbelAddressFromCryptoAddress(&newTestKeys(t).CryptoAddress, 0).GetShortAbelAddress()
+	data := AsBytes([]byte{0x01, 0x02, 0x03})
+
+	signedTx := NewSignedRawTx(data, Txid{0xaa, 0xbb})
+	signedTx.Signers = []*ShortAbelAddress{shortAddress}
+	encoded, err := json.Marshal(signedTx)
+	if err != nil {
+		t.Fatalf("cannot marshal: %s", err)
+	}
+	decodedSignedTx := &SignedRawTx{}
+	err = json.Unmarshal(encoded, decodedSignedTx)
+	if err != nil {
+		t.Fatalf("cannot unmarshal %s: %s", encoded, err)
+	}
+	if decodedSignedTx.HexString() != data.HexString() || decodedSignedTx.Txid != signedTx.Txid ||
+		len(decodedSignedTx.Signers) != 1 || !decodedSignedTx.Signers[0].Equal(shortAddress) {
+		t.Errorf("got %s after a round trip, want the original tx", encoded)
+	}
+
+	// A value marshals the same as a pointer, so that the tx keeps its fields in other structs.
+	encodedValue, err := json.Marshal(struct{ Tx SignedRawTx }{*signedTx})
+	if err != nil {
+		t.Fatalf("cannot marshal: %s", err)
+	}
+	if want := `{"Tx":` + string(encoded) + `}`; string(encodedValue) != want {
+		t.Errorf("got %s, want %s", encodedValue, want)
+	}
+
+	unsignedTx := NewUnsignedRawTx(data, []*ShortAbelAddress{shortAddress})
+	encoded, err = json.Marshal(unsignedTx)
+	if err != nil {
+		t.Fatalf("cannot marshal: %s", err)
+	}
+	decodedUnsignedTx := &UnsignedRawTx{}
+	err = json.Unmarshal(encoded, decodedUnsignedTx)
+	if err != nil {
+		t.Fatalf("cannot unmarshal %s: %s", encoded, err)
+	}
+	if decodedUnsignedTx.HexString() != data.HexString() || len(decodedUnsignedTx.Signers) != 1 ||
+		!decodedUnsignedTx.Signers[0].Equal(shortAddress) {
+		t.Errorf("got %s after a round trip, want the original tx", encoded)
+	}
+}
+
+func TestBytesFrames(t *testing.T) {
+	frames := []Bytes{AsBytes([]byte("first")), nil, MakeRandomBytes(70000, 1), AsBytes([]byte{0x00})}
+
+	buffer := &bytes.Buffer{}
+	for _, frame := range frames {
+		n, err := frame.WriteTo(buffer)
+		if err != nil {
+			t.Fatalf("cannot write a frame: %s", err)
+		}
+		if n != int64(4+frame.Len()) {
+			t.Errorf("wrote %d bytes for a frame of %d", n, frame.Len())
+		}
+	}
+	data := buffer.Bytes()
+
+	// The frames come back in order from one stream, which then ends cleanly.
+	r := bytes.NewReader(data)
+	for i, frame := range frames {
+		got, err := ReadBytesFrom(r)
+		if err != nil {
+			t.Fatalf("cannot read frame %d: %s", i, err)
+		}
+		if !bytes.Equal(got, frame) {
+			t.Errorf("got frame %d of %d bytes, want %d", i, got.Len(), frame.Len())
+		}
+	}
+	if _, err := ReadBytesFrom(r); err != io.EOF {
+		t.Errorf("got error %v after the last frame, want io.EOF", err)
+	}
+
+	// A stream cut inside a header or inside the data of a frame is truncated, not cleanly ended.
+	for _, cut := range []int{2, 4 + 3, len(data) - 1} {
+		r := bytes.NewReader(data[:cut])
+		var err error
+		for err == nil {
+			_, err = ReadBytesFrom(r)
+		}
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("cut at %d: got error %v, want io.ErrUnexpectedEOF", cut, err)
+		}
+	}
+}
+
+func TestBytesFrameLimit(t *testing.T) {
+	// A header over the limit is rejected before its data is allocated or read.
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, MAX_FRAMED_BYTES_LENGTH+1)
+	_, err := ReadBytesFrom(bytes.NewReader(append(header, "data"...)))
+	if err == nil {
+		t.Errorf("read a frame over the limit")
+	}
+	binary.BigEndian.PutUint32(header, math.MaxUint32)
+	_, err = ReadBytesFrom(bytes.NewReader(header))
+	if err == nil || err == io.ErrUnexpectedEOF {
+		t.Errorf("got error %v for a frame of the largest length, want the limit error", err)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping the write of a frame over the limit in short mode")
+	}
+	// The oversize buffer is never written to, so it is not backed by real memory.
+	buffer := &bytes.Buffer{}
+	n, err := MakeBytes(MAX_FRAMED_BYTES_LENGTH + 1).WriteTo(buffer)
+	if err == nil || n != 0 || buffer.Len() != 0 {
+		t.Errorf("wrote %d bytes and got error %v for a frame over the limit", n, err)
+	}
+}
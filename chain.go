@@ -0,0 +1,398 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abesuite/abec/chaincfg"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define constants.
+const (
+	BLOCK_POLL_MIN_INTERVAL = 500 * time.Millisecond
+	BLOCK_POLL_MAX_INTERVAL = 10 * time.Second
+)
+
+type Network int8
+
+const (
+	UNKNOWN_NETWORK Network = -1
+	MAINNET_NETWORK Network = 0x00
+	REGTEST_NETWORK Network = 0x01
+	TESTNET_NETWORK Network = 0x02
+	SIMNET_NETWORK  Network = 0x03
+)
+
+func (network Network) String() string {
+	switch network {
+	case MAINNET_NETWORK:
+		return "mainnet"
+	case REGTEST_NETWORK:
+		return "regtest"
+	case TESTNET_NETWORK:
+		return "testnet"
+	case SIMNET_NETWORK:
+		return "simnet"
+	default:
+		return "unknown"
+	}
+}
+
+// ChainID returns the chain id that addresses on the network are prefixed with, which is the network's netid.
+func (network Network) ChainID() int8 {
+	return int8(network)
+}
+
+// GenesisHash returns the hash of the network's genesis block as compiled into abec, or an empty string for
+// an unknown network.
+func (network Network) GenesisHash() string {
+	switch network {
+	case MAINNET_NETWORK:
+		return chaincfg.MainNetParams.GenesisHash.String()
+	case REGTEST_NETWORK:
+		return chaincfg.RegressionNetParams.GenesisHash.String()
+	case TESTNET_NETWORK:
+		return chaincfg.TestNet3Params.GenesisHash.String()
+	case SIMNET_NETWORK:
+		return chaincfg.SimNetParams.GenesisHash.String()
+	default:
+		return ""
+	}
+}
+
+// ErrChainMismatch is returned when the node's block at a known height does not have the expected hash,
+// which means that the node is on a different or forked chain.
+var ErrChainMismatch = errors.New("chain mismatch")
+
+// Define the ChainInfoDelta data type.
+type ChainInfoDelta struct {
+	BlocksAdvanced int64
+	Stalled        bool
+	NetworkChanged bool
+}
+
+// Define methods for AbecChainInfo.
+func (info *AbecChainInfo) Network() Network {
+	network := Network(info.NetID)
+	switch network {
+	case MAINNET_NETWORK, REGTEST_NETWORK, SIMNET_NETWORK:
+		if info.IsTestnet {
+			return UNKNOWN_NETWORK
+		}
+		return network
+	case TESTNET_NETWORK:
+		if !info.IsTestnet {
+			return UNKNOWN_NETWORK
+		}
+		return network
+	default:
+		return UNKNOWN_NETWORK
+	}
+}
+
+// Define methods for AbecBlock.
+func (block *AbecBlock) Timestamp() time.Time {
+	return time.Unix(block.Time, 0)
+}
+
+// Age returns how long ago the block was made according to clock, which defaults to the system clock. A client
+// passes its own clock, so that a FakeClock also drives the ages it computes.
+func (block *AbecBlock) Age(clock ...Clock) time.Duration {
+	if len(clock) == 0 {
+		clock = []Clock{SystemClock{}}
+	}
+
+	return clock[0].Now().Sub(block.Timestamp())
+}
+
+// BitsValue parses Bits, which the node renders as the big-endian hex of the compact difficulty target.
+func (block *AbecBlock) BitsValue() (uint32, error) {
+	bits, err := strconv.ParseUint(block.Bits, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("block bits %q is not valid: %s", block.Bits, err)
+	}
+
+	return uint32(bits), nil
+}
+
+// VersionValue parses VersionHex, which the node renders as the 8-digit hex of the int32 header version.
+// It falls back to the Version field if VersionHex is missing or malformed.
+func (block *AbecBlock) VersionValue() int64 {
+	version, err := strconv.ParseUint(block.VersionHex, 16, 32)
+	if err != nil {
+		return block.Version
+	}
+
+	return int64(int32(version))
+}
+
+// The hash accessors below return the bytes in the same (display) order as the hex strings from the node,
+// which is the reverse of the internal byte order used when hashing and serializing headers.
+func (block *AbecBlock) BlockHashBytes() (Bytes, error) {
+	return decodeHashString(block.BlockHash)
+}
+
+func (block *AbecBlock) PrevBlockHashBytes() (Bytes, error) {
+	return decodeHashString(block.PrevBlockHash)
+}
+
+func (block *AbecBlock) ContentHashBytes() (Bytes, error) {
+	return decodeHashString(block.ContentHash)
+}
+
+func (block *AbecBlock) SealHashBytes() (Bytes, error) {
+	return decodeHashString(block.SealHash)
+}
+
+func (block *AbecBlock) MerkleRootBytes() (Bytes, error) {
+	return decodeHashString(block.MerkleRoot)
+}
+
+// Define methods for AbecTx.
+// DisplayTxid returns the txid in display order, as rendered by the node and the explorer. This is the
+// order stored in Coin.ID.TxHash and expected by NewOutPointFromTxIdStr.
+// TxHash is rendered by the node from the same witness-less hash, so it holds the same value as TxID.
+func (tx *AbecTx) DisplayTxid() (Bytes, error) {
+	return decodeHashString(tx.TxID)
+}
+
+// InternalTxid returns the txid in internal order, as computed by wire.MsgTxAbe.TxId().
+func (tx *AbecTx) InternalTxid() (Bytes, error) {
+	displayTxid, err := tx.DisplayTxid()
+	if err != nil {
+		return nil, err
+	}
+
+	return reversedBytes(displayTxid), nil
+}
+
+// CheckTxid recomputes the txid from Hex, if present, and checks it against TxID.
+func (tx *AbecTx) CheckTxid() error {
+	internalTxid, err := tx.InternalTxid()
+	if err != nil {
+		return err
+	}
+	if len(tx.Hex) == 0 {
+		return nil
+	}
+
+	txBytes, err := hex.DecodeString(tx.Hex)
+	if err != nil {
+		return fmt.Errorf("tx %s hex is not valid: %s", tx.TxID, err)
+	}
+
+	msgTx := &wire.MsgTxAbe{}
+	err = msgTx.DeserializeFull(bytes.NewReader(txBytes))
+	if err != nil {
+		return fmt.Errorf("tx %s cannot be deserialized: %s", tx.TxID, err)
+	}
+
+	computedTxid := msgTx.TxId()
+	if !bytes.Equal(computedTxid[:], internalTxid) {
+		return fmt.Errorf("tx %s does not match its content txid %s", tx.TxID, computedTxid)
+	}
+
+	return nil
+}
+
+// MemoBytes decodes Memo, which the node renders as hex.
+func (tx *AbecTx) MemoBytes() (Bytes, error) {
+	memo, err := hex.DecodeString(tx.Memo)
+	if err != nil {
+		return nil, fmt.Errorf("tx %s memo is not valid hex: %s", tx.TxID, err)
+	}
+
+	return AsBytes(memo), nil
+}
+
+// MemoString returns the memo as text and whether it is valid UTF-8, the canonical encoding of text memos.
+func (tx *AbecTx) MemoString() (string, bool) {
+	memo, err := tx.MemoBytes()
+	if err != nil || !utf8.Valid(memo) {
+		return "", false
+	}
+
+	return string(memo), true
+}
+
+// Define methods for AbecRPCClient.
+func (client *AbecRPCClient) GetTipBlock() (*AbecBlock, error) {
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	_, block, err := client.GetBlockByHeight(chainInfo.NumBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func (client *AbecRPCClient) GetNetwork() (Network, error) {
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return UNKNOWN_NETWORK, err
+	}
+
+	return chainInfo.Network(), nil
+}
+
+func (client *AbecRPCClient) CheckAddressesNetwork(addrs []*AbelAddress) error {
+	network, err := client.GetNetwork()
+	if err != nil {
+		return err
+	}
+
+	return CheckAddressesNetwork(addrs, network)
+}
+
+func (client *AbecRPCClient) GetTipAge() (time.Duration, error) {
+	block, err := client.GetTipBlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return block.Age(client.clock), nil
+}
+
+func (client *AbecRPCClient) GetBlockByHeightWhenAvailable(ctx context.Context, height int64, maxWait time.Duration) (Bytes, *AbecBlock, error) {
+	deadline := client.clock.Now().Add(maxWait)
+	interval := BLOCK_POLL_MIN_INTERVAL
+	for {
+		blockBytes, block, err := client.GetBlockByHeightContext(ctx, height)
+		if err == nil {
+			return blockBytes, block, nil
+		}
+		if !isHeightNotFound(err) {
+			return nil, nil, err
+		}
+
+		remaining := deadline.Sub(client.clock.Now())
+		if remaining <= 0 {
+			return nil, nil, fmt.Errorf("block at height %d is not available after %s", height, maxWait)
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-client.clock.After(interval):
+		}
+
+		interval *= 2
+		if interval > BLOCK_POLL_MAX_INTERVAL {
+			interval = BLOCK_POLL_MAX_INTERVAL
+		}
+	}
+}
+
+// Define util functions.
+func decodeHashString(hash string) (Bytes, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("hash %q is not valid hex: %s", hash, err)
+	}
+	if len(data) != 32 {
+		return nil, fmt.Errorf("hash %q is not 32 bytes", hash)
+	}
+
+	return AsBytes(data), nil
+}
+
+// VerifyChainIdentity checks that the node's genesis block is the expected one, such as
+// MAINNET_NETWORK.GenesisHash(), before a wallet trusts anything else the node reports.
+func VerifyChainIdentity(client *AbecRPCClient, expectedGenesisHash string) error {
+	return VerifyChainCheckpoint(client, 0, expectedGenesisHash)
+}
+
+// VerifyChainCheckpoint checks that the node's main chain block at height has the expected hash.
+func VerifyChainCheckpoint(client *AbecRPCClient, height int64, expectedHash string) error {
+	expected, err := decodeHashString(expectedHash)
+	if err != nil {
+		return err
+	}
+
+	_, hash, err := client.GetBlockHash(height)
+	if err != nil {
+		return err
+	}
+	actual, err := decodeHashString(*hash)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(expected, actual) {
+		return fmt.Errorf("%w: block at height %d is %s, expected %s", ErrChainMismatch, height, *hash, expectedHash)
+	}
+
+	return nil
+}
+
+func GetChainInfoDelta(prev *AbecChainInfo, cur *AbecChainInfo) (*ChainInfoDelta, error) {
+	if prev == nil || cur == nil {
+		return nil, fmt.Errorf("chain info snapshot is nil")
+	}
+
+	// Snapshots taken from different networks are not comparable, so only the change is reported.
+	if prev.IsTestnet != cur.IsTestnet || prev.NetID != cur.NetID {
+		return &ChainInfoDelta{NetworkChanged: true}, nil
+	}
+
+	blocksAdvanced := cur.NumBlocks - prev.NumBlocks
+	return &ChainInfoDelta{
+		BlocksAdvanced: blocksAdvanced,
+		Stalled:        blocksAdvanced <= 0,
+	}, nil
+}
+
+func isHeightNotFound(err error) bool {
+	var rpcErr *AbecRPCError
+	return errors.Is(err, ErrHeightNotFound) || (errors.As(err, &rpcErr) && rpcErr.IsHeightOutOfRange())
+}
+
+func wrapHeightNotFound(height int64, err error) error {
+	if !isHeightNotFound(err) {
+		return err
+	}
+
+	return fmt.Errorf("%w: height %d is beyond the tip: %s", ErrHeightNotFound, height, err)
+}
+
+func CheckAddressesNetwork(addrs []*AbelAddress, network Network) error {
+	if network == UNKNOWN_NETWORK {
+		return fmt.Errorf("network is unknown")
+	}
+
+	for i, addr := range addrs {
+		if addr.GetChainID() != network.ChainID() {
+			return fmt.Errorf("abel address %d has chain id %d but the network is %s with chain id %d", i, addr.GetChainID(), network, network.ChainID())
+		}
+	}
+
+	return nil
+}
+
+func IsTipStale(prev *AbecBlock, cur *AbecBlock, maxAge time.Duration, clock ...Clock) bool {
+	// The tip is considered stale when it has not advanced since the previous read
+	// and its timestamp is older than maxAge compared to clock.
+	advanced := prev == nil || cur.Height > prev.Height || cur.BlockHash != prev.BlockHash
+	age := cur.Age(clock...)
+	if advanced || age <= maxAge {
+		return false
+	}
+
+	LOG.debug("Chain tip %s at height %d is %s old, the node may have stopped syncing\n", cur.BlockHash, cur.Height, age.Truncate(time.Second))
+	return true
+}
@@ -0,0 +1,418 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abesuite/abec/chainhash"
+)
+
+func TestGetChainInfoDelta(t *testing.T) {
+	tests := []struct {
+		name    string
+		prev    *AbecChainInfo
+		cur     *AbecChainInfo
+		want    *ChainInfoDelta
+		wantErr bool
+	}{
+		{"advanced", &AbecChainInfo{NumBlocks: 10}, &AbecChainInfo{NumBlocks: 13}, &ChainInfoDelta{BlocksAdvanced: 3}, false},
+		{"stalled", &AbecChainInfo{NumBlocks: 10}, &AbecChainInfo{NumBlocks: 10}, &ChainInfoDelta{Stalled: true}, false},
+		{"network changed", &AbecChainInfo{NetID: 0}, &AbecChainInfo{NetID: 1}, &ChainInfoDelta{NetworkChanged: true}, false},
+		{"nil prev", nil, &AbecChainInfo{NumBlocks: 10}, nil, true},
+		{"nil cur", &AbecChainInfo{NumBlocks: 10}, nil, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delta, err := GetChainInfoDelta(test.prev, test.cur)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got delta %+v, want an error", delta)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if *delta != *test.want {
+				t.Errorf("got delta %+v, want %+v", delta, test.want)
+			}
+		})
+	}
+}
+
+func TestAbecChainInfoNetwork(t *testing.T) {
+	tests := []struct {
+		name      string
+		netID     byte
+		isTestnet bool
+		want      Network
+	}{
+		{"mainnet", 0x00, false, MAINNET_NETWORK},
+		{"regtest", 0x01, false, REGTEST_NETWORK},
+		{"testnet", 0x02, true, TESTNET_NETWORK},
+		{"simnet", 0x03, false, SIMNET_NETWORK},
+		{"mainnet flagged as testnet", 0x00, true, UNKNOWN_NETWORK},
+		{"testnet not flagged as testnet", 0x02, false, UNKNOWN_NETWORK},
+		{"unknown netid", 0x04, false, UNKNOWN_NETWORK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := &AbecChainInfo{NetID: test.netID, IsTestnet: test.isTestnet}
+			if got := info.Network(); got != test.want {
+				t.Errorf("got network %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckAddressesNetwork(t *testing.T) {
+	mainnetAddress := newTestAbelAddress(t, MAINNET_NETWORK.ChainID())
+	testnetAddress := newTestAbelAddress(t, TESTNET_NETWORK.ChainID())
+
+	if err := CheckAddressesNetwork([]*AbelAddress{mainnetAddress}, MAINNET_NETWORK); err != nil {
+		t.Errorf("got error %s for a mainnet address on mainnet", err)
+	}
+	if err := CheckAddressesNetwork([]*AbelAddress{mainnetAddress, testnetAddress}, MAINNET_NETWORK); err == nil {
+		t.Errorf("got no error for a testnet address on mainnet")
+	}
+	if err := CheckAddressesNetwork([]*AbelAddress{mainnetAddress}, UNKNOWN_NETWORK); err == nil {
+		t.Errorf("got no error for an unknown network")
+	}
+
+	// A testnet wallet against a mainnet node is caught through the node's getinfo.
+	client := newMockNode(t, map[string]mockMethod{
+		"getinfo": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return &AbecChainInfo{NetID: byte(MAINNET_NETWORK)}, nil
+		},
+	})
+	if err := client.CheckAddressesNetwork([]*AbelAddress{testnetAddress}); err == nil {
+		t.Errorf("got no error for a testnet address against a mainnet node")
+	}
+	if err := client.CheckAddressesNetwork([]*AbelAddress{mainnetAddress}); err != nil {
+		t.Errorf("got error %s for a mainnet address against a mainnet node", err)
+	}
+}
+
+func TestIsTipStaleWithFakeClock(t *testing.T) {
+	madeAt := time.Unix(1700000000, 0)
+	clock := NewFakeClock(madeAt.Add(5 * time.Minute))
+	prev := &AbecBlock{Height: 10, BlockHash: "aa", Time: madeAt.Unix()}
+	cur := &AbecBlock{Height: 10, BlockHash: "aa", Time: madeAt.Unix()}
+
+	if age := cur.Age(clock); age != 5*time.Minute {
+		t.Errorf("got age %s, want 5m0s", age)
+	}
+	if IsTipStale(prev, cur, 10*time.Minute, clock) {
+		t.Errorf("tip of 5m is stale with a max age of 10m")
+	}
+
+	clock.Advance(10 * time.Minute)
+	if !IsTipStale(prev, cur, 10*time.Minute, clock) {
+		t.Errorf("tip of 15m is not stale with a max age of 10m")
+	}
+
+	next := &AbecBlock{Height: 11, BlockHash: "bb", Time: madeAt.Unix()}
+	if IsTipStale(cur, next, 10*time.Minute, clock) {
+		t.Errorf("tip that advanced is stale")
+	}
+}
+
+func TestGetTipAgeUsesClientClock(t *testing.T) {
+	chain := newTestChain(t, 1, nil)
+	_, tip, err := NewSimulationClient(chain).GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get the tip: %s", err)
+	}
+	clock := NewFakeClock(tip.Timestamp().Add(time.Hour))
+	client := NewSimulationClient(chain, WithClock(clock))
+
+	age, err := client.GetTipAge()
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if age != time.Hour {
+		t.Errorf("got age %s, want 1h0m0s", age)
+	}
+}
+
+func TestGetBlockByHeightBeyondTip(t *testing.T) {
+	client := NewSimulationClient(newTestChain(t, 2, nil))
+
+	_, _, err := client.GetBlockByHeight(5)
+	if !errors.Is(err, ErrHeightNotFound) {
+		t.Errorf("got error %v for a height beyond the tip, want ErrHeightNotFound", err)
+	}
+	_, _, err = client.GetBlockByHeight(1)
+	if err != nil {
+		t.Errorf("got error %s for the tip", err)
+	}
+
+	// Only the code and description of a height beyond the tip are taken for one.
+	for _, rpcErr := range []*AbecJSONRPCError{
+		{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block height out of range for the index"},
+		{Code: -8, Message: "Block number out of range"},
+	} {
+		client := newMockNode(t, map[string]mockMethod{
+			"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+				return nil, rpcErr
+			},
+		})
+		_, _, err := client.GetBlockByHeight(5)
+		if err == nil || errors.Is(err, ErrHeightNotFound) {
+			t.Errorf("got error %v for code %d: %s, want another error", err, rpcErr.Code, rpcErr.Message)
+		}
+	}
+}
+
+func TestGetBlockByHeightWhenAvailable(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := NewSimulationChain(0)
+	raw := newTestBlock(t, 0, chainhash.Hash{}, testPayment{keys, 1000})
+	err := chain.AddBlock(raw)
+	if err != nil {
+		t.Fatalf("cannot add block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(raw)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+	prevHash, err := chainhash.NewHashFromStr(block.BlockHash)
+	if err != nil {
+		t.Fatalf("block 0 hash is not valid: %s", err)
+	}
+
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	client := NewSimulationClient(chain, WithClock(clock))
+
+	type result struct {
+		block *AbecBlock
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		_, block, err := client.GetBlockByHeightWhenAvailable(context.Background(), 1, time.Minute)
+		results <- result{block, err}
+	}()
+
+	// The block at height 1 is only found on the second poll.
+	waitFor(t, "the first poll", func() bool { return clock.Waiters() == 1 })
+	err = chain.AddBlock(newTestBlock(t, 1, *prevHash, testPayment{keys, 1000}))
+	if err != nil {
+		t.Fatalf("cannot add block 1: %s", err)
+	}
+	clock.Advance(BLOCK_POLL_MIN_INTERVAL)
+	got := <-results
+	if got.err != nil || got.block.Height != 1 {
+		t.Fatalf("got block %+v and error %v, want the block at height 1", got.block, got.err)
+	}
+
+	// Polling stops once maxWait has passed.
+	go func() {
+		_, block, err := client.GetBlockByHeightWhenAvailable(context.Background(), 5, BLOCK_POLL_MIN_INTERVAL)
+		results <- result{block, err}
+	}()
+	waitFor(t, "the first poll", func() bool { return clock.Waiters() == 1 })
+	clock.Advance(BLOCK_POLL_MIN_INTERVAL)
+	got = <-results
+	if got.err == nil {
+		t.Errorf("got block %+v after maxWait, want an error", got.block)
+	}
+}
+
+func TestAbecTxMemoFromRawTransaction(t *testing.T) {
+	// A getrawtransaction response of abec with the verbose flag, abbreviated in its hex and witness.
+	body := `{
+		"result": {
+			"hex": "0100000001",
+			"txid": "9b0fd7c4a2b7b1f5c0c6f3dd0a3b7f3a1e6e5b2d9c4f8a7e6d5c4b3a29180706",
+			"hash": "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+			"size": 9354,
+			"fullsize": 56920,
+			"version": 1,
+			"vin": [
+				{
+					"serialnumber": "5f2e4c9a",
+					"prevutxoring": {
+						"version": 1,
+						"blockhashs": [
+							"00000000c5d2fdcb7c8f0a1c5bd3a8e9b1f5c6d3e2a1b0c9d8e7f6a5b4c3d2e1",
+							"00000000a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c",
+							"000000001f2e3d4c5b6a79887766554433221100ffeeddccbbaa998877665544"
+						],
+						"outpoints": [
+							{"txid": "3c1f5e7a9b2d4f6a8c0e2a4c6e8a0c2e4a6c8e0a2c4e6a8c0e2a4c6e8a0c2e4a", "index": 0},
+							{"txid": "7e9a1c3e5a7c9e1a3c5e7a9c1e3a5c7e9a1c3e5a7c9e1a3c5e7a9c1e3a5c7e9a", "index": 1}
+						]
+					}
+				}
+			],
+			"vout": [
+				{"n": 0, "script": "00000000"},
+				{"n": 1, "script": "00000001"}
+			],
+			"memo": "696e766f696365202334323a20636166c3a9",
+			"fee": 0.0218,
+			"witness": "0a0b0c",
+			"blockhash": "000000002c7b5e1f8d4a3b6c9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f",
+			"confirmations": 12,
+			"time": 1700000000,
+			"blocktime": 1700000000
+		},
+		"error": null,
+		"id": "1"
+	}`
+	resp := &AbecJSONRPCResponse{}
+	err := json.Unmarshal([]byte(body), resp)
+	if err != nil {
+		t.Fatalf("cannot unmarshal the response: %s", err)
+	}
+	tx := &AbecTx{}
+	err = json.Unmarshal(resp.Result, tx)
+	if err != nil {
+		t.Fatalf("cannot unmarshal the tx: %s", err)
+	}
+	if len(tx.Vin) != 1 || len(tx.Vin[0].UTXORing.OutPoints) != 2 || len(tx.Vout) != 2 {
+		t.Fatalf("got tx %+v, want 1 input of a ring of 2 and 2 outputs", tx)
+	}
+
+	memo, err := tx.MemoBytes()
+	if err != nil {
+		t.Fatalf("cannot decode the memo: %s", err)
+	}
+	if want := []byte("invoice #42: café"); !bytes.Equal(memo, want) {
+		t.Errorf("got memo bytes %x, want %x", []byte(memo), want)
+	}
+	text, ok := tx.MemoString()
+	if !ok || text != "invoice #42: café" {
+		t.Errorf("got memo string %q and %t, want the invoice text", text, ok)
+	}
+
+	// A binary memo has bytes but no text, and a memo that is not hex has neither.
+	tx.Memo = "c328ff00"
+	memo, err = tx.MemoBytes()
+	if err != nil || !bytes.Equal(memo, []byte{0xc3, 0x28, 0xff, 0x00}) {
+		t.Errorf("got memo bytes %x and error %v for a binary memo", []byte(memo), err)
+	}
+	if text, ok := tx.MemoString(); ok {
+		t.Errorf("got memo string %q for a binary memo", text)
+	}
+	tx.Memo = "invoice"
+	if _, err := tx.MemoBytes(); err == nil {
+		t.Errorf("decoded a memo that is not hex")
+	}
+	if _, ok := tx.MemoString(); ok {
+		t.Errorf("got a memo string for a memo that is not hex")
+	}
+}
+
+func TestVerifyChainIdentity(t *testing.T) {
+	genesisHash := MAINNET_NETWORK.GenesisHash()
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var height int64
+			json.Unmarshal(params[0], &height)
+			if height != 0 {
+				return nil, &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+			}
+			return genesisHash, nil
+		},
+	})
+
+	err := VerifyChainIdentity(client, genesisHash)
+	if err != nil {
+		t.Errorf("got error %s with the node's genesis", err)
+	}
+	err = VerifyChainIdentity(client, "0x"+genesisHash)
+	if err != nil {
+		t.Errorf("got error %s with the node's genesis prefixed by 0x", err)
+	}
+
+	err = VerifyChainIdentity(client, TESTNET_NETWORK.GenesisHash())
+	if !errors.Is(err, ErrChainMismatch) {
+		t.Errorf("got error %v with another network's genesis, want ErrChainMismatch", err)
+	}
+
+	// An expected hash that is not a hash, or a node that cannot answer, is not a mismatch.
+	err = VerifyChainIdentity(client, "not a hash")
+	if err == nil || errors.Is(err, ErrChainMismatch) {
+		t.Errorf("got error %v with an invalid expected hash, want an error other than ErrChainMismatch", err)
+	}
+	err = VerifyChainCheckpoint(client, 1, genesisHash)
+	if err == nil || errors.Is(err, ErrChainMismatch) {
+		t.Errorf("got error %v when the node fails, want an error other than ErrChainMismatch", err)
+	}
+}
+
+func TestGetBlockByHeightWhenAvailableBacksOff(t *testing.T) {
+	const height = 7
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var mutex sync.Mutex
+	polls := 0
+	failWith := &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			// The block is only connected after two polls.
+			polls++
+			if polls <= 2 {
+				return nil, failWith
+			}
+			return testBlockHash("07"), nil
+		},
+		"getblockabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return &AbecBlock{Height: height, BlockHash: testBlockHash("07")}, nil
+		},
+	}, WithClock(clock))
+
+	type result struct {
+		block *AbecBlock
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		_, block, err := client.GetBlockByHeightWhenAvailable(context.Background(), height, time.Minute)
+		results <- result{block, err}
+	}()
+
+	// The first wait is BLOCK_POLL_MIN_INTERVAL and the second one twice as long.
+	waitFor(t, "the first poll", func() bool { return clock.Waiters() == 1 })
+	clock.Advance(BLOCK_POLL_MIN_INTERVAL)
+	waitFor(t, "the second poll", func() bool { return clock.Waiters() == 1 })
+	clock.Advance(BLOCK_POLL_MIN_INTERVAL)
+	select {
+	case got := <-results:
+		t.Fatalf("got block %+v and error %v before the backed off interval", got.block, got.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(BLOCK_POLL_MIN_INTERVAL)
+	got := <-results
+	if got.err != nil || got.block.Height != height {
+		t.Fatalf("got block %+v and error %v, want the block at height %d", got.block, got.err, height)
+	}
+	mutex.Lock()
+	if polls != 3 {
+		t.Errorf("got %d polls, want 3", polls)
+	}
+
+	// Another error than a missing height is returned at once, without waiting.
+	polls = 0
+	failWith = &AbecJSONRPCError{Code: -32603, Message: "database is closed"}
+	mutex.Unlock()
+	_, _, err := client.GetBlockByHeightWhenAvailable(context.Background(), height, time.Minute)
+	var rpcErr *AbecRPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32603 {
+		t.Errorf("got error %v, want the node error", err)
+	}
+	mutex.Lock()
+	if polls != 1 {
+		t.Errorf("got %d polls, want 1", polls)
+	}
+	mutex.Unlock()
+}
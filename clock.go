@@ -0,0 +1,102 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Define the Clock interface.
+// Clock is the source of time for the client. Tests can replace the system clock with a FakeClock
+// to drive time-dependent logic such as polling and backoff without real sleeps.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// Define the SystemClock data type and methods.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Define the FakeClock data type and methods.
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*fakeClockWaiter
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	waiter := &fakeClockWaiter{
+		deadline: c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		waiter.ch <- c.now
+		return waiter.ch
+	}
+
+	c.waiters = append(c.waiters, waiter)
+	return waiter.ch
+}
+
+// Advance moves the clock forward by d and fires every waiter whose deadline has been reached.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.setLocked(c.now.Add(d))
+}
+
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.setLocked(now)
+}
+
+// Waiters returns the number of pending After calls, so tests can wait until the code under test is blocked.
+func (c *FakeClock) Waiters() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.waiters)
+}
+
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.ch <- now
+	}
+	c.waiters = pending
+}
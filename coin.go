@@ -1,10 +1,15 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // Define the CoinID and Coin data types.
 type CoinID struct {
-	TxHash Bytes
+	TxHash Txid
 	Index  uint8
 }
 
@@ -17,21 +22,130 @@ type Coin struct {
 	TxVoutData        Bytes
 	BlockHash         Bytes
 	BlockHeight       int64
+	IsCoinbase        bool
+	AccountIndex      uint32
+	Label             string
+	Spent             bool
+	SpentByTxHash     Bytes
+	SpentAtHeight     int64
+
+	// serialNoSecretKey is the serial number secret key of the owner, kept by a block scan so that the serial
+	// number can be derived once the ring group of the coin is complete.
+	serialNoSecretKey *CryptoKey
 }
 
 // Define methods for CoinID.
-func NewCoinID(txHash Bytes, index uint8) *CoinID {
+// The output index is a uint8 throughout the SDK, as in wire.OutPointAbe. The node renders it as a JSON number,
+// which is decoded into an int64 and must be converted with OutputIndexFromInt64.
+func NewCoinID(txHash Txid, index uint8) *CoinID {
 	return &CoinID{
 		TxHash: txHash,
 		Index:  index,
 	}
 }
 
+func NewCoinIDFromRPCIndex(txHash Txid, index int64) (*CoinID, error) {
+	outputIndex, err := OutputIndexFromInt64(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCoinID(txHash, outputIndex), nil
+}
+
 func (id CoinID) String() string {
-	return fmt.Sprintf("%s:%d", id.TxHash.HexString(), id.Index)
+	return fmt.Sprintf("%s:%d", id.TxHash, id.Index)
+}
+
+func (id CoinID) Equal(other CoinID) bool {
+	return id.Index == other.Index && id.TxHash == other.TxHash
+}
+
+// Define methods for Coin.
+func (coin *Coin) Equal(other *Coin) bool {
+	if coin == nil || other == nil {
+		return coin == other
+	}
+
+	return coin.ID.Equal(other.ID)
+}
+
+func (coin *Coin) ToTxInDesc() *TxInDesc {
+	return &TxInDesc{
+		TxOutData:        coin.TxVoutData,
+		CoinValue:        coin.Value,
+		Owner:            coin.OwnerShortAddress,
+		Height:           coin.BlockHeight,
+		TxHash:           coin.ID.TxHash.Bytes(),
+		TxOutIndex:       coin.ID.Index,
+		CoinSerialNumber: coin.SerialNumber,
+	}
+}
+
+// DeriveSerialNumber sets SerialNumber from the blocks of the coin's ring group, at the heights given by
+// GetRingBlockHeights. The serial number secret key of the owner is kept for coins found by a block scan.
+func (coin *Coin) DeriveSerialNumber(ringBlockDescs map[int64]*TxBlockDesc) error {
+	serialNumber, err := coin.deriveSerialNumber(ringBlockDescs)
+	if err != nil {
+		return err
+	}
+	coin.SerialNumber = serialNumber
+
+	return nil
+}
+
+func (coin *Coin) deriveSerialNumber(ringBlockDescs map[int64]*TxBlockDesc) (Bytes, error) {
+	if coin.serialNoSecretKey == nil {
+		return nil, fmt.Errorf("coin %s has no serial number secret key", coin.ID)
+	}
+
+	ringGroup := make(map[int64]*TxBlockDesc)
+	for _, height := range GetRingBlockHeights(coin.BlockHeight) {
+		ringBlockDesc, ok := ringBlockDescs[height]
+		if !ok {
+			return nil, fmt.Errorf("ring block at height %d of coin %s is missing", height, coin.ID)
+		}
+		ringGroup[height] = ringBlockDesc
+	}
+
+	serialNumbers, err := DecodeCoinSerialNumbers([]*CoinID{&coin.ID}, []*CryptoKey{coin.serialNoSecretKey}, ringGroup)
+	if err != nil {
+		return nil, fmt.Errorf("serial number of coin %s cannot be derived: %s", coin.ID, err)
+	}
+
+	return serialNumbers[0], nil
+}
+
+// VerifyValue reports whether the coin is worth exactly expected neutrino.
+func (coin *Coin) VerifyValue(expected int64) bool {
+	return coin.Value == expected
+}
+
+func (coin *Coin) Confirmations(currentHeight int64) int64 {
+	if currentHeight < coin.BlockHeight {
+		return 0
+	}
+
+	return currentHeight - coin.BlockHeight + 1
+}
+
+func (coin *Coin) IsMature(currentHeight int64) bool {
+	if !coin.IsCoinbase {
+		return true
+	}
+
+	return coin.Confirmations(currentHeight) > COINBASE_MATURITY
 }
 
 // Define util functions.
+func OutputIndexFromInt64(index int64) (uint8, error) {
+	if index < 0 || index > math.MaxUint8 {
+		return 0, fmt.Errorf("output index %d is out of range [0, %d]", index, math.MaxUint8)
+	}
+
+	return uint8(index), nil
+}
+
 func NeutrinoToAbel(neutrinoAmount int64) float64 {
 	return float64(neutrinoAmount) / 1e7
 }
@@ -39,3 +153,52 @@ func NeutrinoToAbel(neutrinoAmount int64) float64 {
 func AbelToNeutrino(abelAmount float64) int64 {
 	return int64(abelAmount * 1e7)
 }
+
+func FormatAbel(neutrinoAmount int64, trimZeros ...bool) string {
+	// Format with integer arithmetic to avoid float artifacts such as 0.30000000000000004.
+	sign := ""
+	amount := uint64(neutrinoAmount)
+	if neutrinoAmount < 0 {
+		sign = "-"
+		amount = uint64(-(neutrinoAmount + 1)) + 1
+	}
+
+	formatted := fmt.Sprintf("%s%d.%07d", sign, amount/1e7, amount%1e7)
+	if len(trimZeros) > 0 && trimZeros[0] {
+		formatted = strings.TrimRight(formatted, "0")
+		formatted = strings.TrimSuffix(formatted, ".")
+	}
+
+	return formatted
+}
+
+// ParseAbel is the inverse of FormatAbel. It parses a decimal ABEL amount into neutrino without going through float64.
+func ParseAbel(abelAmount string) (int64, error) {
+	whole, fraction, _ := strings.Cut(abelAmount, ".")
+	if len(whole) == 0 && len(fraction) == 0 {
+		return 0, fmt.Errorf("abel amount %q is empty", abelAmount)
+	}
+	if len(fraction) > 7 {
+		return 0, fmt.Errorf("abel amount %q has more than 7 decimals", abelAmount)
+	}
+	if len(whole) == 0 {
+		whole = "0"
+	}
+
+	wholeValue, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("abel amount %q is not valid: %s", abelAmount, err)
+	}
+	fractionValue := uint64(0)
+	if len(fraction) > 0 {
+		fractionValue, err = strconv.ParseUint(fraction+strings.Repeat("0", 7-len(fraction)), 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("abel amount %q is not valid: %s", abelAmount, err)
+		}
+	}
+	if wholeValue > (math.MaxInt64-fractionValue)/1e7 {
+		return 0, fmt.Errorf("abel amount %q is too large", abelAmount)
+	}
+
+	return int64(wholeValue*1e7 + fractionValue), nil
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOutputIndexFromInt64(t *testing.T) {
+	tests := []struct {
+		index   int64
+		want    uint8
+		wantErr bool
+	}{
+		{0, 0, false},
+		{255, 255, false},
+		{256, 0, true},
+		{-1, 0, true},
+	}
+	for _, test := range tests {
+		got, err := OutputIndexFromInt64(test.index)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("index %d: got %d and error %v, want %d and error %v", test.index, got, err, test.want, test.wantErr)
+		}
+
+		coinID, err := NewCoinIDFromRPCIndex(Txid{1}, test.index)
+		if test.wantErr {
+			if err == nil || coinID != nil {
+				t.Errorf("index %d: got coin id %v, want an error", test.index, coinID)
+			}
+			continue
+		}
+		if err != nil || coinID.TxHash != (Txid{1}) || coinID.Index != test.want {
+			t.Errorf("index %d: got coin id %v and error %v", test.index, coinID, err)
+		}
+	}
+}
+
+func TestFormatAbel(t *testing.T) {
+	tests := []struct {
+		neutrino    int64
+		want        string
+		wantTrimmed string
+	}{
+		{0, "0.0000000", "0"},
+		{1, "0.0000001", "0.0000001"},
+		// Adding 0.1 and 0.2 ABEL as floats gives 0.30000000000000004.
+		{3000000, "0.3000000", "0.3"},
+		{1000000 + 2000000, "0.3000000", "0.3"},
+		{10000000, "1.0000000", "1"},
+		{12345678, "1.2345678", "1.2345678"},
+		{123456789012345678, "12345678901.2345678", "12345678901.2345678"},
+		{-5000000, "-0.5000000", "-0.5"},
+		{-1, "-0.0000001", "-0.0000001"},
+		// The largest amounts are beyond the precision of a float64.
+		{math.MaxInt64, "922337203685.4775807", "922337203685.4775807"},
+		{math.MinInt64, "-922337203685.4775808", "-922337203685.4775808"},
+	}
+	for _, test := range tests {
+		if got := FormatAbel(test.neutrino); got != test.want {
+			t.Errorf("%d neutrino: got %s, want %s", test.neutrino, got, test.want)
+		}
+		if got := FormatAbel(test.neutrino, true); got != test.wantTrimmed {
+			t.Errorf("%d neutrino trimmed: got %s, want %s", test.neutrino, got, test.wantTrimmed)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package core
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
 	"sort"
+	"sync"
 
 	api "github.com/abesuite/abec/sdkapi/v1"
 )
@@ -19,9 +24,13 @@ func GetRingBlockHeights(height int64) []int64 {
 	return ringBlockHeights
 }
 
+// ErrKeyZeroized is returned when a key that has been wiped with Zeroize is used.
+var ErrKeyZeroized = errors.New("crypto key has been zeroized")
+
 // Define the CryptoKey data type.
 type CryptoKey struct {
 	Bytes
+	zeroized bool
 }
 
 // Define methods for CryptoKey.
@@ -29,6 +38,25 @@ func NewCryptoKey(data Bytes) *CryptoKey {
 	return &CryptoKey{Bytes: data}
 }
 
+// Zeroize overwrites the key with zeros and marks it, so that any later use fails with ErrKeyZeroized
+// instead of silently working with an all-zero key.
+func (key *CryptoKey) Zeroize() {
+	zeroBytes(key.Bytes)
+	key.zeroized = true
+}
+
+func (key *CryptoKey) IsZeroized() bool {
+	return key.zeroized
+}
+
+func (key *CryptoKey) checkUsable() error {
+	if key.zeroized {
+		return ErrKeyZeroized
+	}
+
+	return nil
+}
+
 // Define the CryptoKeysAndAddress data type.
 type CryptoKeysAndAddress struct {
 	SpendSecretKey    CryptoKey
@@ -37,6 +65,24 @@ type CryptoKeysAndAddress struct {
 	CryptoAddress     CryptoAddress
 }
 
+// Define methods for CryptoKeysAndAddress.
+// ChangeAddress returns the address change is sent to. Each set of keys has exactly one crypto address, and
+// a fresh one can only come from a fresh seed, so change goes back to the same address the inputs were paid to.
+func (keys *CryptoKeysAndAddress) ChangeAddress(chainID int8) (*AbelAddress, error) {
+	if keys.CryptoAddress.Data().Len() == 0 {
+		return nil, fmt.Errorf("keys have no crypto address")
+	}
+
+	return NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, chainID), nil
+}
+
+// Zeroize wipes the three secret keys with CryptoKey.Zeroize. The crypto address is public and is kept.
+func (keys *CryptoKeysAndAddress) Zeroize() {
+	keys.SpendSecretKey.Zeroize()
+	keys.SerialNoSecretKey.Zeroize()
+	keys.ViewSecretKey.Zeroize()
+}
+
 // Define wrapper methods for Abec APIs.
 func GenerateSafeCryptoSeed() (Bytes, error) {
 	return api.CryptoAddressKeySeedGen()
@@ -58,6 +104,51 @@ func GenerateCryptoKeysAndAddress(cryptoSeed Bytes) (*CryptoKeysAndAddress, erro
 	return cryptoKeysAndAddress, nil
 }
 
+func ValidateCryptoAddress(data Bytes) error {
+	valid, hints := api.CheckCryptoAddress(data)
+	if valid {
+		return nil
+	}
+
+	if len(hints) > 0 {
+		return fmt.Errorf("crypto address is not cryptographically valid: %s", hints)
+	}
+	return fmt.Errorf("crypto address is not cryptographically valid")
+}
+
+// ValidateCryptoAddressesConcurrent validates addrs with up to concurrency workers, or one per CPU if concurrency
+// is not positive, since the check is CPU-bound.
+// The i-th error is nil if and only if the i-th address is valid.
+func ValidateCryptoAddressesConcurrent(addrs []Bytes, concurrency int) []error {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > len(addrs) {
+		concurrency = len(addrs)
+	}
+
+	errs := make([]error, len(addrs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				errs[index] = ValidateCryptoAddress(addrs[index])
+			}
+		}()
+	}
+
+	for i := range addrs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
+
 func DecodeCoinAddressFromTxOutData(txOutData Bytes) (*CoinAddress, error) {
 	coinAddressData, err := api.ExtractCoinAddressFromSerializedTxOut(txOutData)
 	if err != nil {
@@ -68,10 +159,16 @@ func DecodeCoinAddressFromTxOutData(txOutData Bytes) (*CoinAddress, error) {
 }
 
 func DecodeValueFromTxOutData(txOutData Bytes, viewSecretKey *CryptoKey) (int64, error) {
+	err := viewSecretKey.checkUsable()
+	if err != nil {
+		return -1, err
+	}
+
 	// api.ExtractCoinValueFromSerializedTxOut will clear up the view secret key param.
 	// Thus we pass a copy of the view secret key to avoid this side effect.
 	viewSecretKeyData := make([]byte, viewSecretKey.Len())
 	copy(viewSecretKeyData, viewSecretKey.Bytes)
+	defer zeroBytes(viewSecretKeyData)
 
 	value, err := api.ExtractCoinValueFromSerializedTxOut(txOutData, viewSecretKeyData)
 	if err != nil {
@@ -82,9 +179,18 @@ func DecodeValueFromTxOutData(txOutData Bytes, viewSecretKey *CryptoKey) (int64,
 }
 
 func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
+	return GenerateUnsignedRawTxContext(context.Background(), txDesc)
+}
+
+// GenerateUnsignedRawTxContext is like GenerateUnsignedRawTx, but checks ctx between the build steps and
+// returns ctx.Err() once it is done. A step that is already running inside the API is not interrupted.
+func GenerateUnsignedRawTxContext(ctx context.Context, txDesc *TxDesc) (*UnsignedRawTx, error) {
 	// Prepare outPointsToSpend.
 	outPointsToSpend := make([]*api.OutPoint, 0, len(txDesc.TxInDescs))
 	for i := 0; i < len(txDesc.TxInDescs); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		txidStr := hex.EncodeToString(txDesc.TxInDescs[i].TxHash)
 		outPoint, err := api.NewOutPointFromTxIdStr(txidStr, txDesc.TxInDescs[i].TxOutIndex)
 		if err != nil {
@@ -106,6 +212,9 @@ func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
 	}
 
 	// Call API to build the serializedTxRequestDesc.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	serializedTxRequestDesc, err := api.BuildTransferTxRequestDescFromBlocks(
 		outPointsToSpend,
 		serializedBlocksForRingGroup,
@@ -116,6 +225,9 @@ func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Create an unsigned raw tx and return it.
 	signers := make([]*ShortAbelAddress, 0, len(txDesc.TxInDescs))
@@ -126,15 +238,43 @@ func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
 	return NewUnsignedRawTx(serializedTxRequestDesc, signers), nil
 }
 
+// GenerateSignedRawTx signs the tx with fresh randomness, so signing the same unsigned raw tx twice gives
+// different bytes and a different txid. The API draws that randomness from crypto/rand and liboqs internally
+// and offers no way to supply a source, so there is no reproducible signing mode; compare signed txs with
+// SignedRawTx.Validate and the decoded tx rather than by their bytes.
 func GenerateSignedRawTx(unsignedRawTx *UnsignedRawTx, signerKeys []*CryptoKeysAndAddress) (*SignedRawTx, error) {
+	return GenerateSignedRawTxContext(context.Background(), unsignedRawTx, signerKeys)
+}
+
+// GenerateSignedRawTxContext is like GenerateSignedRawTx, but returns ctx.Err() if ctx is done before or
+// after signing. Signing itself is a single API call and is not interrupted.
+func GenerateSignedRawTxContext(ctx context.Context, unsignedRawTx *UnsignedRawTx, signerKeys []*CryptoKeysAndAddress) (*SignedRawTx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Prepare cryptoKeys.
 	cryptoKeys := make([]*api.CryptoKey, 0, len(signerKeys))
 	for i := 0; i < len(signerKeys); i++ {
+		for _, key := range []*CryptoKey{&signerKeys[i].SpendSecretKey, &signerKeys[i].SerialNoSecretKey, &signerKeys[i].ViewSecretKey} {
+			if err := key.checkUsable(); err != nil {
+				return nil, fmt.Errorf("signer %d: %w", i, err)
+			}
+		}
+		// api.CreateTransferTx clears up the secret key params, like api.ExtractCoinValueFromSerializedTxOut.
+		// Thus we pass copies of the secret keys, so that the signer keys can sign again.
+		secretKeysData := make([][]byte, 0, 3)
+		for _, key := range []*CryptoKey{&signerKeys[i].SpendSecretKey, &signerKeys[i].SerialNoSecretKey, &signerKeys[i].ViewSecretKey} {
+			secretKeyData := make([]byte, key.Len())
+			copy(secretKeyData, key.Bytes)
+			defer zeroBytes(secretKeyData)
+			secretKeysData = append(secretKeysData, secretKeyData)
+		}
 		cryptoKeys = append(cryptoKeys, api.NewCryptoKey(
 			signerKeys[i].CryptoAddress.Data(),
-			signerKeys[i].SpendSecretKey.Bytes,
-			signerKeys[i].SerialNoSecretKey.Bytes,
-			signerKeys[i].ViewSecretKey.Bytes))
+			secretKeysData[0],
+			secretKeysData[1],
+			secretKeysData[2]))
 	}
 
 	// Call API to create the signed raw tx.
@@ -142,23 +282,24 @@ func GenerateSignedRawTx(unsignedRawTx *UnsignedRawTx, signerKeys []*CryptoKeysA
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Create a signed raw tx and return it.
 	// NOTE: The txid used by the RPC/SDK/UI is a reversed version of the txid used by the API.
-	reversedTxid := make([]byte, len(txid))
-	for i := 0; i < len(txid); i++ {
-		reversedTxid[i] = txid[len(txid)-i-1]
+	signedTxid, err := NewTxidFromAPIBytes(txid[:])
+	if err != nil {
+		return nil, err
 	}
-
-	return NewSignedRawTx(serializedTxFull, AsBytes(reversedTxid)), nil
+	return NewSignedRawTx(serializedTxFull, signedTxid, unsignedRawTx.Signers), nil
 }
 
 func DecodeCoinSerialNumbers(coinIDs []*CoinID, serialNoSecretKeys []*CryptoKey, ringBlockDescs map[int64]*TxBlockDesc) ([]Bytes, error) {
 	// Prepare outPoints.
 	outPoints := make([]*api.OutPoint, len(coinIDs))
 	for i := 0; i < len(coinIDs); i++ {
-		txidStr := hex.EncodeToString(coinIDs[i].TxHash)
-		outPoint, err := api.NewOutPointFromTxIdStr(txidStr, coinIDs[i].Index)
+		outPoint, err := api.NewOutPointFromTxIdStr(coinIDs[i].TxHash.String(), coinIDs[i].Index)
 		if err != nil {
 			return nil, err
 		}
@@ -171,6 +312,9 @@ func DecodeCoinSerialNumbers(coinIDs []*CoinID, serialNoSecretKeys []*CryptoKey,
 	// Prepare cryptoSecretKeys.
 	cryptoSecretKeys := make([]*api.CryptoKey, len(serialNoSecretKeys))
 	for i := 0; i < len(serialNoSecretKeys); i++ {
+		if err := serialNoSecretKeys[i].checkUsable(); err != nil {
+			return nil, fmt.Errorf("serial number secret key %d: %w", i, err)
+		}
 		cryptoSecretKeys[i] = api.NewCryptoKey(nil, nil, serialNoSecretKeys[i].Bytes, nil)
 	}
 
@@ -189,6 +333,21 @@ func DecodeCoinSerialNumbers(coinIDs []*CoinID, serialNoSecretKeys []*CryptoKey,
 	return coinSerialNumbers, nil
 }
 
+func zeroBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
+
+func reversedBytes(data []byte) Bytes {
+	reversed := make([]byte, len(data))
+	for i := 0; i < len(data); i++ {
+		reversed[i] = data[len(data)-i-1]
+	}
+
+	return AsBytes(reversed)
+}
+
 func getSerializedBlocksForRingGroup(ringBlockDescs map[int64]*TxBlockDesc) [][]byte {
 	heights := make([]int64, 0, len(ringBlockDescs))
 	for height := range ringBlockDescs {
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// cancelAfterContext is a context that is cancelled once Err has been called checks times, so that a test can
+// cancel at each point where a function checks ctx.
+type cancelAfterContext struct {
+	context.Context
+
+	mutex  sync.Mutex
+	checks int
+}
+
+func newCancelAfterContext(checks int) *cancelAfterContext {
+	return &cancelAfterContext{Context: context.Background(), checks: checks}
+}
+
+func (ctx *cancelAfterContext) Err() error {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	if ctx.checks <= 0 {
+		return context.Canceled
+	}
+	ctx.checks--
+	return nil
+}
+
+// newTestTxDesc returns a tx desc, with its ring blocks, that spends a coin of keys in a simulation chain to keys.
+func newTestTxDesc(t *testing.T, keys *CryptoKeysAndAddress) *TxDesc {
+	t.Helper()
+
+	client, coin := newTestBuilderCoin(t, keys, 5000000)
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+	txDesc, err := NewTxBuilder(client).AddInput(coin).AddRecipient(recipient, 3000000).SetFeeRate(0).SetChangeAddress(recipient).Build()
+	if err != nil {
+		t.Fatalf("cannot build the tx desc: %s", err)
+	}
+	txDesc.TxRingBlockDescs, err = FetchRingBlocksForInputs(client, txDesc.TxInDescs)
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+
+	return txDesc
+}
+
+func TestGenerateRawTxContextCancellation(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+
+	// Cancelling at each check of ctx stops the build, until the build no longer checks it again.
+	var unsignedRawTx *UnsignedRawTx
+	for checks := 0; unsignedRawTx == nil; checks++ {
+		if checks > 100 {
+			t.Fatalf("the build checks ctx more than 100 times")
+		}
+		var err error
+		unsignedRawTx, err = GenerateUnsignedRawTxContext(newCancelAfterContext(checks), txDesc)
+		if unsignedRawTx == nil && !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled after %d checks: got error %v, want context.Canceled", checks, err)
+		}
+		if unsignedRawTx != nil && checks < 2 {
+			t.Errorf("the build completed after %d checks of ctx, want checks before and after the API call", checks)
+		}
+	}
+
+	// Signing checks ctx before it signs and after, when the signed tx is dropped and the keys, which the API
+	// must not have wiped, sign again.
+	for _, checks := range []int{0, 1} {
+		signedRawTx, err := GenerateSignedRawTxContext(newCancelAfterContext(checks), unsignedRawTx, []*CryptoKeysAndAddress{keys})
+		if signedRawTx != nil || !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled after %d checks: got a signed tx and error %v, want context.Canceled", checks, err)
+		}
+	}
+	signedRawTx, err := GenerateSignedRawTxContext(newCancelAfterContext(2), unsignedRawTx, []*CryptoKeysAndAddress{keys})
+	if err != nil {
+		t.Fatalf("cannot sign: %s", err)
+	}
+	if signedRawTx.Bytes.Len() == 0 {
+		t.Errorf("got an empty signed tx")
+	}
+}
+
+// copyTestKeys returns keys that do not share any bytes with keys, so that zeroizing them leaves keys usable.
+func copyTestKeys(keys *CryptoKeysAndAddress) *CryptoKeysAndAddress {
+	return &CryptoKeysAndAddress{
+		SpendSecretKey:    *NewCryptoKey(append(Bytes(nil), keys.SpendSecretKey.Bytes...)),
+		SerialNoSecretKey: *NewCryptoKey(append(Bytes(nil), keys.SerialNoSecretKey.Bytes...)),
+		ViewSecretKey:     *NewCryptoKey(append(Bytes(nil), keys.ViewSecretKey.Bytes...)),
+		CryptoAddress:     keys.CryptoAddress,
+	}
+}
+
+func TestSignWithZeroizedKey(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+	unsignedRawTx, err := GenerateUnsignedRawTx(txDesc)
+	if err != nil {
+		t.Fatalf("cannot build the unsigned raw tx: %s", err)
+	}
+
+	zeroizers := map[string]func(keys *CryptoKeysAndAddress){
+		"spend":     func(keys *CryptoKeysAndAddress) { keys.SpendSecretKey.Zeroize() },
+		"serial no": func(keys *CryptoKeysAndAddress) { keys.SerialNoSecretKey.Zeroize() },
+		"view":      func(keys *CryptoKeysAndAddress) { keys.ViewSecretKey.Zeroize() },
+		"all":       func(keys *CryptoKeysAndAddress) { keys.Zeroize() },
+	}
+	for name, zeroize := range zeroizers {
+		signerKeys := copyTestKeys(keys)
+		zeroize(signerKeys)
+
+		signedRawTx, err := GenerateSignedRawTx(unsignedRawTx, []*CryptoKeysAndAddress{signerKeys})
+		if signedRawTx != nil || !errors.Is(err, ErrKeyZeroized) {
+			t.Errorf("%s key zeroized: got a signed tx and error %v, want ErrKeyZeroized", name, err)
+		}
+	}
+
+	// Deriving with a zeroized key fails the same way.
+	zeroizedKeys := copyTestKeys(keys)
+	zeroizedKeys.Zeroize()
+	_, err = DecodeValueFromTxOutData(txDesc.TxInDescs[0].TxOutData, &zeroizedKeys.ViewSecretKey)
+	if !errors.Is(err, ErrKeyZeroized) {
+		t.Errorf("got error %v decoding a value, want ErrKeyZeroized", err)
+	}
+	_, err = txDesc.SerialNumbersToReveal([]*CryptoKey{&zeroizedKeys.SerialNoSecretKey}, nil)
+	if !errors.Is(err, ErrKeyZeroized) {
+		t.Errorf("got error %v deriving a serial number, want ErrKeyZeroized", err)
+	}
+
+	// The keys the zeroized ones were copied from still sign.
+	_, err = GenerateSignedRawTx(unsignedRawTx, []*CryptoKeysAndAddress{keys})
+	if err != nil {
+		t.Errorf("cannot sign with the original keys: %s", err)
+	}
+}
+
+func TestCryptoKeysAndAddressZeroize(t *testing.T) {
+	keys := newTestKeys(t)
+	cryptoAddress := append(Bytes(nil), keys.CryptoAddress.Data()...)
+	secretKeys := map[string]*CryptoKey{
+		"spend":     &keys.SpendSecretKey,
+		"serial no": &keys.SerialNoSecretKey,
+		"view":      &keys.ViewSecretKey,
+	}
+	lengths := make(map[string]int)
+	for name, key := range secretKeys {
+		if key.IsZeroized() || bytes.Count(key.Bytes, []byte{0}) == key.Len() {
+			t.Fatalf("%s key is zeroized before Zeroize", name)
+		}
+		lengths[name] = key.Len()
+	}
+
+	keys.Zeroize()
+	for name, key := range secretKeys {
+		if !key.IsZeroized() {
+			t.Errorf("%s key is not marked as zeroized", name)
+		}
+		if key.Len() != lengths[name] || bytes.Count(key.Bytes, []byte{0}) != key.Len() {
+			t.Errorf("%s key of %d bytes is not overwritten with %d zeros", name, key.Len(), lengths[name])
+		}
+	}
+	if !bytes.Equal(keys.CryptoAddress.Data(), cryptoAddress) {
+		t.Errorf("the public crypto address was changed")
+	}
+
+	// Zeroizing again is harmless.
+	keys.Zeroize()
+	if !keys.SpendSecretKey.IsZeroized() {
+		t.Errorf("spend key is not marked as zeroized after zeroizing again")
+	}
+}
+
+func TestDecodeValueKeepsViewSecretKey(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+	viewSecretKey := append(Bytes(nil), keys.ViewSecretKey.Bytes...)
+
+	// The API wipes the key it is given, so decoding twice only works if it is given a copy.
+	for i := 0; i < 2; i++ {
+		value, err := DecodeValueFromTxOutData(txDesc.TxInDescs[0].TxOutData, &keys.ViewSecretKey)
+		if err != nil || value != txDesc.TxInDescs[0].CoinValue {
+			t.Fatalf("decode %d: got value %d and error %v, want %d", i, value, err, txDesc.TxInDescs[0].CoinValue)
+		}
+	}
+	if keys.ViewSecretKey.IsZeroized() || !bytes.Equal(keys.ViewSecretKey.Bytes, viewSecretKey) {
+		t.Errorf("decoding changed the view secret key")
+	}
+}
+
+func TestValidateCryptoAddressesConcurrent(t *testing.T) {
+	// Valid and invalid addresses alternate, so that an error reported at the wrong index shows.
+	addrs := make([]Bytes, 0, 8)
+	for i := 0; i < 4; i++ {
+		valid := newTestKeys(t).CryptoAddress.Data()
+		addrs = append(addrs, valid, valid[:len(valid)/(i+2)])
+	}
+
+	for _, concurrency := range []int{0, 1, 3, 100} {
+		errs := ValidateCryptoAddressesConcurrent(addrs, concurrency)
+		if len(errs) != len(addrs) {
+			t.Fatalf("concurrency %d: got %d errors for %d addresses", concurrency, len(errs), len(addrs))
+		}
+		for i, err := range errs {
+			if wantValid := i%2 == 0; (err == nil) != wantValid {
+				t.Errorf("concurrency %d: address %d got error %v, want valid %v", concurrency, i, err, wantValid)
+			}
+		}
+	}
+
+	if errs := ValidateCryptoAddressesConcurrent(nil, 4); len(errs) != 0 {
+		t.Errorf("got %d errors without addresses", len(errs))
+	}
+}
+
+func BenchmarkValidateCryptoAddressesConcurrent(b *testing.B) {
+	addrs := make([]Bytes, 64)
+	for i := range addrs {
+		addrs[i] = newTestKeys(b).CryptoAddress.Data()
+	}
+
+	concurrencies := []int{1, 2, 4}
+	if runtime.NumCPU() > 4 {
+		concurrencies = append(concurrencies, runtime.NumCPU())
+	}
+	for _, concurrency := range concurrencies {
+		b.Run(fmt.Sprintf("%d workers", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ValidateCryptoAddressesConcurrent(addrs, concurrency)
+			}
+		})
+	}
+}
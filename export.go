@@ -0,0 +1,93 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Define constants.
+var historyCSVHeader = []string{"txid", "index", "value", "height", "spent"}
+
+// Define the historyRow data type.
+type historyRow struct {
+	Txid   Txid   `json:"txid"`
+	Index  uint8  `json:"index"`
+	Value  string `json:"value"`
+	Height int64  `json:"height"`
+	Spent  bool   `json:"spent"`
+}
+
+// Define util functions.
+// HistoryCSVHeader returns the header row written by ExportHistoryCSV. It is a copy, so changing it does not
+// change the exported files.
+func HistoryCSVHeader() []string {
+	return append([]string(nil), historyCSVHeader...)
+}
+
+// ExportHistoryCSV writes one row per coin after HistoryCSVHeader, with values in ABEL as formatted by FormatAbel.
+// Rows are written as they are produced, so the output is never held in memory as a whole.
+func ExportHistoryCSV(w io.Writer, coins []*Coin) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(historyCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, coin := range coins {
+		row := newHistoryRow(coin)
+		err = writer.Write([]string{
+			row.Txid.String(),
+			strconv.Itoa(int(row.Index)),
+			row.Value,
+			strconv.FormatInt(row.Height, 10),
+			strconv.FormatBool(row.Spent),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportHistoryJSON writes the coins as a JSON array with the same fields as ExportHistoryCSV, one element at a time.
+func ExportHistoryJSON(w io.Writer, coins []*Coin) error {
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	for i, coin := range coins {
+		data, err := json.Marshal(newHistoryRow(coin))
+		if err != nil {
+			return fmt.Errorf("coin %s cannot be exported: %s", coin.ID, err)
+		}
+		if i > 0 {
+			_, err = io.WriteString(w, ",\n")
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+func newHistoryRow(coin *Coin) *historyRow {
+	return &historyRow{
+		Txid:   coin.ID.TxHash,
+		Index:  coin.ID.Index,
+		Value:  FormatAbel(coin.Value),
+		Height: coin.BlockHeight,
+		Spent:  coin.Spent,
+	}
+}
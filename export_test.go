@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func newTestHistory() []*Coin {
+	spent := newTestCoin(2, 5000000, 20)
+	spent.Spent = true
+
+	return []*Coin{newTestCoin(1, 12345678, 10), spent}
+}
+
+func TestExportHistoryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := ExportHistoryCSV(&buf, newTestHistory())
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("cannot read the csv: %s", err)
+	}
+	want := [][]string{
+		{"txid", "index", "value", "height", "spent"},
+		{Txid{1}.String(), "1", "1.2345678", "10", "false"},
+		{Txid{2}.String(), "2", "0.5000000", "20", "true"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got rows %q, want %q", records, want)
+	}
+
+	// The header cannot be changed through the copy returned by HistoryCSVHeader.
+	header := HistoryCSVHeader()
+	header[0] = "changed"
+	if !reflect.DeepEqual(HistoryCSVHeader(), want[0]) {
+		t.Errorf("got header %q after changing a copy, want %q", HistoryCSVHeader(), want[0])
+	}
+
+	buf.Reset()
+	err = ExportHistoryCSV(&buf, nil)
+	if err != nil || buf.String() != "txid,index,value,height,spent\n" {
+		t.Errorf("got %q and error %v without coins, want only the header", buf.String(), err)
+	}
+}
+
+func TestExportHistoryJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := ExportHistoryJSON(&buf, newTestHistory())
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	var rows []historyRow
+	err = json.Unmarshal(buf.Bytes(), &rows)
+	if err != nil {
+		t.Fatalf("cannot decode %q: %s", buf.String(), err)
+	}
+	want := []historyRow{
+		{Txid: Txid{1}, Index: 1, Value: "1.2345678", Height: 10},
+		{Txid: Txid{2}, Index: 2, Value: "0.5000000", Height: 20, Spent: true},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows %+v, want %+v", rows, want)
+	}
+
+	buf.Reset()
+	err = ExportHistoryJSON(&buf, nil)
+	if err != nil || buf.String() != "[]\n" {
+		t.Errorf("got %q and error %v without coins, want an empty array", buf.String(), err)
+	}
+}
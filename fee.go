@@ -0,0 +1,292 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/abesuite/abec/wire"
+)
+
+// Define constants.
+const (
+	// DEFAULT_BLOCK_MAX_SIZE is the default size limit (without witnesses) of blocks assembled by abec miners.
+	DEFAULT_BLOCK_MAX_SIZE = 750000
+
+	// DEFAULT_TX_FEE is the flat fee, 0.1 ABEL, used when the node has no fee rate estimate.
+	DEFAULT_TX_FEE = 1000000
+)
+
+// DefaultFeeHistogramBoundaries are the lower fee rate bounds, in neutrino per kB, of the buckets used by
+// MempoolFeeHistogram. They step 1-2-5 from 1000 to 50000000, below a bucket that starts at 0.
+var DefaultFeeHistogramBoundaries = feeHistogramBoundaries(1000, 50000000)
+
+// Define the FeeBucket data type.
+// FeeBucket covers the mempool txs with fee rates in [MinFeeRatePerKB, MaxFeeRatePerKB). CumulativeSize is the
+// size of the txs in this bucket and every bucket with higher fee rates.
+type FeeBucket struct {
+	MinFeeRatePerKB int64
+	MaxFeeRatePerKB int64
+	Count           int
+	Size            int64
+	CumulativeSize  int64
+}
+
+// Define the FeePolicy data type.
+type FeePolicy struct {
+	FeeRatePerKB    int64
+	MinFeeRatePerKB int64
+}
+
+// Define methods for FeePolicy.
+func NewFeePolicy(feeRatePerKB int64, minFeeRatePerKB ...int64) *FeePolicy {
+	if len(minFeeRatePerKB) == 0 {
+		minFeeRatePerKB = []int64{0}
+	}
+
+	return &FeePolicy{
+		FeeRatePerKB:    feeRatePerKB,
+		MinFeeRatePerKB: minFeeRatePerKB[0],
+	}
+}
+
+func (policy *FeePolicy) ComputeFee(txSize int64) int64 {
+	return ComputeFee(txSize, policy.FeeRatePerKB, policy.MinFeeRatePerKB)
+}
+
+// Define methods for AbecChainInfo.
+func (info *AbecChainInfo) RelayFeeNeutrinoPerKB() int64 {
+	return AbelToNeutrino(info.RelayFee)
+}
+
+// Define util functions.
+// ComputeFeeForTarget returns the fee for txDesc to confirm within confTarget blocks, using the node's
+// fee rate estimate and flooring the rate at the node's relay fee. confTarget must be in
+// [1, MAX_FEE_ESTIMATE_CONF_TARGET].
+// If the node has no estimate, e.g. because it has not yet observed enough blocks or fee estimation is
+// disabled, the flat DEFAULT_TX_FEE is returned instead.
+func ComputeFeeForTarget(client *AbecRPCClient, txDesc *TxDesc, confTarget int) (int64, error) {
+	if confTarget <= 0 || confTarget > MAX_FEE_ESTIMATE_CONF_TARGET {
+		return 0, fmt.Errorf("confirmation target %d is not in range [1, %d]", confTarget, MAX_FEE_ESTIMATE_CONF_TARGET)
+	}
+
+	txSize, err := EstimateTxSize(txDesc)
+	if err != nil {
+		return 0, err
+	}
+
+	feeRate, ok, err := client.estimateFeeRatePerKB(int64(confTarget))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return DEFAULT_TX_FEE, nil
+	}
+
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	return ComputeFee(txSize, feeRate, chainInfo.RelayFeeNeutrinoPerKB()), nil
+}
+
+// EstimateConfirmationBlocks approximates how many blocks it takes to confirm a tx paying feeRateNeutrinoPerKB.
+// It assumes that miners fill blocks of blockMaxSize bytes (DEFAULT_BLOCK_MAX_SIZE by default) strictly by fee
+// rate, that every mempool tx paying at least the same rate is mined first, and that no new txs arrive.
+// Like the miners, it measures fee rates and block space by tx size without witnesses.
+func EstimateConfirmationBlocks(client *AbecRPCClient, feeRateNeutrinoPerKB int64, blockMaxSize ...int64) (int, error) {
+	if len(blockMaxSize) == 0 || blockMaxSize[0] <= 0 {
+		blockMaxSize = []int64{DEFAULT_BLOCK_MAX_SIZE}
+	}
+
+	_, mempool, err := client.GetMempool()
+	if err != nil {
+		return 0, err
+	}
+
+	sizeAhead := int64(0)
+	for _, entry := range *mempool {
+		if entry.Size <= 0 {
+			continue
+		}
+		if mempoolFeeRate(AbelToNeutrino(entry.Fee), entry.Size) >= feeRateNeutrinoPerKB {
+			sizeAhead += entry.Size
+		}
+	}
+
+	return int(sizeAhead/blockMaxSize[0]) + 1, nil
+}
+
+// MempoolFeeHistogram buckets the mempool txs by fee rate, highest fee rates first, and leaves out empty buckets.
+// Sizes and fee rates are measured by tx size without witnesses, as the node does. The bucket lower bounds are
+// DefaultFeeHistogramBoundaries unless others are given.
+func MempoolFeeHistogram(client *AbecRPCClient, boundaries ...int64) ([]*FeeBucket, error) {
+	_, mempool, err := client.GetMempool()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(boundaries) == 0 {
+		boundaries = DefaultFeeHistogramBoundaries
+	}
+	sortedBoundaries := make([]int64, 0, len(boundaries)+1)
+	sortedBoundaries = append(sortedBoundaries, 0)
+	for _, boundary := range boundaries {
+		if boundary > 0 {
+			sortedBoundaries = append(sortedBoundaries, boundary)
+		}
+	}
+	sort.Slice(sortedBoundaries, func(i, j int) bool {
+		return sortedBoundaries[i] > sortedBoundaries[j]
+	})
+
+	buckets := make([]*FeeBucket, 0, len(sortedBoundaries))
+	maxFeeRate := int64(math.MaxInt64)
+	for _, boundary := range sortedBoundaries {
+		if boundary == maxFeeRate {
+			continue
+		}
+		buckets = append(buckets, &FeeBucket{MinFeeRatePerKB: boundary, MaxFeeRatePerKB: maxFeeRate})
+		maxFeeRate = boundary
+	}
+
+	for _, entry := range *mempool {
+		if entry.Size <= 0 {
+			continue
+		}
+		feeRate := mempoolFeeRate(AbelToNeutrino(entry.Fee), entry.Size)
+		for _, bucket := range buckets {
+			if feeRate >= bucket.MinFeeRatePerKB {
+				bucket.Count++
+				bucket.Size += entry.Size
+				break
+			}
+		}
+	}
+
+	histogram := make([]*FeeBucket, 0, len(buckets))
+	cumulativeSize := int64(0)
+	for _, bucket := range buckets {
+		cumulativeSize += bucket.Size
+		bucket.CumulativeSize = cumulativeSize
+		if bucket.Count > 0 {
+			histogram = append(histogram, bucket)
+		}
+	}
+
+	return histogram, nil
+}
+
+// FeeRateForBlockBudget returns the lowest bucket bound at which at most sizeBudget bytes of mempool txs,
+// e.g. DEFAULT_BLOCK_MAX_SIZE for the next block, pay a higher or equal fee rate. If the highest bucket alone
+// exceeds the budget, its lower bound is returned.
+func FeeRateForBlockBudget(histogram []*FeeBucket, sizeBudget int64) int64 {
+	feeRate := int64(0)
+	for _, bucket := range histogram {
+		if bucket.CumulativeSize > sizeBudget {
+			if bucket.MaxFeeRatePerKB == math.MaxInt64 {
+				return bucket.MinFeeRatePerKB
+			}
+			return bucket.MaxFeeRatePerKB
+		}
+		feeRate = bucket.MinFeeRatePerKB
+	}
+
+	return feeRate
+}
+
+// EstimateTxSize estimates the serialized size, with witness, of the tx that txDesc describes. The inputs are
+// sized by their actual rings when the tx desc holds its ring blocks, and as full rings otherwise.
+func EstimateTxSize(txDesc *TxDesc) (int64, error) {
+	if len(txDesc.TxRingBlockDescs) == 0 {
+		return estimateTxSize(len(txDesc.TxInDescs), len(txDesc.TxOutDescs), txDesc.TxMemo.Len())
+	}
+
+	rings, err := FindInputRings(txDesc.TxRingBlockDescs, txDesc.TxInDescs)
+	if err != nil {
+		return 0, err
+	}
+
+	ringVersions := make([]uint32, len(rings))
+	ringSizes := make([]int, len(rings))
+	for i, ring := range rings {
+		ringVersions[i] = uint32(ring.Version)
+		ringSizes[i] = len(ring.OutPoints)
+	}
+
+	return estimateTxSizeForRings(ringVersions, ringSizes, len(txDesc.TxOutDescs), txDesc.TxMemo.Len())
+}
+
+// EstimateTxFee returns the fee for the tx that txDesc describes at feeRatePerKB, so that TxFee can be set
+// from a rate instead of a fixed amount.
+func EstimateTxFee(txDesc *TxDesc, feeRatePerKB int64) (int64, error) {
+	txSize, err := EstimateTxSize(txDesc)
+	if err != nil {
+		return 0, err
+	}
+
+	return ComputeFee(txSize, feeRatePerKB), nil
+}
+
+func ComputeFee(txSize int64, feeRatePerKB int64, minFeeRatePerKB ...int64) int64 {
+	if len(minFeeRatePerKB) > 0 && feeRatePerKB < minFeeRatePerKB[0] {
+		feeRatePerKB = minFeeRatePerKB[0]
+	}
+
+	// Round up so that a partial kilobyte is never charged below the rate.
+	return (txSize*feeRatePerKB + 999) / 1000
+}
+
+func feeHistogramBoundaries(min int64, max int64) []int64 {
+	boundaries := make([]int64, 0)
+	for magnitude := min; magnitude <= max; magnitude *= 10 {
+		for _, step := range []int64{1, 2, 5} {
+			if magnitude*step <= max {
+				boundaries = append(boundaries, magnitude*step)
+			}
+		}
+	}
+
+	return boundaries
+}
+
+func mempoolFeeRate(fee int64, txSize int64) int64 {
+	// This is how the node computes the fee rate of mempool entries, in neutrino per kB.
+	return fee * 1000 / txSize
+}
+
+func estimateTxSize(numInputs int, numOutputs int, memoLen int) (int64, error) {
+	// All inputs are assumed to spend from full rings of the current ring version.
+	ringVersions := make([]uint32, numInputs)
+	ringSizes := make([]int, numInputs)
+	for i := 0; i < numInputs; i++ {
+		ringVersions[i] = wire.TxVersion
+		ringSizes[i] = wire.TxoRingSize
+	}
+
+	return estimateTxSizeForRings(ringVersions, ringSizes, numOutputs, memoLen)
+}
+
+func estimateTxSizeForRings(ringVersions []uint32, ringSizes []int, numOutputs int, memoLen int) (int64, error) {
+	if numOutputs > math.MaxUint8 {
+		return 0, fmt.Errorf("%d outputs are more than a tx can have", numOutputs)
+	}
+
+	contentSize, err := wire.PrecomputeTrTxConSize(wire.TxVersion, ringVersions, ringSizes, uint8(numOutputs), uint32(memoLen))
+	if err != nil {
+		return 0, err
+	}
+
+	// The witness size is precomputed for a single ring version, which is taken from the first input.
+	witnessRingVersion := uint32(wire.TxVersion)
+	if len(ringVersions) > 0 {
+		witnessRingVersion = ringVersions[0]
+	}
+	witnessSize, err := wire.PrecomputeTrTxWitnessSize(wire.TxVersion, witnessRingVersion, ringSizes, numOutputs)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(contentSize) + int64(witnessSize), nil
+}
@@ -0,0 +1,308 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// newFeeNode returns a client of a node that estimates feeRate ABEL per kB, or fails estimatefee with
+// estimateErr, and relays txs paying at least relayFee ABEL per kB.
+func newFeeNode(t *testing.T, feeRate float64, estimateErr *AbecJSONRPCError, relayFee float64) *AbecRPCClient {
+	t.Helper()
+
+	return newMockNode(t, map[string]mockMethod{
+		"estimatefee": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			if estimateErr != nil {
+				return nil, estimateErr
+			}
+			return feeRate, nil
+		},
+		"getinfo": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return &AbecChainInfo{RelayFee: relayFee}, nil
+		},
+	})
+}
+
+func TestComputeFeeForTarget(t *testing.T) {
+	txDesc := NewTxDesc([]*TxInDesc{NewTxInDesc(nil, 5000000)}, []*TxOutDesc{NewTxOutDesc(nil, 1000000), NewTxOutDesc(nil, 3000000)}, 0, nil)
+	txSize, err := EstimateTxSize(txDesc)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		feeRate     float64
+		estimateErr *AbecJSONRPCError
+		relayFee    float64
+		want        int64
+	}{
+		// The node reports rates in ABEL per kB, which are 1e7 neutrino per kB.
+		{"estimate in abel per kb", 0.001, nil, 0.0001, (txSize*10000 + 999) / 1000},
+		{"floored at the relay fee", 0.00001, nil, 0.0001, (txSize*1000 + 999) / 1000},
+		{"no estimate", -1, nil, 0.0001, DEFAULT_TX_FEE},
+		{"estimation disabled", 0, &AbecJSONRPCError{Code: -32603, Message: "Fee estimation disabled"}, 0.0001, DEFAULT_TX_FEE},
+		{"not enough blocks", 0, &AbecJSONRPCError{Code: -32603, Message: "not enough blocks have been observed"}, 0.0001, DEFAULT_TX_FEE},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newFeeNode(t, test.feeRate, test.estimateErr, test.relayFee)
+
+			fee, err := ComputeFeeForTarget(client, txDesc, 6)
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if fee != test.want {
+				t.Errorf("got fee %d, want %d", fee, test.want)
+			}
+		})
+	}
+
+	for _, confTarget := range []int{0, MAX_FEE_ESTIMATE_CONF_TARGET + 1} {
+		_, err = ComputeFeeForTarget(newFeeNode(t, 0.001, nil, 0), txDesc, confTarget)
+		if err == nil {
+			t.Errorf("got no error for a confirmation target of %d", confTarget)
+		}
+	}
+
+	// Other node errors are returned rather than taken for a missing estimate.
+	_, err = ComputeFeeForTarget(newFeeNode(t, 0, &AbecJSONRPCError{Code: -32603, Message: "can only estimate fees for up to 25 blocks from now"}, 0), txDesc, 6)
+	if err == nil {
+		t.Errorf("got no error for an estimatefee failure")
+	}
+}
+
+func TestGetEstimatedTxFee(t *testing.T) {
+	txSize, err := estimateTxSize(1, 2, 0)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+
+	fee, err := newFeeNode(t, 0.002, nil, 0.0001).GetEstimatedTxFee(6)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if want := (txSize*20000 + 999) / 1000; fee != want {
+		t.Errorf("got fee %d, want %d", fee, want)
+	}
+
+	fee, err = newFeeNode(t, -1, nil, 0.0001).GetEstimatedTxFee(6)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if fee != DEFAULT_TX_FEE {
+		t.Errorf("got fee %d without an estimate, want %d", fee, DEFAULT_TX_FEE)
+	}
+
+	fee, err = newFeeNode(t, 0, &AbecJSONRPCError{Code: -32603, Message: "not enough blocks have been observed"}, 0.0001).GetEstimatedTxFee(MAX_FEE_ESTIMATE_CONF_TARGET)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if fee != DEFAULT_TX_FEE {
+		t.Errorf("got fee %d before enough blocks are observed, want %d", fee, DEFAULT_TX_FEE)
+	}
+
+	for _, confTarget := range []int64{0, 100} {
+		fee, err = newFeeNode(t, 0.002, nil, 0.0001).GetEstimatedTxFee(confTarget)
+		if err == nil {
+			t.Errorf("got fee %d for a confirmation target of %d, want an error", fee, confTarget)
+		}
+	}
+
+	_, err = newFeeNode(t, 0, &AbecJSONRPCError{Code: -32603, Message: "cannot confirm transaction in zero blocks"}, 0.0001).GetEstimatedTxFee(6)
+	if err == nil {
+		t.Errorf("got no error for an estimatefee failure")
+	}
+}
+
+func TestEstimateTxFee(t *testing.T) {
+	oneInput := NewTxDesc([]*TxInDesc{NewTxInDesc(nil)}, []*TxOutDesc{NewTxOutDesc(nil, 1)}, 0, nil)
+	twoInputs := NewTxDesc([]*TxInDesc{NewTxInDesc(nil), NewTxInDesc(nil)}, []*TxOutDesc{NewTxOutDesc(nil, 1)}, 0, nil)
+
+	oneInputSize, err := EstimateTxSize(oneInput)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+	twoInputsSize, err := EstimateTxSize(twoInputs)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+	if twoInputsSize <= oneInputSize {
+		t.Errorf("tx with two inputs is estimated at %d bytes, not more than %d with one", twoInputsSize, oneInputSize)
+	}
+
+	fee, err := EstimateTxFee(oneInput, 1500)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if want := (oneInputSize*1500 + 999) / 1000; fee != want {
+		t.Errorf("got fee %d, want %d", fee, want)
+	}
+}
+
+func TestComputeFee(t *testing.T) {
+	tests := []struct {
+		name         string
+		txSize       int64
+		feeRatePerKB int64
+		minFeeRate   []int64
+		want         int64
+	}{
+		{"whole kb", 2000, 1000, nil, 2000},
+		{"partial kb rounds up", 1001, 1000, nil, 1001},
+		{"fraction of a neutrino rounds up", 1, 1, nil, 1},
+		{"floored at the minimum rate", 1000, 500, []int64{1000}, 1000},
+		{"above the minimum rate", 1000, 2000, []int64{1000}, 2000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if fee := ComputeFee(test.txSize, test.feeRatePerKB, test.minFeeRate...); fee != test.want {
+				t.Errorf("got fee %d, want %d", fee, test.want)
+			}
+		})
+	}
+}
+
+func TestRelayFeeNeutrinoPerKB(t *testing.T) {
+	tests := []struct {
+		relayFee float64
+		want     int64
+	}{
+		{0, 0},
+		{0.00001, 100},
+		{0.0001, 1000},
+		{0.001, 10000},
+		{0.01, 100000},
+	}
+	for _, test := range tests {
+		info := &AbecChainInfo{RelayFee: test.relayFee}
+		if got := info.RelayFeeNeutrinoPerKB(); got != test.want {
+			t.Errorf("relay fee %v ABEL per kB: got %d neutrino per kB, want %d", test.relayFee, got, test.want)
+		}
+	}
+
+	// A fee rate below the relay fee is floored at it, and one above is kept.
+	relayFee := (&AbecChainInfo{RelayFee: 0.0001}).RelayFeeNeutrinoPerKB()
+	if fee := ComputeFee(1500, 500, relayFee); fee != 1500 {
+		t.Errorf("got fee %d below the relay fee, want 1500", fee)
+	}
+	if fee := NewFeePolicy(500, relayFee).ComputeFee(1500); fee != 1500 {
+		t.Errorf("got fee %d from a fee policy below the relay fee, want 1500", fee)
+	}
+	if fee := ComputeFee(1500, 2000, relayFee); fee != 3000 {
+		t.Errorf("got fee %d above the relay fee, want 3000", fee)
+	}
+}
+
+// testMempoolTx is a mempool tx of size bytes without witness paying fee neutrino.
+type testMempoolTx struct {
+	size int64
+	fee  int64
+}
+
+// newMempoolNode returns a client of a node whose mempool holds txs.
+func newMempoolNode(t *testing.T, txs ...testMempoolTx) *AbecRPCClient {
+	t.Helper()
+
+	return newMockNode(t, map[string]mockMethod{
+		"getrawmempool": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			mempool := make(map[string]interface{})
+			for i, tx := range txs {
+				mempool[testBlockHash(fmt.Sprintf("%02x", i))] = map[string]interface{}{
+					"size":     tx.size,
+					"fullsize": 10 * tx.size,
+					"fee":      float64(tx.fee) / 1e7,
+				}
+			}
+			return mempool, nil
+		},
+	})
+}
+
+// testMempool holds txs at fee rates of 20000, 5000, 2000, 1999, 1000 and 998 neutrino per kB, and one with no
+// size, which has no fee rate.
+var testMempool = []testMempoolTx{{100, 2000}, {1000, 5000}, {2000, 4000}, {1000, 1999}, {500, 500}, {1500, 1498}, {0, 1000}}
+
+func TestEstimateConfirmationBlocks(t *testing.T) {
+	client := newMempoolNode(t, testMempool...)
+
+	for _, tc := range []struct {
+		feeRate int64
+		want    int
+	}{
+		// With blocks of 2000 bytes, a tx waits for the txs paying at least its rate to fill blocks ahead of it.
+		{20001, 1},
+		{20000, 1},
+		{5000, 1},
+		{2000, 2},
+		{1000, 3},
+		{1, 4},
+	} {
+		got, err := EstimateConfirmationBlocks(client, tc.feeRate, 2000)
+		if err != nil {
+			t.Fatalf("fee rate %d: got error %s", tc.feeRate, err)
+		}
+		if got != tc.want {
+			t.Errorf("fee rate %d: got %d blocks, want %d", tc.feeRate, got, tc.want)
+		}
+	}
+
+	got, err := EstimateConfirmationBlocks(client, 1)
+	if err != nil || got != 1 {
+		t.Errorf("got %d blocks and error %v with the default block size, want 1", got, err)
+	}
+
+	_, err = EstimateConfirmationBlocks(newMockNode(t, map[string]mockMethod{}), 1000)
+	if err == nil {
+		t.Errorf("got no error without a mempool")
+	}
+}
+
+func TestMempoolFeeHistogram(t *testing.T) {
+	client := newMempoolNode(t, testMempool...)
+
+	// The bucket from 3000 to 5000 is empty and left out, but still counts toward the cumulative sizes.
+	histogram, err := MempoolFeeHistogram(client, 5000, 1000, 10000, 2000, 3000, 0, -1)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	want := []FeeBucket{
+		{MinFeeRatePerKB: 10000, MaxFeeRatePerKB: math.MaxInt64, Count: 1, Size: 100, CumulativeSize: 100},
+		{MinFeeRatePerKB: 5000, MaxFeeRatePerKB: 10000, Count: 1, Size: 1000, CumulativeSize: 1100},
+		{MinFeeRatePerKB: 2000, MaxFeeRatePerKB: 3000, Count: 1, Size: 2000, CumulativeSize: 3100},
+		{MinFeeRatePerKB: 1000, MaxFeeRatePerKB: 2000, Count: 2, Size: 1500, CumulativeSize: 4600},
+		{MinFeeRatePerKB: 0, MaxFeeRatePerKB: 1000, Count: 1, Size: 1500, CumulativeSize: 6100},
+	}
+	if len(histogram) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(histogram), len(want))
+	}
+	for i, bucket := range histogram {
+		if *bucket != want[i] {
+			t.Errorf("got bucket %d %+v, want %+v", i, *bucket, want[i])
+		}
+	}
+
+	for _, tc := range []struct {
+		sizeBudget int64
+		want       int64
+	}{
+		// The highest bucket alone exceeds the budget, so its lower bound is the best to be had.
+		{99, 10000},
+		{100, 10000},
+		{1100, 3000},
+		{3099, 3000},
+		{3100, 2000},
+		{6099, 1000},
+		{6100, 0},
+		{DEFAULT_BLOCK_MAX_SIZE, 0},
+	} {
+		if got := FeeRateForBlockBudget(histogram, tc.sizeBudget); got != tc.want {
+			t.Errorf("size budget %d: got fee rate %d, want %d", tc.sizeBudget, got, tc.want)
+		}
+	}
+	if got := FeeRateForBlockBudget(nil, 1000); got != 0 {
+		t.Errorf("got fee rate %d for an empty mempool, want 0", got)
+	}
+}
@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/abesuite/abec v0.0.0-00010101000000-000000000000
 	github.com/abesuite/abeutil v0.0.0-20231107022913-d6d3bf295938
+	github.com/gorilla/websocket v1.4.2
 )
 
 require golang.org/x/crypto v0.1.0
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abesuite/abec/abecrypto"
+	"github.com/abesuite/abec/chainhash"
+	"github.com/abesuite/abec/wire"
+)
+
+// mockMethod answers a JSON-RPC method of a mock node with a result, or with an error if it returns one.
+type mockMethod func(params []json.RawMessage) (interface{}, *AbecJSONRPCError)
+
+// newMockNode returns a client of a node that answers the given methods, and a method not found error otherwise.
+func newMockNode(t *testing.T, methods map[string]mockMethod, options ...AbecRPCClientOption) *AbecRPCClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+			ID     string            `json:"id"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp := map[string]interface{}{"id": req.ID, "result": nil, "error": nil}
+		method, ok := methods[req.Method]
+		if !ok {
+			resp["error"] = &AbecJSONRPCError{Code: -32601, Message: "Method not found"}
+		} else if result, rpcErr := method(req.Params); rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewAbecRPCClient(server.URL, "", "", options...)
+}
+
+// testPayment is a coinbase output of a test block.
+type testPayment struct {
+	keys  *CryptoKeysAndAddress
+	value int64
+}
+
+func newTestKeys(t testing.TB) *CryptoKeysAndAddress {
+	t.Helper()
+
+	seed, err := GenerateSafeCryptoSeed()
+	if err != nil {
+		t.Fatalf("cannot generate a seed: %s", err)
+	}
+	keys, err := GenerateCryptoKeysAndAddress(seed)
+	if err != nil {
+		t.Fatalf("cannot generate keys: %s", err)
+	}
+
+	return keys
+}
+
+// newTestChain returns a simulation chain of numBlocks blocks from height 0, where the coinbase of the block
+// at each height pays the payments given for it. A block without payments pays a fresh address.
+func newTestChain(t testing.TB, numBlocks int64, payments map[int64][]testPayment) *SimulationChain {
+	t.Helper()
+
+	chain := NewSimulationChain(0)
+	filler := newTestKeys(t)
+	prevHash := chainhash.Hash{}
+	for height := int64(0); height < numBlocks; height++ {
+		blockPayments := payments[height]
+		if len(blockPayments) == 0 {
+			blockPayments = []testPayment{{filler, 1000}}
+		}
+
+		raw := newTestBlock(t, height, prevHash, blockPayments...)
+		err := chain.AddBlock(raw)
+		if err != nil {
+			t.Fatalf("cannot add block %d: %s", height, err)
+		}
+
+		block, err := DecodeAbecBlock(raw)
+		if err != nil {
+			t.Fatalf("cannot decode block %d: %s", height, err)
+		}
+		hash, err := chainhash.NewHashFromStr(block.BlockHash)
+		if err != nil {
+			t.Fatalf("block %d hash is not valid: %s", height, err)
+		}
+		prevHash = *hash
+	}
+
+	return chain
+}
+
+// newTestBlock returns a serialized block at height whose coinbase pays each payment in order.
+func newTestBlock(t testing.TB, height int64, prevHash chainhash.Hash, payments ...testPayment) Bytes {
+	t.Helper()
+
+	msgTx := wire.NewMsgTxAbe(wire.TxVersion)
+	txIn, err := wire.NewStandardCoinbaseTxIn(int32(height), msgTx.Version)
+	if err != nil {
+		t.Fatalf("cannot make the coinbase input: %s", err)
+	}
+	msgTx.AddTxIn(txIn)
+	msgTx.TxMemo = []byte{byte(msgTx.Version >> 24), byte(msgTx.Version >> 16), byte(msgTx.Version >> 8), byte(msgTx.Version)}
+
+	txOutDescs := make([]*abecrypto.AbeTxOutputDesc, 0, len(payments))
+	for _, payment := range payments {
+		msgTx.TxFee += uint64(payment.value)
+		txOutDescs = append(txOutDescs, abecrypto.NewAbeTxOutDesc(payment.keys.CryptoAddress.Data(), uint64(payment.value)))
+	}
+	coinbaseTx, err := abecrypto.CoinbaseTxGen(txOutDescs, msgTx)
+	if err != nil {
+		t.Fatalf("cannot generate the coinbase tx: %s", err)
+	}
+
+	msgBlock := &wire.MsgBlockAbe{
+		Header: wire.BlockHeader{
+			Version:   wire.BlockVersionEthashPow,
+			PrevBlock: prevHash,
+			Timestamp: time.Unix(1700000000+height*256, 0),
+			Bits:      0x1d00ffff,
+			Height:    int32(height),
+		},
+	}
+	msgBlock.AddTransaction(coinbaseTx)
+
+	var buf bytes.Buffer
+	err = msgBlock.SerializeNoWitness(&buf)
+	if err != nil {
+		t.Fatalf("cannot serialize the block: %s", err)
+	}
+
+	return AsBytes(buf.Bytes())
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Define constants.
+const (
+	KEYSTORE_VERSION = 1
+	KEYSTORE_KDF     = "scrypt"
+	KEYSTORE_CIPHER  = "aes-256-gcm"
+
+	KEYSTORE_SCRYPT_N       = 1 << 15
+	KEYSTORE_SCRYPT_R       = 8
+	KEYSTORE_SCRYPT_P       = 1
+	KEYSTORE_SCRYPT_KEY_LEN = 32
+	KEYSTORE_SALT_LEN       = 32
+
+	// KEYSTORE_SCRYPT_MAX_MEMORY bounds the 128*N*R bytes scrypt allocates for the params of a keystore file.
+	KEYSTORE_SCRYPT_MAX_MEMORY = 256 << 20
+)
+
+// ErrWrongPassphrase is returned by ImportEncryptedKeystore when the keystore cannot be decrypted. A wrong
+// passphrase and a tampered keystore both fail the same authentication check and cannot be told apart.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// Define the keystore data types.
+type keystoreEnvelope struct {
+	Version    int            `json:"version"`
+	KDF        string         `json:"kdf"`
+	KDFParams  keystoreScrypt `json:"kdfparams"`
+	Cipher     string         `json:"cipher"`
+	Nonce      Bytes          `json:"nonce"`
+	Ciphertext Bytes          `json:"ciphertext"`
+}
+
+type keystoreScrypt struct {
+	N    int   `json:"n"`
+	R    int   `json:"r"`
+	P    int   `json:"p"`
+	Salt Bytes `json:"salt"`
+}
+
+// Define util functions.
+// ExportEncryptedKeystore encrypts the secret keys and the crypto address with AES-256-GCM under a key derived
+// from passphrase with scrypt, and returns them in a versioned JSON envelope.
+func ExportEncryptedKeystore(keys *CryptoKeysAndAddress, passphrase string) ([]byte, error) {
+	for _, key := range []*CryptoKey{&keys.SpendSecretKey, &keys.SerialNoSecretKey, &keys.ViewSecretKey} {
+		if err := key.checkUsable(); err != nil {
+			return nil, err
+		}
+	}
+
+	envelope := &keystoreEnvelope{
+		Version: KEYSTORE_VERSION,
+		KDF:     KEYSTORE_KDF,
+		KDFParams: keystoreScrypt{
+			N:    KEYSTORE_SCRYPT_N,
+			R:    KEYSTORE_SCRYPT_R,
+			P:    KEYSTORE_SCRYPT_P,
+			Salt: MakeBytes(KEYSTORE_SALT_LEN),
+		},
+		Cipher: KEYSTORE_CIPHER,
+	}
+	_, err := rand.Read(envelope.KDFParams.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newKeystoreAEAD(passphrase, &envelope.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Nonce = MakeBytes(aead.NonceSize())
+	_, err = rand.Read(envelope.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := encodeKeystoreSecrets(keys)
+	defer zeroBytes(plaintext)
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, envelope.additionalData())
+
+	return json.Marshal(envelope)
+}
+
+// ImportEncryptedKeystore decrypts a keystore made by ExportEncryptedKeystore. It returns ErrWrongPassphrase
+// when the ciphertext does not authenticate under passphrase.
+func ImportEncryptedKeystore(data []byte, passphrase string) (*CryptoKeysAndAddress, error) {
+	envelope := &keystoreEnvelope{}
+	err := json.Unmarshal(data, envelope)
+	if err != nil {
+		return nil, fmt.Errorf("keystore is not valid json: %s", err)
+	}
+	if envelope.Version != KEYSTORE_VERSION {
+		return nil, fmt.Errorf("keystore version %d is not supported", envelope.Version)
+	}
+	if envelope.KDF != KEYSTORE_KDF || envelope.Cipher != KEYSTORE_CIPHER {
+		return nil, fmt.Errorf("keystore kdf %q with cipher %q is not supported", envelope.KDF, envelope.Cipher)
+	}
+
+	aead, err := newKeystoreAEAD(passphrase, &envelope.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Nonce.Len() != aead.NonceSize() {
+		return nil, fmt.Errorf("keystore nonce length %d is not %d", envelope.Nonce.Len(), aead.NonceSize())
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, envelope.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	defer zeroBytes(plaintext)
+
+	return decodeKeystoreSecrets(plaintext)
+}
+
+// additionalData binds the header to the ciphertext, so that changing the kdf params fails authentication.
+func (envelope *keystoreEnvelope) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d|%s|%d|%d|%d|%s|%s", envelope.Version, envelope.KDF,
+		envelope.KDFParams.N, envelope.KDFParams.R, envelope.KDFParams.P, envelope.KDFParams.Salt.HexString(), envelope.Cipher))
+}
+
+func newKeystoreAEAD(passphrase string, params *keystoreScrypt) (cipher.AEAD, error) {
+	// The params come from the keystore file, so they are bounded to keep a crafted file from exhausting memory
+	// with N*R or the CPU with R*P.
+	if params.N <= 1 || params.N&(params.N-1) != 0 || params.R <= 0 || params.P <= 0 {
+		return nil, fmt.Errorf("keystore kdf params n=%d r=%d p=%d are invalid", params.N, params.R, params.P)
+	}
+	if params.N > KEYSTORE_SCRYPT_MAX_MEMORY/128/params.R || params.P > KEYSTORE_SCRYPT_R*KEYSTORE_SCRYPT_P*4/params.R {
+		return nil, fmt.Errorf("keystore kdf params n=%d r=%d p=%d exceed the supported limits", params.N, params.R, params.P)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), params.Salt, params.N, params.R, params.P, KEYSTORE_SCRYPT_KEY_LEN)
+	if err != nil {
+		return nil, fmt.Errorf("keystore kdf params are invalid: %s", err)
+	}
+	defer zeroBytes(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encodeKeystoreSecrets writes each key and the address with a 4-byte length prefix.
+func encodeKeystoreSecrets(keys *CryptoKeysAndAddress) []byte {
+	fields := [][]byte{keys.SpendSecretKey.Bytes, keys.SerialNoSecretKey.Bytes, keys.ViewSecretKey.Bytes, keys.CryptoAddress.Data()}
+
+	size := 0
+	for _, field := range fields {
+		size += 4 + len(field)
+	}
+	plaintext := make([]byte, size)
+	offset := 0
+	for _, field := range fields {
+		binary.BigEndian.PutUint32(plaintext[offset:], uint32(len(field)))
+		offset += 4
+		offset += copy(plaintext[offset:], field)
+	}
+
+	return plaintext
+}
+
+func decodeKeystoreSecrets(plaintext []byte) (*CryptoKeysAndAddress, error) {
+	fields := make([]Bytes, 0, 4)
+	for len(fields) < 4 {
+		if len(plaintext) < 4 {
+			return nil, fmt.Errorf("keystore secrets are truncated")
+		}
+		length := binary.BigEndian.Uint32(plaintext)
+		plaintext = plaintext[4:]
+		if uint64(len(plaintext)) < uint64(length) {
+			return nil, fmt.Errorf("keystore secrets are truncated")
+		}
+		field := MakeBytes(int(length))
+		copy(field, plaintext[:length])
+		fields = append(fields, field)
+		plaintext = plaintext[length:]
+	}
+	if len(plaintext) != 0 {
+		return nil, fmt.Errorf("keystore secrets have %d trailing bytes", len(plaintext))
+	}
+
+	return &CryptoKeysAndAddress{
+		SpendSecretKey:    *NewCryptoKey(fields[0]),
+		SerialNoSecretKey: *NewCryptoKey(fields[1]),
+		ViewSecretKey:     *NewCryptoKey(fields[2]),
+		CryptoAddress:     *NewCryptoAddress(fields[3]),
+	}, nil
+}
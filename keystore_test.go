@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// tamperKeystore returns data with its envelope changed by tamper.
+func tamperKeystore(t *testing.T, data []byte, tamper func(envelope *keystoreEnvelope)) []byte {
+	t.Helper()
+
+	envelope := &keystoreEnvelope{}
+	err := json.Unmarshal(data, envelope)
+	if err != nil {
+		t.Fatalf("cannot parse the keystore: %s", err)
+	}
+	tamper(envelope)
+	tampered, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("cannot encode the keystore: %s", err)
+	}
+
+	return tampered
+}
+
+func TestEncryptedKeystore(t *testing.T) {
+	keys := newTestKeys(t)
+	data, err := ExportEncryptedKeystore(keys, "correct horse")
+	if err != nil {
+		t.Fatalf("cannot export: %s", err)
+	}
+	if bytes.Contains(data, []byte(keys.SpendSecretKey.HexString())) {
+		t.Fatalf("keystore contains the spend secret key in the clear")
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		imported, err := ImportEncryptedKeystore(data, "correct horse")
+		if err != nil {
+			t.Fatalf("cannot import: %s", err)
+		}
+		if !bytes.Equal(imported.SpendSecretKey.Bytes, keys.SpendSecretKey.Bytes) ||
+			!bytes.Equal(imported.SerialNoSecretKey.Bytes, keys.SerialNoSecretKey.Bytes) ||
+			!bytes.Equal(imported.ViewSecretKey.Bytes, keys.ViewSecretKey.Bytes) ||
+			!imported.CryptoAddress.Equal(&keys.CryptoAddress) {
+			t.Errorf("imported keys differ from the exported ones")
+		}
+	})
+
+	t.Run("fresh salt and nonce", func(t *testing.T) {
+		other, err := ExportEncryptedKeystore(keys, "correct horse")
+		if err != nil {
+			t.Fatalf("cannot export: %s", err)
+		}
+		if bytes.Equal(other, data) {
+			t.Errorf("two exports of the same keys are identical")
+		}
+	})
+
+	for _, tc := range []struct {
+		name       string
+		data       []byte
+		passphrase string
+	}{
+		{"wrong passphrase", data, "wrong horse"},
+		{"tampered ciphertext", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.Ciphertext[0] ^= 0xff
+		}), "correct horse"},
+		{"tampered salt", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.Salt[0] ^= 0xff
+		}), "correct horse"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ImportEncryptedKeystore(tc.data, tc.passphrase)
+			if !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("got error %v, want ErrWrongPassphrase", err)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"not json", []byte("keystore")},
+		{"unsupported version", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.Version = KEYSTORE_VERSION + 1
+		})},
+		{"unsupported cipher", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.Cipher = "aes-128-cbc"
+		})},
+		{"excessive kdf params", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.N = 1 << 30
+		})},
+		// Each of these params is within the bounds alone, but together they need 4 GiB.
+		{"excessive kdf memory", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.N, envelope.KDFParams.R, envelope.KDFParams.P = 1<<20, 32, 1
+		})},
+		{"kdf n not a power of two", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.N = KEYSTORE_SCRYPT_N + 1
+		})},
+		{"kdf n of 1", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.N = 1
+		})},
+		{"kdf r of 0", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.KDFParams.R = 0
+		})},
+		{"short nonce", tamperKeystore(t, data, func(envelope *keystoreEnvelope) {
+			envelope.Nonce = envelope.Nonce[1:]
+		})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ImportEncryptedKeystore(tc.data, "correct horse")
+			if err == nil || errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("got error %v, want a format error", err)
+			}
+		})
+	}
+}
+
+func TestDecodeKeystoreSecrets(t *testing.T) {
+	keys := newTestKeys(t)
+	plaintext := encodeKeystoreSecrets(keys)
+
+	decoded, err := decodeKeystoreSecrets(plaintext)
+	if err != nil {
+		t.Fatalf("cannot decode: %s", err)
+	}
+	if !bytes.Equal(decoded.ViewSecretKey.Bytes, keys.ViewSecretKey.Bytes) || !decoded.CryptoAddress.Equal(&keys.CryptoAddress) {
+		t.Errorf("decoded keys differ from the encoded ones")
+	}
+
+	for _, tc := range []struct {
+		name      string
+		plaintext []byte
+	}{
+		{"empty", nil},
+		{"truncated", plaintext[:len(plaintext)-1]},
+		{"trailing bytes", append(append([]byte(nil), plaintext...), 0)},
+		{"oversized length", []byte{0xff, 0xff, 0xff, 0xff, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeKeystoreSecrets(tc.plaintext)
+			if err == nil {
+				t.Errorf("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/abesuite/abec/abecrypto/abecryptoparam"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define the ProtocolLimits data type.
+type ProtocolLimits struct {
+	MaxTxInputs   int
+	MaxTxOutputs  int
+	MaxTxMemoSize int
+	RelayFeePerKB int64
+	FromNode      bool
+}
+
+// Define methods for ProtocolLimits.
+func (limits *ProtocolLimits) Validate(txDesc *TxDesc) error {
+	if len(txDesc.TxInDescs) > limits.MaxTxInputs {
+		return fmt.Errorf("tx desc has %d inputs, more than the limit of %d", len(txDesc.TxInDescs), limits.MaxTxInputs)
+	}
+	if len(txDesc.TxOutDescs) > limits.MaxTxOutputs {
+		return fmt.Errorf("tx desc has %d outputs, more than the limit of %d", len(txDesc.TxOutDescs), limits.MaxTxOutputs)
+	}
+	if txDesc.TxMemo.Len() > limits.MaxTxMemoSize {
+		return fmt.Errorf("tx desc memo length %d exceeds %d bytes", txDesc.TxMemo.Len(), limits.MaxTxMemoSize)
+	}
+
+	return nil
+}
+
+// Define util functions.
+// DefaultProtocolLimits returns the limits compiled into the abec version the SDK is built against, for the
+// current tx version. The relay fee is left at 0, since it is a node setting.
+func DefaultProtocolLimits() (*ProtocolLimits, error) {
+	maxTxInputs, err := abecryptoparam.GetTxInputMaxNum(wire.TxVersion)
+	if err != nil {
+		return nil, err
+	}
+	maxTxOutputs, err := abecryptoparam.GetTxOutputMaxNum(wire.TxVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProtocolLimits{
+		MaxTxInputs:   maxTxInputs,
+		MaxTxOutputs:  maxTxOutputs,
+		MaxTxMemoSize: MAX_TX_MEMO_SIZE,
+	}, nil
+}
+
+// GetProtocolLimits returns the limits the node enforces, as far as it reports them. Abec has no RPC for the
+// tx size and count limits, which are consensus rules fixed by the tx version, so those are always the
+// compiled-in ones. The relay fee is read from getinfo, and left at 0 if the node cannot be reached.
+func GetProtocolLimits(client *AbecRPCClient) (*ProtocolLimits, error) {
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		LOG.debug("Cannot read limits from the node, using the compiled-in limits: %s\n", err)
+		return limits, nil
+	}
+
+	limits.RelayFeePerKB = chainInfo.RelayFeeNeutrinoPerKB()
+	limits.FromNode = true
+	return limits, nil
+}
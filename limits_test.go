@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetProtocolLimits(t *testing.T) {
+	defaults, err := DefaultProtocolLimits()
+	if err != nil {
+		t.Fatalf("cannot get the default limits: %s", err)
+	}
+
+	t.Run("node", func(t *testing.T) {
+		client := newMockNode(t, map[string]mockMethod{
+			"getinfo": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+				return &AbecChainInfo{RelayFee: 0.0002}, nil
+			},
+		})
+
+		limits, err := GetProtocolLimits(client)
+		if err != nil {
+			t.Fatalf("got error %s", err)
+		}
+		want := *defaults
+		want.RelayFeePerKB = 2000
+		want.FromNode = true
+		if *limits != want {
+			t.Errorf("got limits %+v, want %+v", *limits, want)
+		}
+	})
+
+	t.Run("unreachable node", func(t *testing.T) {
+		client := NewAbecRPCClient("http://127.0.0.1:1", "", "", WithTimeout(time.Second), WithDialTimeout(time.Second))
+
+		limits, err := GetProtocolLimits(client)
+		if err != nil {
+			t.Fatalf("got error %s, want the compiled-in limits", err)
+		}
+		if *limits != *defaults {
+			t.Errorf("got limits %+v, want the compiled-in limits %+v", *limits, *defaults)
+		}
+	})
+}
+
+func TestValidateWithLimits(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+	defaults, err := DefaultProtocolLimits()
+	if err != nil {
+		t.Fatalf("cannot get the default limits: %s", err)
+	}
+
+	err = txDesc.ValidateWithLimits(defaults)
+	if err != nil {
+		t.Fatalf("got error %s within the default limits", err)
+	}
+
+	// The tx desc has one input, a recipient and a change output, and the memo set below.
+	txDesc.TxMemo = Bytes("memo")
+	tests := []struct {
+		name    string
+		limits  ProtocolLimits
+		wantErr string
+	}{
+		{"within", ProtocolLimits{MaxTxInputs: 1, MaxTxOutputs: 2, MaxTxMemoSize: 4}, ""},
+		{"inputs", ProtocolLimits{MaxTxInputs: 0, MaxTxOutputs: 2, MaxTxMemoSize: 4}, "1 inputs"},
+		{"outputs", ProtocolLimits{MaxTxInputs: 1, MaxTxOutputs: 1, MaxTxMemoSize: 4}, "2 outputs"},
+		{"memo", ProtocolLimits{MaxTxInputs: 1, MaxTxOutputs: 2, MaxTxMemoSize: 3}, "memo length 4"},
+	}
+	for _, test := range tests {
+		err := txDesc.ValidateWithLimits(&test.limits)
+		if test.wantErr == "" && err != nil {
+			t.Errorf("%s: got error %s", test.name, err)
+		}
+		if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("%s: got error %v, want one about %q", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -8,16 +8,28 @@ import (
 	"strings"
 )
 
+const (
+	DEFAULT_LOG_VALUE_MAX_LENGTH = 128
+)
+
 type Logger struct {
 	*log.Logger
+	valueMaxLength int
 }
 
 func NewLogger(name string) *Logger {
 	return &Logger{
-		Logger: log.New(os.Stderr, fmt.Sprintf("[%s] ", name), log.LstdFlags),
+		Logger:         log.New(os.Stderr, fmt.Sprintf("[%s] ", name), log.LstdFlags),
+		valueMaxLength: DEFAULT_LOG_VALUE_MAX_LENGTH,
 	}
 }
 
+// SetValueMaxLength sets how many characters of a long request param or response body are kept in debug logs.
+// A non-positive length disables truncation.
+func (logger *Logger) SetValueMaxLength(length int) {
+	logger.valueMaxLength = length
+}
+
 func loggerEnabled() bool {
 	enabled := strings.ToLower(os.Getenv("ABELSDK_DEBUG"))
 	return enabled == "true" || enabled == "1" || enabled == "on" || enabled == "yes"
@@ -42,4 +54,41 @@ func (logger *Logger) debug(format string, v ...interface{}) {
 	logger.Printf(callerInfo+format, v...)
 }
 
+// loggedValue defers formatting a value for the debug log until the log is actually written.
+type loggedValue struct {
+	value     interface{}
+	maxLength int
+}
+
+func (logger *Logger) value(v interface{}) loggedValue {
+	return loggedValue{value: v, maxLength: logger.valueMaxLength}
+}
+
+func (v loggedValue) String() string {
+	switch value := v.value.(type) {
+	case []interface{}:
+		formatted := make([]string, 0, len(value))
+		for _, item := range value {
+			formatted = append(formatted, loggedValue{value: item, maxLength: v.maxLength}.String())
+		}
+		return "[" + strings.Join(formatted, " ") + "]"
+	case Bytes:
+		return value.Summary(1)
+	case []byte:
+		return AsBytes(value).Summary(1)
+	case string:
+		return truncateLoggedString(value, v.maxLength)
+	default:
+		return truncateLoggedString(fmt.Sprintf("%+v", value), v.maxLength)
+	}
+}
+
+func truncateLoggedString(s string, maxLength int) string {
+	if maxLength <= 0 || len(s) <= maxLength {
+		return s
+	}
+
+	return fmt.Sprintf("%s...(%d bytes)", s[:maxLength], len(s))
+}
+
 var LOG = NewLogger("abelsdk")
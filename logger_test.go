@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoggedValueTruncation(t *testing.T) {
+	long := strings.Repeat("ab", 100)
+	tests := []struct {
+		name      string
+		value     interface{}
+		maxLength int
+		want      string
+	}{
+		{"short string", "abcd", 8, "abcd"},
+		{"long string", long, 8, "abababab...(200 bytes)"},
+		{"truncation disabled", long, 0, long},
+		{"params", []interface{}{long, 1}, 8, "[abababab...(200 bytes) 1]"},
+		{"struct", struct{ Data string }{long}, 8, "{Data:ab...(207 bytes)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := loggedValue{value: test.value, maxLength: test.maxLength}.String()
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDebugLogTruncatesLongParam(t *testing.T) {
+	t.Setenv("ABELSDK_DEBUG", "1")
+	var buf bytes.Buffer
+	LOG.SetOutput(&buf)
+	t.Cleanup(func() {
+		LOG.SetOutput(os.Stderr)
+	})
+
+	client := newMockNode(t, map[string]mockMethod{
+		"sendrawtransactionabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return testBlockHash("cd"), nil
+		},
+	})
+	txStr := strings.Repeat("0a", 2000)
+	_, _, err := client.SendRawTx(txStr)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	logged := buf.String()
+	if strings.Contains(logged, txStr) {
+		t.Errorf("the debug log has the whole %d byte param", len(txStr))
+	}
+	if want := txStr[:DEFAULT_LOG_VALUE_MAX_LENGTH] + "...(4000 bytes)"; !strings.Contains(logged, want) {
+		t.Errorf("got debug log %q, want the param truncated to %d characters", logged, DEFAULT_LOG_VALUE_MAX_LENGTH)
+	}
+}
@@ -41,6 +41,79 @@ func GenerateCryptoSeedFromMnemonic(mnemonic []string, sequenceNumber uint64) ([
 	return generateCryptoSeed(usedSeed, 2*len(seed), sequenceNumber)
 }
 
+// SeedToMnemonic encodes a 32-byte mnemonic seed as a 24-word phrase from the BIP39 english wordlist, with
+// the checksum byte of the Abelian wallets (the first byte of the double SHA-256 of the seed). A crypto seed
+// from GenerateSafeCryptoSeed is not a mnemonic seed and cannot be written as a phrase.
+func SeedToMnemonic(seed Bytes) (string, error) {
+	if seed.Len() != seedLength {
+		return "", fmt.Errorf("mnemonic seed length %d is not %d", seed.Len(), seedLength)
+	}
+
+	return strings.Join(seedToWords(seed, english), " "), nil
+}
+
+// MnemonicToSeed checks the words and checksum of a 24-word phrase and returns its 32-byte mnemonic seed, the
+// inverse of SeedToMnemonic. The key sets of the seed are derived with DeriveCryptoKeysAndAddress.
+func MnemonicToSeed(mnemonic string) (Bytes, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	err := validateMnemonicWords(words)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := wordsToSeed(words, englishMap)
+	seedHash := chainhash.DoubleHashH(seed[:seedLength])
+	if seedHash[0] != seed[seedLength] {
+		return nil, fmt.Errorf("mnemonic checksum does not match")
+	}
+
+	return AsBytes(seed[:seedLength]), nil
+}
+
+// MnemonicToCryptoSeed checks a 24-word phrase like MnemonicToSeed and returns the crypto seed of the phrase's
+// first key set, which GenerateCryptoKeysAndAddress takes directly. It is the same key set as
+// DeriveCryptoKeysAndAddress with index 0.
+func MnemonicToCryptoSeed(mnemonic string) (Bytes, error) {
+	seed, err := MnemonicToSeed(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateCryptoSeed(generateUsedSeed(seed), 2*seedLength, 0)
+}
+
+// DeriveCryptoKeysAndAddress derives the index-th key set of a 32-byte mnemonic seed, the same way the
+// Abelian wallets derive the key sets of a mnemonic by sequence number, so a seed and index always give the
+// same address. Index 0 is the key set of MnemonicToCryptoSeed for the seed's phrase. A crypto seed passed to
+// GenerateCryptoKeysAndAddress directly has only that one key set and cannot be derived from.
+func DeriveCryptoKeysAndAddress(seed Bytes, index uint32) (*CryptoKeysAndAddress, error) {
+	if seed.Len() != seedLength {
+		return nil, fmt.Errorf("mnemonic seed length %d is not %d", seed.Len(), seedLength)
+	}
+
+	cryptoSeed, err := generateCryptoSeed(generateUsedSeed(seed), 2*seedLength, uint64(index))
+	if err != nil {
+		return nil, err
+	}
+
+	return GenerateCryptoKeysAndAddress(cryptoSeed)
+}
+
+func validateMnemonicWords(words []string) error {
+	if len(words) != 24 {
+		return fmt.Errorf("mnemonic has %d words, not 24", len(words))
+	}
+
+	// Unknown words would otherwise be decoded as the first word of the list.
+	for i, word := range words {
+		if _, ok := englishMap[word]; !ok {
+			return fmt.Errorf("mnemonic word %d %q is not in the wordlist", i+1, word)
+		}
+	}
+
+	return nil
+}
+
 func seedToWords(seed []byte, wordlist []string) []string {
 	res := make([]string, 0, 24)
 	hash := chainhash.DoubleHashH(seed)
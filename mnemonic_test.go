@@ -0,0 +1,52 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestMnemonicToSeedRoundTrip(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		seed := make([]byte, 32)
+		rand.Read(seed)
+
+		mnemonic, err := SeedToMnemonic(seed)
+		if err != nil {
+			t.Fatalf("cannot encode seed %x: %s", seed, err)
+		}
+		decoded, err := MnemonicToSeed(strings.ToUpper(mnemonic))
+		if err != nil {
+			t.Fatalf("cannot decode the mnemonic of seed %x: %s", seed, err)
+		}
+		if !bytes.Equal(decoded, seed) {
+			t.Errorf("got seed %x from the mnemonic of seed %x", []byte(decoded), seed)
+		}
+	}
+}
+
+func TestMnemonicToSeedErrors(t *testing.T) {
+	mnemonic, err := SeedToMnemonic(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("cannot encode the seed: %s", err)
+	}
+	words := strings.Fields(mnemonic)
+
+	// Changing the first word keeps every word valid but breaks the checksum.
+	changed := append([]string{}, words...)
+	changed[0] = "zoo"
+	for name, phrase := range map[string]string{
+		"checksum":     strings.Join(changed, " "),
+		"unknown word": strings.Join(append(append([]string{}, words[:23]...), "abelian"), " "),
+		"23 words":     strings.Join(words[:23], " "),
+		"empty":        "",
+	} {
+		if _, err := MnemonicToSeed(phrase); err == nil {
+			t.Errorf("%s: decoded an invalid mnemonic", name)
+		}
+		if _, err := MnemonicToCryptoSeed(phrase); err == nil {
+			t.Errorf("%s: derived a crypto seed from an invalid mnemonic", name)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Define constants.
+const (
+	// Version 2 encodes the bytes fields as hex, and version 1 as base64.
+	COIN_NOTE_VERSION = 2
+)
+
+// Define the coinNote data type.
+// A coin note carries everything an offline signer needs to spend a coin: the txout data, value and owner
+// to build the TxInDesc, and the ring block heights the online machine has to fetch for the ring group.
+type coinNote struct {
+	Version           int     `json:"version"`
+	TxHash            Txid    `json:"txHash"`
+	Index             uint8   `json:"index"`
+	TxVoutData        Bytes   `json:"txVoutData"`
+	Value             int64   `json:"value"`
+	SerialNumber      Bytes   `json:"serialNumber,omitempty"`
+	OwnerShortAddress Bytes   `json:"ownerShortAddress,omitempty"`
+	BlockHash         Bytes   `json:"blockHash,omitempty"`
+	BlockHeight       int64   `json:"blockHeight"`
+	IsCoinbase        bool    `json:"isCoinbase"`
+	RingBlockHeights  []int64 `json:"ringBlockHeights"`
+	Label             string  `json:"label,omitempty"`
+}
+
+type coinNoteV1Bytes struct {
+	TxVoutData        []byte `json:"txVoutData"`
+	SerialNumber      []byte `json:"serialNumber,omitempty"`
+	OwnerShortAddress []byte `json:"ownerShortAddress,omitempty"`
+	BlockHash         []byte `json:"blockHash,omitempty"`
+}
+
+// Define methods for Coin.
+func (coin *Coin) ExportNote() (Bytes, error) {
+	if coin.ID.TxHash.IsZero() || coin.TxVoutData.Len() == 0 {
+		return nil, fmt.Errorf("coin %s has no txout data to export", coin.ID)
+	}
+
+	note := &coinNote{
+		Version:          COIN_NOTE_VERSION,
+		TxHash:           coin.ID.TxHash,
+		Index:            coin.ID.Index,
+		TxVoutData:       coin.TxVoutData,
+		Value:            coin.Value,
+		SerialNumber:     coin.SerialNumber,
+		BlockHash:        coin.BlockHash,
+		BlockHeight:      coin.BlockHeight,
+		IsCoinbase:       coin.IsCoinbase,
+		RingBlockHeights: GetRingBlockHeights(coin.BlockHeight),
+		Label:            coin.Label,
+	}
+	if coin.OwnerShortAddress != nil {
+		note.OwnerShortAddress = coin.OwnerShortAddress.Data()
+	}
+
+	data, err := json.Marshal(note)
+	if err != nil {
+		return nil, err
+	}
+
+	return AsBytes(data), nil
+}
+
+// Define util functions.
+func ImportCoinNote(data Bytes) (*Coin, error) {
+	note, err := parseCoinNote(data)
+	if err != nil {
+		return nil, err
+	}
+	if note.TxHash.IsZero() || note.TxVoutData.Len() == 0 {
+		return nil, fmt.Errorf("coin note has no txout data")
+	}
+	if note.Value <= 0 {
+		return nil, fmt.Errorf("coin note value %d is not positive", note.Value)
+	}
+
+	coin := &Coin{
+		ID:           *NewCoinID(note.TxHash, note.Index),
+		Value:        note.Value,
+		SerialNumber: note.SerialNumber,
+		TxVoutData:   note.TxVoutData,
+		BlockHash:    note.BlockHash,
+		BlockHeight:  note.BlockHeight,
+		IsCoinbase:   note.IsCoinbase,
+		Label:        note.Label,
+	}
+	if note.OwnerShortAddress.Len() > 0 {
+		coin.OwnerShortAddress = NewShortAbelAddress(note.OwnerShortAddress)
+		err = coin.OwnerShortAddress.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("coin note owner is not valid: %s", err)
+		}
+	}
+
+	return coin, nil
+}
+
+func parseCoinNote(data Bytes) (*coinNote, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	err := data.JSONUnmarshal(&header)
+	if err != nil {
+		return nil, fmt.Errorf("coin note cannot be parsed: %s", err)
+	}
+
+	switch header.Version {
+	case COIN_NOTE_VERSION:
+		note := &coinNote{}
+		err = data.JSONUnmarshal(note)
+		if err != nil {
+			return nil, fmt.Errorf("coin note cannot be parsed: %s", err)
+		}
+		return note, nil
+	case 1:
+		// Decode the base64 bytes fields separately, and the others as in the current version.
+		var fields map[string]json.RawMessage
+		err = data.JSONUnmarshal(&fields)
+		if err != nil {
+			return nil, fmt.Errorf("coin note cannot be parsed: %s", err)
+		}
+		v1Bytes := &coinNoteV1Bytes{}
+		err = data.JSONUnmarshal(v1Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("coin note cannot be parsed: %s", err)
+		}
+		for _, key := range []string{"txVoutData", "serialNumber", "ownerShortAddress", "blockHash"} {
+			delete(fields, key)
+		}
+		otherFields, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		note := &coinNote{}
+		err = json.Unmarshal(otherFields, note)
+		if err != nil {
+			return nil, fmt.Errorf("coin note cannot be parsed: %s", err)
+		}
+		note.TxVoutData = AsBytes(v1Bytes.TxVoutData)
+		note.SerialNumber = AsBytes(v1Bytes.SerialNumber)
+		note.OwnerShortAddress = AsBytes(v1Bytes.OwnerShortAddress)
+		note.BlockHash = AsBytes(v1Bytes.BlockHash)
+		return note, nil
+	default:
+		return nil, fmt.Errorf("coin note version %d is not supported", header.Version)
+	}
+}
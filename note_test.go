@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestCoinNoteRoundTrip(t *testing.T) {
+	keys := newTestKeys(t)
+	_, coin := newTestBuilderCoin(t, keys, 5000000)
+	if coin.SerialNumber.Len() == 0 || coin.OwnerShortAddress == nil || coin.BlockHash.Len() == 0 {
+		t.Fatalf("the scanned coin is missing its serial number, owner or block hash")
+	}
+	coin.Label = "cold storage"
+
+	note, err := coin.ExportNote()
+	if err != nil {
+		t.Fatalf("cannot export the note: %s", err)
+	}
+	imported, err := ImportCoinNote(note)
+	if err != nil {
+		t.Fatalf("cannot import the note: %s", err)
+	}
+
+	if imported.ID != coin.ID || imported.Value != coin.Value || imported.BlockHeight != coin.BlockHeight ||
+		imported.IsCoinbase != coin.IsCoinbase || imported.Label != coin.Label {
+		t.Errorf("got coin %s of %d at height %d, want coin %s of %d at height %d", imported.ID, imported.Value, imported.BlockHeight, coin.ID, coin.Value, coin.BlockHeight)
+	}
+	if !bytes.Equal(imported.BlockHash, coin.BlockHash) {
+		t.Errorf("got block hash %x, want %x", []byte(imported.BlockHash), []byte(coin.BlockHash))
+	}
+
+	// The imported coin yields the same input as the original.
+	got, want := imported.ToTxInDesc(), coin.ToTxInDesc()
+	if !bytes.Equal(got.TxOutData, want.TxOutData) || !bytes.Equal(got.TxHash, want.TxHash) || got.TxOutIndex != want.TxOutIndex ||
+		got.CoinValue != want.CoinValue || got.Height != want.Height || !bytes.Equal(got.CoinSerialNumber, want.CoinSerialNumber) {
+		t.Errorf("the input of the imported coin differs from the original")
+	}
+	if got.Owner == nil || !bytes.Equal(got.Owner.Data(), want.Owner.Data()) {
+		t.Errorf("the owner of the imported coin differs from the original")
+	}
+
+	// The note tells the online machine which ring blocks to fetch.
+	var fields struct {
+		RingBlockHeights []int64 `json:"ringBlockHeights"`
+	}
+	err = note.JSONUnmarshal(&fields)
+	if err != nil {
+		t.Fatalf("cannot parse the note: %s", err)
+	}
+	wantHeights := GetRingBlockHeights(coin.BlockHeight)
+	if len(fields.RingBlockHeights) != len(wantHeights) {
+		t.Fatalf("got ring block heights %v, want %v", fields.RingBlockHeights, wantHeights)
+	}
+	for i, height := range wantHeights {
+		if fields.RingBlockHeights[i] != height {
+			t.Errorf("got ring block heights %v, want %v", fields.RingBlockHeights, wantHeights)
+		}
+	}
+}
+
+func TestImportCoinNoteErrors(t *testing.T) {
+	valid := map[string]interface{}{
+		"version":    COIN_NOTE_VERSION,
+		"txHash":     Txid{0x01}.HexString(),
+		"index":      0,
+		"txVoutData": "0a0b",
+		"value":      1000,
+	}
+	marshal := func(changes map[string]interface{}) Bytes {
+		fields := make(map[string]interface{})
+		for key, value := range valid {
+			fields[key] = value
+		}
+		for key, value := range changes {
+			fields[key] = value
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatalf("cannot marshal the note: %s", err)
+		}
+		return AsBytes(data)
+	}
+
+	if _, err := ImportCoinNote(marshal(nil)); err != nil {
+		t.Fatalf("got error %s for a valid note", err)
+	}
+	for name, changes := range map[string]map[string]interface{}{
+		"unsupported version": {"version": COIN_NOTE_VERSION + 1},
+		"no txout data":       {"txVoutData": ""},
+		"zero value":          {"value": 0},
+		"invalid owner":       {"ownerShortAddress": "0102"},
+	} {
+		if _, err := ImportCoinNote(marshal(changes)); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+	if _, err := ImportCoinNote(Bytes("not json")); err == nil {
+		t.Errorf("got no error for a note that is not json")
+	}
+	if _, err := (&Coin{ID: *NewCoinID(Txid{0x01}, 0), Value: 1000}).ExportNote(); err == nil {
+		t.Errorf("exported a coin without txout data")
+	}
+}
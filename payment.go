@@ -0,0 +1,97 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Define constants.
+const (
+	PAYMENT_URI_SCHEME = "abelian"
+)
+
+// Define the PaymentRequest data type and methods.
+// A payment request is encoded as "abelian:<short abel address hex>?amount=<ABEL>&memo=<memo hex>".
+// The amount is in ABEL with up to 7 decimals, and both query parameters are optional. A full abel address is
+// about 21 KB in hex, too large for a QR code, so the request carries the short address, and the payer looks
+// up the full address it identifies, checking it with IsFor before paying.
+type PaymentRequest struct {
+	Address *ShortAbelAddress
+	Amount  int64
+	Memo    Bytes
+}
+
+func NewPaymentRequest(address *ShortAbelAddress, amount int64, memo ...Bytes) *PaymentRequest {
+	if len(memo) == 0 {
+		memo = append(memo, nil)
+	}
+
+	return &PaymentRequest{
+		Address: address,
+		Amount:  amount,
+		Memo:    memo[0],
+	}
+}
+
+func (r *PaymentRequest) EncodeURI() string {
+	query := url.Values{}
+	if r.Amount > 0 {
+		query.Set("amount", FormatAbel(r.Amount, true))
+	}
+	if r.Memo.Len() > 0 {
+		query.Set("memo", r.Memo.HexString())
+	}
+
+	uri := fmt.Sprintf("%s:%s", PAYMENT_URI_SCHEME, r.Address.HexString())
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+
+	return uri
+}
+
+// IsFor reports whether address is the full abel address identified by the short address of the request.
+func (r *PaymentRequest) IsFor(address *AbelAddress) bool {
+	return address != nil && r.Address.Equal(address.GetShortAbelAddress())
+}
+
+// Define util functions.
+func ParsePaymentURI(s string) (*PaymentRequest, error) {
+	scheme, rest, found := strings.Cut(s, ":")
+	if !found || !strings.EqualFold(scheme, PAYMENT_URI_SCHEME) {
+		return nil, fmt.Errorf("payment uri does not start with %s:", PAYMENT_URI_SCHEME)
+	}
+
+	addressHex, rawQuery, _ := strings.Cut(rest, "?")
+	address, err := NewShortAbelAddressFromHex(addressHex)
+	if err != nil {
+		return nil, fmt.Errorf("payment uri address is not valid: %s", err)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("payment uri query is not valid: %s", err)
+	}
+
+	request := &PaymentRequest{Address: address}
+	if amount := query.Get("amount"); len(amount) > 0 {
+		request.Amount, err = ParseAbel(amount)
+		if err != nil {
+			return nil, fmt.Errorf("payment uri amount is not valid: %s", err)
+		}
+		if request.Amount <= 0 {
+			return nil, fmt.Errorf("payment uri amount %s is not positive", amount)
+		}
+	}
+	if memo := query.Get("memo"); len(memo) > 0 {
+		memoData, err := hex.DecodeString(memo)
+		if err != nil {
+			return nil, fmt.Errorf("payment uri memo is not valid hex: %s", err)
+		}
+		request.Memo = memoData
+	}
+
+	return request, nil
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPaymentURIRoundTrip(t *testing.T) {
+	abelAddress := newTestAbelAddress(t, 0)
+	shortAddress := abelAddress.GetShortAbelAddress()
+
+	for _, request := range []*PaymentRequest{
+		NewPaymentRequest(shortAddress, 12_3400000, []byte("invoice #42: café")),
+		NewPaymentRequest(shortAddress, 1, []byte{0x00, 0xff}),
+		NewPaymentRequest(shortAddress, 5_0000000),
+		NewPaymentRequest(shortAddress, 0),
+	} {
+		uri := request.EncodeURI()
+		if !strings.HasPrefix(uri, PAYMENT_URI_SCHEME+":"+shortAddress.HexString()) {
+			t.Errorf("got uri %s, want it to start with the short address", uri)
+		}
+		// The uri must fit in a QR code, which holds at most 4296 alphanumeric characters.
+		if len(uri) > 512 {
+			t.Errorf("got uri of %d characters, too long for a QR code", len(uri))
+		}
+
+		parsed, err := ParsePaymentURI(uri)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", uri, err)
+		}
+		if !parsed.Address.Equal(shortAddress) || parsed.Amount != request.Amount || !bytes.Equal(parsed.Memo, request.Memo) {
+			t.Errorf("got request %+v from %s, want %+v", parsed, uri, request)
+		}
+		if !parsed.IsFor(abelAddress) {
+			t.Errorf("request parsed from %s is not for its own address", uri)
+		}
+	}
+}
+
+func TestPaymentRequestIsFor(t *testing.T) {
+	abelAddress := newTestAbelAddress(t, 0)
+	request := NewPaymentRequest(abelAddress.GetShortAbelAddress(), 1)
+
+	if !request.IsFor(abelAddress) {
+		t.Errorf("request is not for its own address")
+	}
+	if request.IsFor(newTestAbelAddress(t, 0)) {
+		t.Errorf("request is for another address")
+	}
+	if request.IsFor(NewAbelAddressFromCryptoAddress(abelAddress.GetCryptoAddress(), 1)) {
+		t.Errorf("request is for its address on another chain")
+	}
+	if request.IsFor(nil) {
+		t.Errorf("request is for a nil address")
+	}
+}
+
+func TestParsePaymentURIErrors(t *testing.T) {
+	abelAddress := newTestAbelAddress(t, 0)
+	shortHex := abelAddress.GetShortAbelAddress().HexString()
+
+	for _, uri := range []string{
+		"bitcoin:" + shortHex,
+		shortHex,
+		PAYMENT_URI_SCHEME + ":" + abelAddress.HexString(),
+		PAYMENT_URI_SCHEME + ":" + shortHex[:len(shortHex)-2],
+		PAYMENT_URI_SCHEME + ":" + shortHex + "?amount=0",
+		PAYMENT_URI_SCHEME + ":" + shortHex + "?amount=-1",
+		PAYMENT_URI_SCHEME + ":" + shortHex + "?amount=1.00000001",
+		PAYMENT_URI_SCHEME + ":" + shortHex + "?memo=xyz",
+		PAYMENT_URI_SCHEME + ":" + shortHex + "?amount=1&memo=%zz",
+	} {
+		if _, err := ParsePaymentURI(uri); err == nil {
+			t.Errorf("parsed invalid uri %.80s", uri)
+		}
+	}
+}
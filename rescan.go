@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Define constants.
+const (
+	RESCAN_PROGRESS_INTERVAL = 100
+	RESCAN_BATCH_PAUSE       = 50 * time.Millisecond
+)
+
+// Define util functions.
+// RescanWallet scans the blocks from fromHeight up to the tip at the time of the call and applies the coins
+// found for keys to wallet. Blocks are applied one at a time and the wallet's scanned height is advanced after
+// each, so that when ctx is cancelled the wallet is left consistent at ScannedHeight, and the rescan resumes
+// by calling RescanWallet again from ScannedHeight+1. To spare the node, the rescan pauses for
+// RESCAN_BATCH_PAUSE after every RESCAN_PROGRESS_INTERVAL blocks, which is also when progress is called with
+// the height scanned to. progress may be nil. The serial numbers of the coins found are derived as each ring
+// group is completed, so that later blocks spending them mark them spent.
+func RescanWallet(ctx context.Context, client *AbecRPCClient, wallet *Wallet, keys *CryptoKeysAndAddress, fromHeight int64, progress func(int64)) error {
+	if fromHeight < 0 {
+		fromHeight = 0
+	}
+
+	_, chainInfo, err := client.GetChainInfoContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := NewFingerprintIndex()
+	index.Add(keys, chainInfo.Network().ChainID())
+
+	for height := fromHeight; height <= chainInfo.NumBlocks; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The verbose block only lists the txids, so the raw block is decoded for its outputs and inputs.
+		_, hash, err := client.GetBlockHashContext(ctx, height)
+		if err != nil {
+			return wrapHeightNotFound(height, err)
+		}
+		block, err := client.GetBlockParsedContext(ctx, *hash)
+		if err != nil {
+			return err
+		}
+		_, err = wallet.ApplyBlock(block, index)
+		if err != nil {
+			return err
+		}
+		// A block that completes a ring group makes the serial numbers of its coins derivable, and they are
+		// needed before a later block spends them.
+		if ringBlockHeights := GetRingBlockHeights(height); height == ringBlockHeights[len(ringBlockHeights)-1] {
+			_, err = wallet.DeriveSerialNumbersContext(ctx, client, height)
+			if err != nil {
+				return err
+			}
+		}
+		wallet.SetScannedHeight(height)
+
+		if (height-fromHeight+1)%RESCAN_PROGRESS_INTERVAL != 0 || height == chainInfo.NumBlocks {
+			continue
+		}
+		if progress != nil {
+			progress(height)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-client.clock.After(RESCAN_BATCH_PAUSE):
+		}
+	}
+
+	if progress != nil {
+		progress(wallet.ScannedHeight())
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/abesuite/abec/abeutil"
+	"github.com/abesuite/abec/blockchain"
+	api "github.com/abesuite/abec/sdkapi/v1"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define the RingAnalysis data type.
+type RingAnalysis struct {
+	InputIndex      int
+	RingBlockHeight int64
+	RingSize        int
+	MaxRingSize     int
+}
+
+// Define methods for RingAnalysis.
+func (analysis *RingAnalysis) IsReduced() bool {
+	return analysis.RingSize < analysis.MaxRingSize
+}
+
+// Define util functions.
+// AnalyzeRingAnonymity reports the size of the ring each input would be spent from.
+//
+// The node builds rings deterministically from the outputs of each ring group of consecutive blocks.
+// Coinbase outputs and transfer outputs of the group are pooled separately, ordered by a hash of the
+// block hashes and the outpoint, and then cut into rings of at most the ring size in effect at that
+// height. A ring group with few outputs, or the last ring of a pool, can therefore be smaller than the
+// maximum, and an input in such a ring is hidden among fewer decoys.
+func AnalyzeRingAnonymity(ringBlockDescs map[int64]*TxBlockDesc, inputs []*TxInDesc) ([]*RingAnalysis, error) {
+	ringSizesByGroup := make(map[int64]map[wire.OutPointId]int)
+	analyses := make([]*RingAnalysis, 0, len(inputs))
+	for i, input := range inputs {
+		ringBlockHeights := GetRingBlockHeights(input.Height)
+		ringBlockHeight := ringBlockHeights[0]
+
+		ringSizes, ok := ringSizesByGroup[ringBlockHeight]
+		if !ok {
+			var err error
+			ringSizes, err = buildRingSizes(ringBlockDescs, ringBlockHeights)
+			if err != nil {
+				return nil, fmt.Errorf("input %d: %s", i, err)
+			}
+			ringSizesByGroup[ringBlockHeight] = ringSizes
+		}
+
+		outPoint, err := api.NewOutPointFromTxIdStr(hex.EncodeToString(input.TxHash), input.TxOutIndex)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %s", i, err)
+		}
+		ringSize, ok := ringSizes[outPoint.OutPointId()]
+		if !ok {
+			return nil, fmt.Errorf("input %d is not in any ring of the ring group at height %d", i, ringBlockHeight)
+		}
+
+		analyses = append(analyses, &RingAnalysis{
+			InputIndex:      i,
+			RingBlockHeight: ringBlockHeight,
+			RingSize:        ringSize,
+			MaxRingSize:     int(wire.GetTxoRingSizeByBlockHeight(int32(ringBlockHeight))),
+		})
+	}
+
+	return analyses, nil
+}
+
+// FindInputRings returns the ring each input is spent from, in the form the node reports and accepts rings.
+func FindInputRings(ringBlockDescs map[int64]*TxBlockDesc, inputs []*TxInDesc) ([]*AbecUTXORing, error) {
+	txoRingsByGroup := make(map[int64]map[wire.RingId]*wire.TxoRing)
+	rings := make([]*AbecUTXORing, 0, len(inputs))
+	for i, input := range inputs {
+		ringBlockHeights := GetRingBlockHeights(input.Height)
+		ringBlockHeight := ringBlockHeights[0]
+
+		txoRings, ok := txoRingsByGroup[ringBlockHeight]
+		if !ok {
+			var err error
+			txoRings, err = buildTxoRings(ringBlockDescs, ringBlockHeights)
+			if err != nil {
+				return nil, fmt.Errorf("input %d: %s", i, err)
+			}
+			txoRingsByGroup[ringBlockHeight] = txoRings
+		}
+
+		outPoint, err := api.NewOutPointFromTxIdStr(hex.EncodeToString(input.TxHash), input.TxOutIndex)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %s", i, err)
+		}
+		ring := findTxoRing(txoRings, outPoint.OutPointId())
+		if ring == nil {
+			return nil, fmt.Errorf("input %d is not in any ring of the ring group at height %d", i, ringBlockHeight)
+		}
+
+		rings = append(rings, newAbecUTXORing(ring.OutPointRing))
+	}
+
+	return rings, nil
+}
+
+func findTxoRing(txoRings map[wire.RingId]*wire.TxoRing, outPointId wire.OutPointId) *wire.TxoRing {
+	for _, txoRing := range txoRings {
+		for _, outPoint := range txoRing.OutPointRing.OutPoints {
+			if outPoint.OutPointId() == outPointId {
+				return txoRing
+			}
+		}
+	}
+
+	return nil
+}
+
+func newAbecUTXORing(outPointRing *wire.OutPointRing) *AbecUTXORing {
+	ring := &AbecUTXORing{
+		Version:     int64(outPointRing.Version),
+		BlockHashes: make([]string, 0, len(outPointRing.BlockHashs)),
+		OutPoints:   make([]*AbecOutPoint, 0, len(outPointRing.OutPoints)),
+	}
+	for _, blockHash := range outPointRing.BlockHashs {
+		ring.BlockHashes = append(ring.BlockHashes, blockHash.String())
+	}
+	for _, outPoint := range outPointRing.OutPoints {
+		ring.OutPoints = append(ring.OutPoints, &AbecOutPoint{
+			TxHash: outPoint.TxHash.String(),
+			Index:  int64(outPoint.Index),
+		})
+	}
+
+	return ring
+}
+
+func buildRingSizes(ringBlockDescs map[int64]*TxBlockDesc, ringBlockHeights []int64) (map[wire.OutPointId]int, error) {
+	txoRings, err := buildTxoRings(ringBlockDescs, ringBlockHeights)
+	if err != nil {
+		return nil, err
+	}
+
+	ringSizes := make(map[wire.OutPointId]int)
+	for _, txoRing := range txoRings {
+		for _, outPoint := range txoRing.OutPointRing.OutPoints {
+			ringSizes[outPoint.OutPointId()] = len(txoRing.OutPointRing.OutPoints)
+		}
+	}
+
+	return ringSizes, nil
+}
+
+func buildTxoRings(ringBlockDescs map[int64]*TxBlockDesc, ringBlockHeights []int64) (map[wire.RingId]*wire.TxoRing, error) {
+	blocks := make([]*abeutil.BlockAbe, 0, len(ringBlockHeights))
+	for _, height := range ringBlockHeights {
+		ringBlockDesc, ok := ringBlockDescs[height]
+		if !ok {
+			return nil, fmt.Errorf("ring block at height %d is missing", height)
+		}
+
+		block, err := abeutil.NewBlockFromBytesAbe(ringBlockDesc.BinData)
+		if err != nil {
+			return nil, fmt.Errorf("ring block at height %d cannot be parsed: %s", height, err)
+		}
+		err = api.GetAndSetHeight(block)
+		if err != nil {
+			return nil, fmt.Errorf("ring block at height %d cannot be parsed: %s", height, err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	ringSize := wire.GetTxoRingSizeByBlockHeight(int32(ringBlockHeights[0]))
+	return blockchain.BuildTxoRings(len(blocks), int(ringSize), blocks)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abesuite/abec/wire"
+)
+
+func TestAnalyzeRingAnonymity(t *testing.T) {
+	keys := newTestKeys(t)
+	payments := []testPayment{{keys, 1000}, {keys, 2000}, {keys, 3000}, {keys, 4000}}
+	client := NewSimulationClient(newTestChain(t, 3, map[int64][]testPayment{0: payments}))
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	inputs := make([]*TxInDesc, 0)
+	for _, coin := range w.Coins() {
+		inputs = append(inputs, coin.ToTxInDesc())
+	}
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, inputs)
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+
+	analyses, err := AnalyzeRingAnonymity(ringBlockDescs, inputs)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	rings, err := FindInputRings(ringBlockDescs, inputs)
+	if err != nil {
+		t.Fatalf("cannot find the rings: %s", err)
+	}
+	if len(analyses) != len(inputs) {
+		t.Fatalf("got %d analyses for %d inputs", len(analyses), len(inputs))
+	}
+
+	// The ring group pools the 4 payments with the coinbase outputs of blocks 1 and 2, so every input is in
+	// the same ring of 6, one short of the maximum.
+	maxRingSize := int(wire.GetTxoRingSizeByBlockHeight(0))
+	for i, analysis := range analyses {
+		if analysis.InputIndex != i || analysis.RingBlockHeight != 0 {
+			t.Errorf("analysis %d is for input %d at ring block height %d, want input %d at 0", i, analysis.InputIndex, analysis.RingBlockHeight, i)
+		}
+		if analysis.RingSize != 6 || analysis.MaxRingSize != maxRingSize || !analysis.IsReduced() {
+			t.Errorf("input %d: got ring size %d of %d, reduced %t, want 6 of %d, reduced", i, analysis.RingSize, analysis.MaxRingSize, analysis.IsReduced(), maxRingSize)
+		}
+		if len(rings[i].OutPoints) != analysis.RingSize {
+			t.Errorf("input %d: got ring size %d, but its ring has %d outpoints", i, analysis.RingSize, len(rings[i].OutPoints))
+		}
+	}
+	if full := (&RingAnalysis{RingSize: maxRingSize, MaxRingSize: maxRingSize}); full.IsReduced() {
+		t.Errorf("a full ring is reported reduced")
+	}
+
+	withoutBlock := make(map[int64]*TxBlockDesc)
+	for height, ringBlockDesc := range ringBlockDescs {
+		if height != 1 {
+			withoutBlock[height] = ringBlockDesc
+		}
+	}
+	_, err = AnalyzeRingAnonymity(withoutBlock, inputs)
+	if err == nil {
+		t.Errorf("got no error with a missing ring block")
+	}
+
+	foreign := *inputs[0]
+	foreign.TxHash = Txid{0xff}.APIBytes()
+	_, err = AnalyzeRingAnonymity(ringBlockDescs, []*TxInDesc{&foreign})
+	if err == nil {
+		t.Errorf("got no error for an input outside the ring group")
+	}
+}
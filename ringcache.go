@@ -0,0 +1,124 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Define the RingBlockCache data type.
+// RingBlockCache holds each ring block once, so that tx descs built from it share the same *TxBlockDesc
+// for a height instead of each holding a copy of the block bytes.
+type RingBlockCache struct {
+	mutex      sync.RWMutex
+	fetchMutex sync.Mutex
+	blocks     map[int64]*TxBlockDesc
+}
+
+// Define methods for RingBlockCache.
+func NewRingBlockCache() *RingBlockCache {
+	return &RingBlockCache{
+		blocks: make(map[int64]*TxBlockDesc),
+	}
+}
+
+func (cache *RingBlockCache) Len() int {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	return len(cache.blocks)
+}
+
+// Add stores ringBlockDesc unless the cache already holds its height, and returns the one the cache holds.
+func (cache *RingBlockCache) Add(ringBlockDesc *TxBlockDesc) *TxBlockDesc {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cached, ok := cache.blocks[ringBlockDesc.Height]; ok {
+		return cached
+	}
+	cache.blocks[ringBlockDesc.Height] = ringBlockDesc
+	return ringBlockDesc
+}
+
+// RingBlockDescsForInputs returns the ring blocks of the inputs, fetching the heights the cache does not hold
+// yet in a single batch.
+func (cache *RingBlockCache) RingBlockDescsForInputs(client *AbecRPCClient, txInDescs []*TxInDesc) (map[int64]*TxBlockDesc, error) {
+	heights := GetRingBlockHeightsForInputs(txInDescs)
+	if len(cache.missingHeights(heights)) > 0 {
+		// Fetches are serialized, so that concurrent misses on the same heights fetch them only once.
+		cache.fetchMutex.Lock()
+		missingHeights := cache.missingHeights(heights)
+		results := FetchBlocksConcurrent(client, missingHeights)
+		err := BlockFetchError(results)
+		if err == nil {
+			for _, result := range results {
+				cache.Add(NewTxBlockDesc(result.Bytes, result.Height))
+			}
+		}
+		cache.fetchMutex.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	ringBlockDescs := make(map[int64]*TxBlockDesc, len(heights))
+	for _, height := range heights {
+		ringBlockDescs[height] = cache.blocks[height]
+	}
+
+	return ringBlockDescs, nil
+}
+
+// Share replaces the ring blocks of each tx desc with the cache's copy of the same height, adding the ones
+// the cache does not hold yet. It is used to deduplicate tx descs that were built separately.
+func (cache *RingBlockCache) Share(txDescs ...*TxDesc) {
+	for _, txDesc := range txDescs {
+		for height, ringBlockDesc := range txDesc.TxRingBlockDescs {
+			txDesc.TxRingBlockDescs[height] = cache.Add(ringBlockDesc)
+		}
+	}
+}
+
+func (cache *RingBlockCache) missingHeights(heights []int64) []int64 {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	missing := make([]int64, 0)
+	for _, height := range heights {
+		if _, ok := cache.blocks[height]; !ok {
+			missing = append(missing, height)
+		}
+	}
+
+	return missing
+}
+
+// Define util functions.
+// BuildTxDescsWithCache builds a tx desc for each set of inputs and outputs, with the ring blocks taken from
+// the cache. Each set of outputs is expected to already include change, so that the inputs cover the outputs
+// plus the fee.
+func BuildTxDescsWithCache(client *AbecRPCClient, cache *RingBlockCache, txInDescs [][]*TxInDesc, txOutDescs [][]*TxOutDesc, txFees []int64) ([]*TxDesc, error) {
+	if len(txOutDescs) != len(txInDescs) || len(txFees) != len(txInDescs) {
+		return nil, fmt.Errorf("got %d input sets, %d output sets and %d fees", len(txInDescs), len(txOutDescs), len(txFees))
+	}
+
+	txDescs := make([]*TxDesc, 0, len(txInDescs))
+	for i := range txInDescs {
+		ringBlockDescs, err := cache.RingBlockDescsForInputs(client, txInDescs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		txDesc := NewTxDesc(txInDescs[i], txOutDescs[i], txFees[i], ringBlockDescs)
+		err = txDesc.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("tx desc %d: %s", i, err)
+		}
+		txDescs = append(txDescs, txDesc)
+	}
+
+	return txDescs, nil
+}
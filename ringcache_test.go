@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// countingTransport counts the block hash lookups sent to the chain by height, one per block fetch.
+type countingTransport struct {
+	chain *SimulationChain
+
+	mutex   sync.Mutex
+	fetches map[int64]int
+}
+
+func (transport *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var rpcReq struct {
+		Method string  `json:"method"`
+		Params []int64 `json:"params"`
+	}
+	if json.Unmarshal(body, &rpcReq) == nil && rpcReq.Method == "getblockhash" && len(rpcReq.Params) == 1 {
+		transport.mutex.Lock()
+		transport.fetches[rpcReq.Params[0]]++
+		transport.mutex.Unlock()
+	}
+
+	return transport.chain.RoundTrip(req)
+}
+
+func newCountingClient(t *testing.T, numBlocks int64) (*AbecRPCClient, *countingTransport) {
+	t.Helper()
+
+	transport := &countingTransport{chain: newTestChain(t, numBlocks, nil), fetches: make(map[int64]int)}
+	client := NewSimulationClient(transport.chain)
+	client.httpClient.Transport = transport
+
+	return client, transport
+}
+
+func TestRingBlockCacheSharesBlocks(t *testing.T) {
+	client, transport := newCountingClient(t, 6)
+	cache := NewRingBlockCache()
+
+	// The inputs at heights 0 and 2 share the ring group of heights 0 to 2, and the input at 4 adds 3 to 5.
+	first, err := cache.RingBlockDescsForInputs(client, []*TxInDesc{{Height: 0}})
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	second, err := cache.RingBlockDescsForInputs(client, []*TxInDesc{{Height: 2}, {Height: 4}})
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(first) != 3 || len(second) != 6 || cache.Len() != 6 {
+		t.Fatalf("got %d and %d ring blocks and %d cached, want 3, 6 and 6", len(first), len(second), cache.Len())
+	}
+	for height, ringBlockDesc := range first {
+		if second[height] != ringBlockDesc {
+			t.Errorf("block %d is held twice", height)
+		}
+	}
+	for height := int64(0); height < 6; height++ {
+		if transport.fetches[height] != 1 {
+			t.Errorf("block %d was fetched %d times, want once", height, transport.fetches[height])
+		}
+	}
+
+	// Share replaces the separately fetched blocks of a tx desc with the cached ones.
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, []*TxInDesc{{Height: 1}})
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+	txDesc := NewTxDesc(nil, nil, 0, ringBlockDescs)
+	cache.Share(txDesc)
+	for height, ringBlockDesc := range txDesc.TxRingBlockDescs {
+		if ringBlockDesc != first[height] {
+			t.Errorf("block %d is not shared with the cache", height)
+		}
+	}
+	if cache.Len() != 6 {
+		t.Errorf("got %d cached blocks after sharing, want 6", cache.Len())
+	}
+}
+
+func TestRingBlockCacheFetchesMissOnce(t *testing.T) {
+	client, transport := newCountingClient(t, 3)
+	cache := NewRingBlockCache()
+
+	// Concurrent misses on the same heights fetch each block once.
+	const numGoroutines = 8
+	results := make([]map[int64]*TxBlockDesc, numGoroutines)
+	errs := make([]error, numGoroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.RingBlockDescsForInputs(client, []*TxInDesc{{Height: int64(i % 3)}})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < numGoroutines; i++ {
+		if errs[i] != nil {
+			t.Fatalf("goroutine %d: got error %s", i, errs[i])
+		}
+		for height, ringBlockDesc := range results[i] {
+			if ringBlockDesc != results[0][height] {
+				t.Errorf("goroutine %d: got another copy of block %d", i, height)
+			}
+		}
+	}
+	for height := int64(0); height < 3; height++ {
+		if transport.fetches[height] != 1 {
+			t.Errorf("block %d was fetched %d times, want once", height, transport.fetches[height])
+		}
+	}
+
+	// A hit fetches nothing.
+	_, err := cache.RingBlockDescsForInputs(client, []*TxInDesc{{Height: 1}})
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	for height := int64(0); height < 3; height++ {
+		if transport.fetches[height] != 1 {
+			t.Errorf("block %d was fetched again on a hit", height)
+		}
+	}
+}
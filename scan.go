@@ -0,0 +1,206 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Define the FingerprintIndexEntry data type.
+type FingerprintIndexEntry struct {
+	Keys         *CryptoKeysAndAddress
+	Address      *AbelAddress
+	AccountIndex uint32
+}
+
+// Define the OutputMatch data type.
+type OutputMatch struct {
+	Index        int64
+	Address      *AbelAddress
+	Keys         *CryptoKeysAndAddress
+	AccountIndex uint32
+	Value        int64
+	TxOutData    Bytes
+}
+
+// Define the FingerprintIndex data type.
+type FingerprintIndex struct {
+	mutex   sync.RWMutex
+	entries map[string]*FingerprintIndexEntry
+	stats   *ScanStats
+}
+
+// Define methods for FingerprintIndex.
+func NewFingerprintIndex() *FingerprintIndex {
+	return &FingerprintIndex{
+		entries: make(map[string]*FingerprintIndexEntry),
+	}
+}
+
+func (index *FingerprintIndex) Add(keys *CryptoKeysAndAddress, chainID ...int8) {
+	index.AddAccount(0, keys, chainID...)
+}
+
+// AddAccount indexes keys that belong to the given account, so that the coins found for them are tagged with it.
+func (index *FingerprintIndex) AddAccount(accountIndex uint32, keys *CryptoKeysAndAddress, chainID ...int8) {
+	entry := &FingerprintIndexEntry{
+		Keys:         keys,
+		Address:      NewAbelAddressFromCryptoAddress(&keys.CryptoAddress, chainID...),
+		AccountIndex: accountIndex,
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.entries[keys.CryptoAddress.Fingerprint().HexString()] = entry
+}
+
+// SetScanStats attaches stats that every block scan through the index is recorded in.
+func (index *FingerprintIndex) SetScanStats(stats *ScanStats) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.stats = stats
+}
+
+func (index *FingerprintIndex) ScanStats() *ScanStats {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return index.stats
+}
+
+func (index *FingerprintIndex) Lookup(fingerprint Bytes) *FingerprintIndexEntry {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return index.entries[fingerprint.HexString()]
+}
+
+func (index *FingerprintIndex) Len() int {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return len(index.entries)
+}
+
+// Define util functions.
+func ScanBlockForCoins(block *AbecBlock, keys *CryptoKeysAndAddress, chainID ...int8) ([]*Coin, error) {
+	return ScanBlockForAccountCoins(block, 0, keys, chainID...)
+}
+
+func ScanBlockForAccountCoins(block *AbecBlock, accountIndex uint32, keys *CryptoKeysAndAddress, chainID ...int8) ([]*Coin, error) {
+	index := NewFingerprintIndex()
+	index.AddAccount(accountIndex, keys, chainID...)
+	return ScanBlockMultiWallet(block, index)
+}
+
+// ScanBlockForPayments returns only the coins paying keys exactly expectedValue, to detect payments by
+// unique invoice amounts.
+func ScanBlockForPayments(block *AbecBlock, keys *CryptoKeysAndAddress, expectedValue int64, chainID ...int8) ([]*Coin, error) {
+	coins, err := ScanBlockForCoins(block, keys, chainID...)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]*Coin, 0, len(coins))
+	for _, coin := range coins {
+		if coin.VerifyValue(expectedValue) {
+			payments = append(payments, coin)
+		}
+	}
+
+	return payments, nil
+}
+
+func ScanBlockMultiWallet(block *AbecBlock, index *FingerprintIndex) ([]*Coin, error) {
+	blockHash, err := hex.DecodeString(block.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("block hash is not valid hex: %s", err)
+	}
+
+	coins := make([]*Coin, 0)
+	for i, tx := range block.RawTxs {
+		txHash, err := NewTxidFromHex(tx.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("txid of tx %d is not valid: %s", i, err)
+		}
+
+		matches, err := matchTxOutputs(tx, index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			coinID, err := NewCoinIDFromRPCIndex(txHash, match.Index)
+			if err != nil {
+				return nil, fmt.Errorf("output %s:%d: %s", tx.TxID, match.Index, err)
+			}
+
+			coins = append(coins, &Coin{
+				ID:                *coinID,
+				OwnerShortAddress: match.Address.GetShortAbelAddress(),
+				OwnerAddress:      match.Address,
+				Value:             match.Value,
+				TxVoutData:        match.TxOutData,
+				BlockHash:         blockHash,
+				BlockHeight:       block.Height,
+				IsCoinbase:        i == 0,
+				AccountIndex:      match.AccountIndex,
+				serialNoSecretKey: &match.Keys.SerialNoSecretKey,
+			})
+		}
+	}
+
+	if stats := index.ScanStats(); stats != nil {
+		stats.Record(1, int64(len(coins)))
+	}
+
+	return coins, nil
+}
+
+// MatchMyOutputs reports which outputs of tx pay one of the given keys, and the decoded value of each.
+func MatchMyOutputs(tx *AbecTx, keys []*CryptoKeysAndAddress, chainID ...int8) ([]*OutputMatch, error) {
+	index := NewFingerprintIndex()
+	for _, k := range keys {
+		index.Add(k, chainID...)
+	}
+
+	return matchTxOutputs(tx, index)
+}
+
+func matchTxOutputs(tx *AbecTx, index *FingerprintIndex) ([]*OutputMatch, error) {
+	matches := make([]*OutputMatch, 0)
+	for _, vout := range tx.Vout {
+		txOutData, err := hex.DecodeString(vout.Script)
+		if err != nil {
+			return nil, fmt.Errorf("script of output %s:%d is not valid hex: %s", tx.TxID, vout.N, err)
+		}
+
+		// Decode the coin address once and match it against all indexed wallets.
+		coinAddress, err := DecodeCoinAddressFromTxOutData(txOutData)
+		if err != nil {
+			return nil, err
+		}
+		entry := index.Lookup(coinAddress.Fingerprint())
+		if entry == nil {
+			continue
+		}
+
+		value, err := DecodeValueFromTxOutData(txOutData, &entry.Keys.ViewSecretKey)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, &OutputMatch{
+			Index:        vout.N,
+			Address:      entry.Address,
+			Keys:         entry.Keys,
+			AccountIndex: entry.AccountIndex,
+			Value:        value,
+			TxOutData:    txOutData,
+		})
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestScanBlockDerivesSerialNumber(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 3, map[int64][]testPayment{0: {{keys, 5000}}})
+	client := NewSimulationClient(chain)
+
+	blockBytes, err := client.GetBlockBytesByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+	coins, err := ScanBlockForCoins(block, keys)
+	if err != nil {
+		t.Fatalf("cannot scan block 0: %s", err)
+	}
+	if len(coins) != 1 || coins[0].Value != 5000 {
+		t.Fatalf("got %d coins, want one coin of 5000", len(coins))
+	}
+	coin := coins[0]
+
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, []*TxInDesc{coin.ToTxInDesc()})
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+	err = coin.DeriveSerialNumber(ringBlockDescs)
+	if err != nil {
+		t.Fatalf("cannot derive the serial number: %s", err)
+	}
+	if coin.SerialNumber.Len() == 0 {
+		t.Fatalf("coin has no serial number")
+	}
+
+	want, err := DecodeCoinSerialNumbers([]*CoinID{&coin.ID}, []*CryptoKey{&keys.SerialNoSecretKey}, ringBlockDescs)
+	if err != nil {
+		t.Fatalf("cannot decode the serial number: %s", err)
+	}
+	if !bytes.Equal(coin.SerialNumber, want[0]) {
+		t.Errorf("got serial number %s, want %s", coin.SerialNumber, want[0])
+	}
+}
+
+func TestDeriveSerialNumberNeedsCompleteRingGroup(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 2, map[int64][]testPayment{0: {{keys, 5000}}})
+	client := NewSimulationClient(chain)
+
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	coins := w.Coins()
+	if len(coins) != 1 {
+		t.Fatalf("got %d coins, want 1", len(coins))
+	}
+	if coins[0].SerialNumber != nil {
+		t.Errorf("coin has a serial number before its ring group is complete")
+	}
+
+	err = coins[0].DeriveSerialNumber(map[int64]*TxBlockDesc{})
+	if err == nil {
+		t.Errorf("derived a serial number without the ring blocks")
+	}
+}
+
+func TestRescanWalletDerivesSerialNumbers(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 4, map[int64][]testPayment{1: {{keys, 5000}}, 3: {{keys, 7000}}})
+	client := NewSimulationClient(chain)
+
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+
+	coins := w.Coins()
+	if len(coins) != 2 {
+		t.Fatalf("got %d coins, want 2", len(coins))
+	}
+	for _, coin := range coins {
+		complete := coin.BlockHeight < 3
+		if got := coin.SerialNumber.Len() > 0; got != complete {
+			t.Errorf("coin at height %d has serial number %v, want %v", coin.BlockHeight, got, complete)
+		}
+	}
+}
+
+// interruptingTransport cancels a rescan on the given request number, failing that request as a node that
+// went away mid-rescan would, and passes every other request to the chain.
+type interruptingTransport struct {
+	chain       *SimulationChain
+	interruptAt int
+	cancel      context.CancelFunc
+
+	mutex    sync.Mutex
+	requests int
+}
+
+func (transport *interruptingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport.mutex.Lock()
+	transport.requests++
+	interrupt := transport.requests == transport.interruptAt
+	transport.mutex.Unlock()
+	if interrupt {
+		transport.cancel()
+		return nil, context.Canceled
+	}
+
+	return transport.chain.RoundTrip(req)
+}
+
+func TestRescanWalletResumesAfterInterruption(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 9, map[int64][]testPayment{1: {{keys, 5000}}, 4: {{keys, 7000}}, 5: {{keys, 3000}}, 8: {{keys, 2000}}})
+
+	want := NewWallet()
+	err := RescanWallet(context.Background(), NewSimulationClient(chain), want, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+
+	// Interrupt the rescan at every request in turn, including the ring block fetches for serial numbers.
+	for interruptAt := 1; ; interruptAt++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		transport := &interruptingTransport{chain: chain, interruptAt: interruptAt, cancel: cancel}
+		client := NewSimulationClient(chain)
+		client.httpClient.Transport = transport
+
+		w := NewWallet()
+		err := RescanWallet(ctx, client, w, keys, 0, nil)
+		cancel()
+		if err == nil {
+			// The rescan made fewer requests than interruptAt, so every request has been interrupted.
+			break
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v when interrupted at request %d, want context.Canceled", err, interruptAt)
+		}
+
+		err = RescanWallet(context.Background(), NewSimulationClient(chain), w, keys, w.ScannedHeight()+1, nil)
+		if err != nil {
+			t.Fatalf("cannot resume the rescan interrupted at request %d: %s", interruptAt, err)
+		}
+		expectSameCoins(t, fmt.Sprintf("interrupted at request %d", interruptAt), w.Coins(), want.Coins())
+		if w.ScannedHeight() != want.ScannedHeight() {
+			t.Errorf("interrupted at request %d: got scanned height %d, want %d", interruptAt, w.ScannedHeight(), want.ScannedHeight())
+		}
+	}
+}
+
+func expectSameCoins(t *testing.T, name string, coins []*Coin, want []*Coin) {
+	t.Helper()
+
+	if len(coins) != len(want) {
+		t.Errorf("%s: got %d coins, want %d", name, len(coins), len(want))
+		return
+	}
+	for i, coin := range coins {
+		if coin.ID != want[i].ID || coin.Value != want[i].Value || coin.BlockHeight != want[i].BlockHeight ||
+			!bytes.Equal(coin.SerialNumber, want[i].SerialNumber) {
+			t.Errorf("%s: got coin %d %+v, want %+v", name, i, coin, want[i])
+		}
+	}
+}
+
+func TestScanBlockForPayments(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	chain := newTestChain(t, 1, map[int64][]testPayment{0: {{keys, 5000}, {other, 7000}, {keys, 7000}}})
+	blockBytes, err := NewSimulationClient(chain).GetBlockBytesByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+
+	tests := []struct {
+		name          string
+		keys          *CryptoKeysAndAddress
+		expectedValue int64
+		wantIndex     int
+	}{
+		{"first output", keys, 5000, 0},
+		{"value also paid to other keys", keys, 7000, 2},
+		{"other keys", other, 7000, 1},
+		{"value mismatch", keys, 6000, -1},
+		{"other keys value mismatch", other, 5000, -1},
+	}
+	for _, test := range tests {
+		payments, err := ScanBlockForPayments(block, test.keys, test.expectedValue)
+		if err != nil {
+			t.Fatalf("%s: got error %s", test.name, err)
+		}
+		if test.wantIndex < 0 {
+			if len(payments) != 0 {
+				t.Errorf("%s: got %d payments, want none", test.name, len(payments))
+			}
+			continue
+		}
+		if len(payments) != 1 || int(payments[0].ID.Index) != test.wantIndex || payments[0].Value != test.expectedValue {
+			t.Errorf("%s: got %d payments, want output %d of %d", test.name, len(payments), test.wantIndex, test.expectedValue)
+		}
+	}
+
+	coin := newTestCoin(0, 5000, 0)
+	if !coin.VerifyValue(5000) || coin.VerifyValue(4999) || coin.VerifyValue(5001) {
+		t.Errorf("coin of 5000 does not verify exactly 5000")
+	}
+}
+
+func TestMatchMyOutputs(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	foreignKeys := newTestKeys(t)
+	payments := []testPayment{{keys, 1000}, {foreignKeys, 2000}, {otherKeys, 3000}, {foreignKeys, 4000}}
+	client := NewSimulationClient(newTestChain(t, 1, map[int64][]testPayment{0: payments}))
+
+	blockBytes, err := client.GetBlockBytesByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+	tx := block.RawTxs[0]
+
+	matches, err := MatchMyOutputs(tx, []*CryptoKeysAndAddress{keys, otherKeys})
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	want := []struct {
+		index int64
+		keys  *CryptoKeysAndAddress
+		value int64
+	}{{0, keys, 1000}, {2, otherKeys, 3000}}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(matches), len(want))
+	}
+	for i, match := range matches {
+		if match.Index != want[i].index || match.Keys != want[i].keys || match.Value != want[i].value {
+			t.Errorf("match %d is output %d of %d, want output %d of %d", i, match.Index, match.Value, want[i].index, want[i].value)
+		}
+		if !bytes.Equal(match.Address.Fingerprint(), want[i].keys.CryptoAddress.Fingerprint()) {
+			t.Errorf("match %d has the address of other keys", i)
+		}
+	}
+
+	matches, err = MatchMyOutputs(tx, nil)
+	if err != nil || len(matches) != 0 {
+		t.Errorf("got %d matches and error %v without keys", len(matches), err)
+	}
+
+	invalid := *tx
+	invalid.Vout = []*AbecTxVout{{N: 0, Script: "zz"}}
+	_, err = MatchMyOutputs(&invalid, []*CryptoKeysAndAddress{keys})
+	if err == nil {
+		t.Errorf("got no error for an output script that is not hex")
+	}
+}
+
+func BenchmarkScanBlockManyWallets(b *testing.B) {
+	const numWallets = 100
+	wallets := make([]*CryptoKeysAndAddress, numWallets)
+	for i := range wallets {
+		wallets[i] = newTestKeys(b)
+	}
+	const numPayments = 4
+	payments := make([]testPayment, 0, numPayments)
+	for i := 0; i < numPayments; i++ {
+		payments = append(payments, testPayment{wallets[i*numWallets/numPayments], 5000})
+	}
+	blockBytes, err := NewSimulationClient(newTestChain(b, 1, map[int64][]testPayment{0: payments})).GetBlockBytesByHeight(0)
+	if err != nil {
+		b.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		b.Fatalf("cannot decode block 0: %s", err)
+	}
+
+	b.Run("index", func(b *testing.B) {
+		index := NewFingerprintIndex()
+		for _, keys := range wallets {
+			index.Add(keys)
+		}
+		for i := 0; i < b.N; i++ {
+			coins, err := ScanBlockMultiWallet(block, index)
+			if err != nil || len(coins) != len(payments) {
+				b.Fatalf("got %d coins and error %v, want %d coins", len(coins), err, len(payments))
+			}
+		}
+	})
+	b.Run("per wallet", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			found := 0
+			for _, keys := range wallets {
+				coins, err := ScanBlockForCoins(block, keys)
+				if err != nil {
+					b.Fatalf("got error %s", err)
+				}
+				found += len(coins)
+			}
+			if found != len(payments) {
+				b.Fatalf("got %d coins, want %d", found, len(payments))
+			}
+		}
+	})
+}
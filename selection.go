@@ -0,0 +1,285 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Define constants.
+const (
+	// DUST_THRESHOLD is the smallest change, 0.001 ABEL, that is worth a change output. Smaller change is
+	// added to the fee instead.
+	DUST_THRESHOLD = 10000
+
+	// BRANCH_AND_BOUND_MAX_TRIES bounds the search of BRANCH_AND_BOUND_STRATEGY before it falls back.
+	BRANCH_AND_BOUND_MAX_TRIES = 100000
+)
+
+type CoinSelectionStrategy int
+
+const (
+	LARGEST_FIRST_STRATEGY CoinSelectionStrategy = iota
+	MINIMIZE_RING_FETCHES_STRATEGY
+	BRANCH_AND_BOUND_STRATEGY
+)
+
+func (strategy CoinSelectionStrategy) String() string {
+	switch strategy {
+	case LARGEST_FIRST_STRATEGY:
+		return "largest-first"
+	case MINIMIZE_RING_FETCHES_STRATEGY:
+		return "minimize-ring-fetches"
+	case BRANCH_AND_BOUND_STRATEGY:
+		return "branch-and-bound"
+	default:
+		return "unknown"
+	}
+}
+
+// Define the InsufficientFundsError data type.
+// InsufficientFundsError is returned when the coins cannot cover the target value plus the fee of spending
+// all of them. Shortfall is the missing amount in neutrino.
+type InsufficientFundsError struct {
+	Shortfall int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: need %d more neutrino", e.Shortfall)
+}
+
+// Define the TooManyInputsError data type.
+// TooManyInputsError is returned when the coins cover the target value plus the fee, but only with more than
+// the MaxInputs inputs a tx can have. Shortfall is the amount in neutrino the largest MaxInputs coins miss.
+type TooManyInputsError struct {
+	MaxInputs int
+	Shortfall int64
+}
+
+func (e *TooManyInputsError) Error() string {
+	return fmt.Sprintf("too many inputs: %d coins need %d more neutrino", e.MaxInputs, e.Shortfall)
+}
+
+// Define util functions.
+// SelectCoins returns the coins to spend for targetValue and the change left after the fee. Change below
+// DUST_THRESHOLD is added to the fee and returned as 0, so no change output is made for it.
+// BRANCH_AND_BOUND_STRATEGY looks for coins that need no change output at all before falling back to
+// LARGEST_FIRST_STRATEGY, which is the default. No more coins are selected than a tx can spend, and a
+// TooManyInputsError is returned if the target needs more.
+func SelectCoins(coins []*Coin, targetValue int64, feeRate int64, strategy ...CoinSelectionStrategy) ([]*Coin, int64, error) {
+	// Fees are estimated for one recipient output plus one change output.
+	selected, change, _, err := selectCoins(coins, targetValue, 2, 0, NewFeePolicy(feeRate), strategy...)
+	return selected, change, err
+}
+
+// SelectCoinsWithLabel is like SelectCoins, but only spends coins with the given label.
+func SelectCoinsWithLabel(coins []*Coin, label string, targetValue int64, feeRate int64, strategy ...CoinSelectionStrategy) ([]*Coin, int64, error) {
+	return SelectCoins(FilterCoinsByLabel(coins, label), targetValue, feeRate, strategy...)
+}
+
+// FilterCoinsByLabel returns the coins with the given label. It reads the labels without a lock, so to filter
+// the coins of a wallet that may be relabelled concurrently, use Wallet.CoinsWithLabel instead.
+func FilterCoinsByLabel(coins []*Coin, label string) []*Coin {
+	filtered := make([]*Coin, 0, len(coins))
+	for _, coin := range coins {
+		if coin.Label == label {
+			filtered = append(filtered, coin)
+		}
+	}
+
+	return filtered
+}
+
+func FilterCoinsByAccount(coins []*Coin, accountIndex uint32) []*Coin {
+	filtered := make([]*Coin, 0, len(coins))
+	for _, coin := range coins {
+		if coin.AccountIndex == accountIndex {
+			filtered = append(filtered, coin)
+		}
+	}
+
+	return filtered
+}
+
+func selectCoins(coins []*Coin, targetValue int64, numOutputs int, memoLen int, feePolicy *FeePolicy, strategy ...CoinSelectionStrategy) ([]*Coin, int64, int64, error) {
+	if len(strategy) == 0 {
+		strategy = []CoinSelectionStrategy{LARGEST_FIRST_STRATEGY}
+	}
+
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var candidates []*Coin
+	switch strategy[0] {
+	case LARGEST_FIRST_STRATEGY:
+		candidates = orderCoinsLargestFirst(coins)
+	case MINIMIZE_RING_FETCHES_STRATEGY:
+		candidates = orderCoinsByRingGroup(coins)
+	case BRANCH_AND_BOUND_STRATEGY:
+		candidates = orderCoinsLargestFirst(coins)
+		if numOutputs > 1 {
+			selected, fee, err := selectCoinsWithoutChange(candidates, targetValue, numOutputs-1, memoLen, feePolicy, limits.MaxTxInputs)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if selected != nil {
+				return selected, 0, fee, nil
+			}
+		}
+	default:
+		return nil, 0, 0, fmt.Errorf("unknown coin selection strategy %d", strategy[0])
+	}
+
+	selected, change, fee, err := accumulateCoins(candidates, targetValue, numOutputs, memoLen, feePolicy, limits.MaxTxInputs)
+	var tooManyInputs *TooManyInputsError
+	if errors.As(err, &tooManyInputs) && strategy[0] == MINIMIZE_RING_FETCHES_STRATEGY {
+		// Grouping by ring may spread the value over too many coins, while the largest coins may still do.
+		return accumulateCoins(orderCoinsLargestFirst(coins), targetValue, numOutputs, memoLen, feePolicy, limits.MaxTxInputs)
+	}
+
+	return selected, change, fee, err
+}
+
+// accumulateCoins selects candidates in order until they cover the target value plus the fee. It returns a
+// TooManyInputsError if that takes more than maxInputs coins.
+func accumulateCoins(candidates []*Coin, targetValue int64, numOutputs int, memoLen int, feePolicy *FeePolicy, maxInputs int) ([]*Coin, int64, int64, error) {
+	total := int64(0)
+	fee := int64(0)
+	limitShortfall := int64(0)
+	for i, coin := range candidates {
+		numInputs := i + 1
+		total += coin.Value
+
+		txSize, err := estimateTxSize(numInputs, numOutputs, memoLen)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		fee = feePolicy.ComputeFee(txSize)
+
+		if total >= targetValue+fee {
+			if numInputs > maxInputs {
+				return nil, 0, 0, &TooManyInputsError{MaxInputs: maxInputs, Shortfall: limitShortfall}
+			}
+
+			selected := candidates[:numInputs:numInputs]
+			change := total - targetValue - fee
+			if change < DUST_THRESHOLD {
+				return selected, 0, fee + change, nil
+			}
+			return selected, change, fee, nil
+		}
+		if numInputs == maxInputs {
+			limitShortfall = targetValue + fee - total
+		}
+	}
+
+	return nil, 0, 0, &InsufficientFundsError{Shortfall: targetValue + fee - total}
+}
+
+// selectCoinsWithoutChange searches depth-first, larger coins first, for coins whose total exceeds the target
+// plus the fee of a tx without a change output by less than DUST_THRESHOLD. The excess goes to the returned
+// fee. It picks at most maxInputs coins, and returns no coins when there is no such set or the search runs out
+// of tries.
+func selectCoinsWithoutChange(candidates []*Coin, targetValue int64, numOutputs int, memoLen int, feePolicy *FeePolicy, maxInputs int) ([]*Coin, int64, error) {
+	// fees[n] is the fee of a tx with n inputs.
+	if maxInputs > len(candidates) {
+		maxInputs = len(candidates)
+	}
+	fees := make([]int64, maxInputs+1)
+	for n := 1; n <= maxInputs; n++ {
+		txSize, err := estimateTxSize(n, numOutputs, memoLen)
+		if err != nil {
+			return nil, 0, err
+		}
+		fees[n] = feePolicy.ComputeFee(txSize)
+	}
+
+	// remaining[i] is the total value of the candidates from i on.
+	remaining := make([]int64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].Value
+	}
+
+	picked := make([]int, 0)
+	tries := 0
+	var search func(i int, total int64) bool
+	search = func(i int, total int64) bool {
+		tries++
+		if tries > BRANCH_AND_BOUND_MAX_TRIES {
+			return false
+		}
+
+		n := len(picked)
+		if n > 0 && total >= targetValue+fees[n] {
+			return total-targetValue-fees[n] < DUST_THRESHOLD
+		}
+		// Fees only grow with more inputs, so the branch fails if even the current fee cannot be covered.
+		if i == len(candidates) || n == maxInputs || total+remaining[i] < targetValue+fees[n] {
+			return false
+		}
+
+		picked = append(picked, i)
+		if search(i+1, total+candidates[i].Value) {
+			return true
+		}
+		picked = picked[:len(picked)-1]
+
+		return search(i+1, total)
+	}
+	if !search(0, 0) {
+		return nil, 0, nil
+	}
+
+	selected := make([]*Coin, 0, len(picked))
+	total := int64(0)
+	for _, i := range picked {
+		selected = append(selected, candidates[i])
+		total += candidates[i].Value
+	}
+
+	return selected, total - targetValue, nil
+}
+
+func orderCoinsLargestFirst(coins []*Coin) []*Coin {
+	ordered := make([]*Coin, len(coins))
+	copy(ordered, coins)
+
+	// Coins from Wallet.CoinsSortedByValue are already ordered, so skip the sort for them.
+	largerFirst := func(i, j int) bool {
+		return ordered[i].Value > ordered[j].Value
+	}
+	if !sort.SliceIsSorted(ordered, largerFirst) {
+		sort.SliceStable(ordered, largerFirst)
+	}
+
+	return ordered
+}
+
+func orderCoinsByRingGroup(coins []*Coin) []*Coin {
+	// Coins in the same ring group share the three ring blocks that have to be fetched,
+	// so exhaust the most valuable groups first to touch as few groups as possible.
+	groups := make(map[int64][]*Coin)
+	groupValues := make(map[int64]int64)
+	groupHeights := make([]int64, 0)
+	for _, coin := range coins {
+		groupHeight := GetRingBlockHeights(coin.BlockHeight)[0]
+		if _, ok := groups[groupHeight]; !ok {
+			groupHeights = append(groupHeights, groupHeight)
+		}
+		groups[groupHeight] = append(groups[groupHeight], coin)
+		groupValues[groupHeight] += coin.Value
+	}
+
+	sort.SliceStable(groupHeights, func(i, j int) bool {
+		return groupValues[groupHeights[i]] > groupValues[groupHeights[j]]
+	})
+
+	ordered := make([]*Coin, 0, len(coins))
+	for _, groupHeight := range groupHeights {
+		ordered = append(ordered, orderCoinsLargestFirst(groups[groupHeight])...)
+	}
+
+	return ordered
+}
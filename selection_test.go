@@ -0,0 +1,239 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// newTestCoins returns a coin of each value, with distinct ids.
+func newTestCoins(values ...int64) []*Coin {
+	coins := make([]*Coin, len(values))
+	for i, value := range values {
+		coins[i] = newTestCoin(uint8(i), value, 10)
+	}
+
+	return coins
+}
+
+// repeatValue returns n copies of value.
+func repeatValue(value int64, n int) []int64 {
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = value
+	}
+
+	return values
+}
+
+func TestSelectCoins(t *testing.T) {
+	// Without a fee rate the selection arithmetic is exact. Only the branch-and-bound search after the
+	// first coin of 2500000 can reach 9000000 without change, with 4 coins of 2000000 and the coin of
+	// 1000000, but it exceeds BRANCH_AND_BOUND_MAX_TRIES first.
+	cutoffValues := append(append([]int64{2500000}, repeatValue(2000000, 60)...), 1000000)
+
+	tests := []struct {
+		name         string
+		values       []int64
+		targetValue  int64
+		strategy     CoinSelectionStrategy
+		wantSelected []int64
+		wantChange   int64
+	}{
+		{"largest first with change", []int64{100000, 500000, 300000}, 600000, LARGEST_FIRST_STRATEGY, []int64{500000, 300000}, 200000},
+		{"dust change folded into the fee", []int64{500000, 300000}, 795000, LARGEST_FIRST_STRATEGY, []int64{500000, 300000}, 0},
+		{"change at the dust threshold", []int64{500000, 300000}, 800000 - DUST_THRESHOLD, LARGEST_FIRST_STRATEGY, []int64{500000, 300000}, DUST_THRESHOLD},
+		{"branch and bound exact match", []int64{500000, 300000, 200000, 100000}, 600000, BRANCH_AND_BOUND_STRATEGY, []int64{500000, 100000}, 0},
+		{"branch and bound within dust", []int64{500000, 300000, 105000}, 600000, BRANCH_AND_BOUND_STRATEGY, []int64{500000, 105000}, 0},
+		{"branch and bound falls back", []int64{500000, 300000}, 600000, BRANCH_AND_BOUND_STRATEGY, []int64{500000, 300000}, 200000},
+		{"branch and bound gives up after max tries", cutoffValues, 9000000, BRANCH_AND_BOUND_STRATEGY, append([]int64{2500000}, repeatValue(2000000, 4)...), 1500000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, change, err := SelectCoins(newTestCoins(test.values...), test.targetValue, 0, test.strategy)
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if len(selected) != len(test.wantSelected) {
+				t.Fatalf("got %d coins, want %d", len(selected), len(test.wantSelected))
+			}
+			for i, coin := range selected {
+				if coin.Value != test.wantSelected[i] {
+					t.Errorf("coin %d has value %d, want %d", i, coin.Value, test.wantSelected[i])
+				}
+			}
+			if change != test.wantChange {
+				t.Errorf("got change %d, want %d", change, test.wantChange)
+			}
+		})
+	}
+}
+
+func TestSelectCoinsInsufficientFunds(t *testing.T) {
+	const feeRate = 10000
+	txSize, err := estimateTxSize(2, 2, 0)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+	fee := ComputeFee(txSize, feeRate)
+
+	tests := []struct {
+		name          string
+		feeRate       int64
+		strategy      CoinSelectionStrategy
+		wantShortfall int64
+	}{
+		{"without fee", 0, LARGEST_FIRST_STRATEGY, 100000},
+		{"with the fee of spending every coin", feeRate, LARGEST_FIRST_STRATEGY, 100000 + fee},
+		{"branch and bound", feeRate, BRANCH_AND_BOUND_STRATEGY, 100000 + fee},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := SelectCoins(newTestCoins(100000, 200000), 400000, test.feeRate, test.strategy)
+
+			var insufficientFunds *InsufficientFundsError
+			if !errors.As(err, &insufficientFunds) {
+				t.Fatalf("got error %v, want InsufficientFundsError", err)
+			}
+			if insufficientFunds.Shortfall != test.wantShortfall {
+				t.Errorf("got shortfall %d, want %d", insufficientFunds.Shortfall, test.wantShortfall)
+			}
+		})
+	}
+}
+
+func TestSelectCoinsMaxInputs(t *testing.T) {
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		t.Fatalf("cannot get the protocol limits: %s", err)
+	}
+	maxInputs := limits.MaxTxInputs
+
+	for _, strategy := range []CoinSelectionStrategy{LARGEST_FIRST_STRATEGY, MINIMIZE_RING_FETCHES_STRATEGY, BRANCH_AND_BOUND_STRATEGY} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			coins := newTestCoins(repeatValue(1000000, maxInputs+2)...)
+
+			selected, _, err := SelectCoins(coins, int64(maxInputs)*1000000, 0, strategy)
+			if err != nil || len(selected) != maxInputs {
+				t.Errorf("got %d coins and error %v, want %d coins", len(selected), err, maxInputs)
+			}
+
+			_, _, err = SelectCoins(coins, int64(maxInputs+1)*1000000, 0, strategy)
+			var tooManyInputs *TooManyInputsError
+			if !errors.As(err, &tooManyInputs) {
+				t.Fatalf("got error %v, want TooManyInputsError", err)
+			}
+			if tooManyInputs.MaxInputs != maxInputs || tooManyInputs.Shortfall != 1000000 {
+				t.Errorf("got %d inputs short by %d, want %d inputs short by 1000000", tooManyInputs.MaxInputs, tooManyInputs.Shortfall, maxInputs)
+			}
+
+			_, _, err = SelectCoins(coins, int64(maxInputs+3)*1000000, 0, strategy)
+			var insufficientFunds *InsufficientFundsError
+			if !errors.As(err, &insufficientFunds) || insufficientFunds.Shortfall != 1000000 {
+				t.Errorf("got error %v, want a shortfall of 1000000 over all coins", err)
+			}
+		})
+	}
+
+	t.Run("ring groups fall back to the largest coins", func(t *testing.T) {
+		// The group at height 30 is the most valuable, but its coins are too small to reach the target.
+		coins := make([]*Coin, 0, maxInputs+2)
+		for i := 0; i <= maxInputs; i++ {
+			coins = append(coins, newTestCoin(uint8(i), 1000000, 30))
+		}
+		coins = append(coins, newTestCoin(uint8(maxInputs+1), 4000000, 60))
+
+		selected, _, err := SelectCoins(coins, int64(maxInputs+1)*1000000, 0, MINIMIZE_RING_FETCHES_STRATEGY)
+		if err != nil {
+			t.Fatalf("got error %s", err)
+		}
+		if len(selected) > maxInputs || selected[0].Value != 4000000 {
+			t.Errorf("got %d coins starting with %d, want the coin of 4000000 first", len(selected), selected[0].Value)
+		}
+	})
+}
+
+func TestSelectCoinsMinimizeRingFetches(t *testing.T) {
+	// The coins at heights 30 to 32 share a ring group, while each larger coin is in a ring group of its own.
+	coins := []*Coin{
+		newTestCoin(0, 1200000, 3),
+		newTestCoin(1, 1000000, 30),
+		newTestCoin(2, 1200000, 9),
+		newTestCoin(3, 1000000, 31),
+		newTestCoin(4, 1200000, 15),
+		newTestCoin(5, 1000000, 32),
+	}
+
+	ringGroups := func(selected []*Coin) map[int64]bool {
+		groups := make(map[int64]bool)
+		for _, coin := range selected {
+			groups[GetRingBlockHeights(coin.BlockHeight)[0]] = true
+		}
+		return groups
+	}
+
+	selected, _, err := SelectCoins(coins, 2500000, 0, MINIMIZE_RING_FETCHES_STRATEGY)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if groups := ringGroups(selected); len(selected) != 3 || len(groups) != 1 || !groups[30] {
+		t.Errorf("got %d coins from ring groups %v, want 3 coins from the ring group at 30", len(selected), groups)
+	}
+
+	selected, _, err = SelectCoins(coins, 2500000, 0, LARGEST_FIRST_STRATEGY)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if groups := ringGroups(selected); len(groups) != 3 {
+		t.Errorf("got coins from %d ring groups with largest first, want 3", len(groups))
+	}
+}
+
+// newBenchmarkWallet returns a wallet of n unspent coins with distinct ids and values in no particular order.
+func newBenchmarkWallet(n int) *Wallet {
+	w := NewWallet()
+	for i := 0; i < n; i++ {
+		var txHash Txid
+		binary.BigEndian.PutUint32(txHash[:], uint32(i))
+		w.AddCoin(&Coin{ID: CoinID{TxHash: txHash}, Value: int64(i*7919%n+1) * 1000, BlockHeight: 10})
+	}
+
+	return w
+}
+
+func BenchmarkOrderCoinsLargestFirst(b *testing.B) {
+	w := newBenchmarkWallet(10000)
+	for _, bench := range []struct {
+		name  string
+		coins []*Coin
+	}{
+		{"value index", w.CoinsSortedByValue()},
+		{"unsorted", w.UnspentCoins()},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				orderCoinsLargestFirst(bench.coins)
+			}
+		})
+	}
+}
+
+func BenchmarkSelectCoinsLargeWallet(b *testing.B) {
+	w := newBenchmarkWallet(10000)
+	for _, bench := range []struct {
+		name  string
+		coins func() []*Coin
+	}{
+		{"value index", w.CoinsSortedByValue},
+		{"unsorted", w.UnspentCoins},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, err := SelectCoins(bench.coins(), 30000000, 0)
+				if err != nil {
+					b.Fatalf("cannot select: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abesuite/abec/wire"
+)
+
+// Define constants.
+const (
+	SIMULATION_ENDPOINT = "http://simulation.invalid"
+)
+
+// Define the SimulationChain data type.
+// SimulationChain is an in-memory chain that a client from NewSimulationClient talks to instead of a node,
+// so that the whole flow of selecting coins, building, signing and sending a tx can run without a network.
+//
+// A chain is loaded from a fixture, a JSON object such as
+//
+//	{"netID": 2, "relayFee": 0.0001, "feeEstimate": 0.001, "blocks": ["<raw block hex>", ...]}
+//
+// where the fees are in ABEL per kB, as the node reports them, and each block is the hex returned by getblockabe with verbosity 0 from a real node, in height order.
+// Blocks are decoded locally with DecodeAbecBlock, so the coins of a wallet are those its keys own in the
+// fixture blocks. Sent txs are checked to deserialize and are kept in the simulated mempool, and their serial
+// numbers are reported as consumed by getutxoring. They are never mined.
+type SimulationChain struct {
+	mutex         sync.RWMutex
+	netID         byte
+	relayFee      float64
+	feeEstimate   float64
+	blocks        []*simulationBlock
+	blocksByHash  map[string]*simulationBlock
+	mempool       map[string]*simulationMempoolTx
+	mempoolTxids  []string
+	serialNumbers map[string]bool
+}
+
+type SimulationFixture struct {
+	NetID       byte     `json:"netID"`
+	RelayFee    float64  `json:"relayFee"`
+	FeeEstimate float64  `json:"feeEstimate"`
+	Blocks      []string `json:"blocks"`
+}
+
+type simulationBlock struct {
+	raw   Bytes
+	block *AbecBlock
+}
+
+// simulationRPCError is an error the simulation answers with the code abec uses for it, rather than -1.
+type simulationRPCError struct {
+	code    int
+	message string
+}
+
+func (e *simulationRPCError) Error() string {
+	return e.message
+}
+
+type simulationMempoolTx struct {
+	tx       *AbecTx
+	fullSize int64
+}
+
+// Define methods for SimulationChain.
+func NewSimulationChain(netID byte) *SimulationChain {
+	return &SimulationChain{
+		netID:         netID,
+		blocksByHash:  make(map[string]*simulationBlock),
+		mempool:       make(map[string]*simulationMempoolTx),
+		serialNumbers: make(map[string]bool),
+	}
+}
+
+func LoadSimulationChain(r io.Reader) (*SimulationChain, error) {
+	fixture := &SimulationFixture{}
+	err := json.NewDecoder(r).Decode(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("simulation fixture cannot be parsed: %s", err)
+	}
+
+	chain := NewSimulationChain(fixture.NetID)
+	chain.relayFee = fixture.RelayFee
+	chain.feeEstimate = fixture.FeeEstimate
+	for i, blockHex := range fixture.Blocks {
+		raw, err := hex.DecodeString(blockHex)
+		if err != nil {
+			return nil, fmt.Errorf("simulation fixture block %d is not valid hex: %s", i, err)
+		}
+		err = chain.AddBlock(AsBytes(raw))
+		if err != nil {
+			return nil, fmt.Errorf("simulation fixture block %d: %s", i, err)
+		}
+	}
+
+	return chain, nil
+}
+
+func LoadSimulationChainFile(path string) (*SimulationChain, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadSimulationChain(file)
+}
+
+// AddBlock appends a raw block, which must be at the next height.
+func (chain *SimulationChain) AddBlock(raw Bytes) error {
+	block, err := DecodeAbecBlock(raw)
+	if err != nil {
+		return err
+	}
+
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+
+	if len(chain.blocks) > 0 && block.Height != chain.blocks[len(chain.blocks)-1].block.Height+1 {
+		return fmt.Errorf("block height %d does not follow the tip height %d", block.Height, chain.blocks[len(chain.blocks)-1].block.Height)
+	}
+
+	simBlock := &simulationBlock{raw: raw, block: block}
+	chain.blocks = append(chain.blocks, simBlock)
+	chain.blocksByHash[block.BlockHash] = simBlock
+	for _, tx := range block.RawTxs {
+		for _, vin := range tx.Vin {
+			chain.serialNumbers[vin.SerialNumber] = true
+		}
+	}
+
+	return nil
+}
+
+// SetFeeEstimate sets the fee rate in ABEL per kB returned by estimatefee, or disables estimation if it is not positive.
+func (chain *SimulationChain) SetFeeEstimate(feeRatePerKB float64) {
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+
+	chain.feeEstimate = feeRatePerKB
+}
+
+// SentTxs returns the txs in the simulated mempool, in the order they were sent.
+func (chain *SimulationChain) SentTxs() []*AbecTx {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	txs := make([]*AbecTx, 0, len(chain.mempoolTxids))
+	for _, txid := range chain.mempoolTxids {
+		txs = append(txs, chain.mempool[txid].tx)
+	}
+
+	return txs
+}
+
+func (chain *SimulationChain) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonReq struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+		ID     string            `json:"id"`
+	}
+	jsonResp := map[string]interface{}{"id": nil, "result": nil, "error": nil}
+	err = json.Unmarshal(body, &jsonReq)
+	if err != nil {
+		// Like abec, which only parses single requests, answer a batch with a parse error.
+		jsonResp["error"] = &AbecJSONRPCError{Code: -32700, Message: fmt.Sprintf("failed to parse request: %s", err)}
+	} else {
+		jsonResp["id"] = jsonReq.ID
+		result, err := chain.handle(jsonReq.Method, jsonReq.Params)
+		if err != nil {
+			code := -1
+			var simErr *simulationRPCError
+			if errors.As(err, &simErr) {
+				code = simErr.code
+			}
+			jsonResp["error"] = &AbecJSONRPCError{Code: code, Message: err.Error()}
+		} else {
+			jsonResp["result"] = result
+		}
+	}
+
+	respBody, err := json.Marshal(jsonResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+func (chain *SimulationChain) handle(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "getinfo":
+		return chain.getInfo(), nil
+	case "getbestblockhash":
+		return chain.getBestBlockHash()
+	case "getblockhash":
+		var height int64
+		err := unmarshalSimulationParam(params, 0, &height)
+		if err != nil {
+			return nil, err
+		}
+		return chain.getBlockHash(height)
+	case "getblockabe":
+		var hash string
+		verbosity := 1
+		err := unmarshalSimulationParam(params, 0, &hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(params) > 1 {
+			err = unmarshalSimulationParam(params, 1, &verbosity)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return chain.getBlock(hash, verbosity)
+	case "getrawtransaction":
+		var txid string
+		verbose := false
+		err := unmarshalSimulationParam(params, 0, &txid)
+		if err != nil {
+			return nil, err
+		}
+		if len(params) > 1 {
+			err = unmarshalSimulationParam(params, 1, &verbose)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return chain.getRawTx(txid, verbose)
+	case "getrawmempool":
+		return chain.getMempool(), nil
+	case "estimatefee":
+		return chain.estimateFee()
+	case "getutxoring":
+		ring := &AbecUTXORing{}
+		err := unmarshalSimulationParam(params, 0, ring)
+		if err != nil {
+			return nil, err
+		}
+		return chain.getUTXORing(ring)
+	case "sendrawtransaction", "sendrawtransactionabe":
+		var txHex string
+		err := unmarshalSimulationParam(params, 0, &txHex)
+		if err != nil {
+			return nil, err
+		}
+		return chain.sendRawTx(txHex)
+	default:
+		return nil, fmt.Errorf("method %s is not supported by the simulation", method)
+	}
+}
+
+func (chain *SimulationChain) getInfo() *AbecChainInfo {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	return &AbecChainInfo{
+		NumBlocks: chain.tipHeightLocked(),
+		IsTestnet: Network(chain.netID) == TESTNET_NETWORK,
+		RelayFee:  chain.relayFee,
+		NetID:     chain.netID,
+	}
+}
+
+func (chain *SimulationChain) getBlockHash(height int64) (string, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	simBlock := chain.blockAtHeightLocked(height)
+	if simBlock == nil {
+		return "", &simulationRPCError{code: RPC_ERROR_OUT_OF_RANGE, message: "Block number out of range"}
+	}
+
+	return simBlock.block.BlockHash, nil
+}
+
+func (chain *SimulationChain) getBestBlockHash() (string, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	if len(chain.blocks) == 0 {
+		return "", fmt.Errorf("chain has no blocks")
+	}
+
+	return chain.blocks[len(chain.blocks)-1].block.BlockHash, nil
+}
+
+func (chain *SimulationChain) getBlock(hash string, verbosity int) (interface{}, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	simBlock, ok := chain.blocksByHash[hash]
+	if !ok {
+		return nil, fmt.Errorf("Block not found")
+	}
+	if verbosity == 0 {
+		return simBlock.raw.HexString(), nil
+	}
+
+	block := *simBlock.block
+	block.Confirmations = chain.tipHeightLocked() - block.Height + 1
+	if next := chain.blockAtHeightLocked(block.Height + 1); next != nil {
+		block.NextBlockHash = next.block.BlockHash
+	}
+	if verbosity == 1 {
+		block.RawTxs = nil
+	}
+
+	return &block, nil
+}
+
+func (chain *SimulationChain) getRawTx(txid string, verbose bool) (interface{}, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	tx := chain.findTxLocked(txid)
+	if tx == nil {
+		return nil, &simulationRPCError{code: RPC_ERROR_NO_TX_INFO, message: fmt.Sprintf("No information available about transaction %s", txid)}
+	}
+	if !verbose {
+		return tx.Hex, nil
+	}
+
+	return tx, nil
+}
+
+func (chain *SimulationChain) getMempool() AbecMempool {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	mempool := make(AbecMempool)
+	for txid, entry := range chain.mempool {
+		value := mempool[txid]
+		value.Size = entry.tx.Size
+		value.FullSize = entry.fullSize
+		value.Fee = entry.tx.Fee
+		value.Time = entry.tx.Time
+		value.Height = chain.tipHeightLocked()
+		mempool[txid] = value
+	}
+
+	return mempool
+}
+
+func (chain *SimulationChain) estimateFee() (float64, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	if chain.feeEstimate <= 0 {
+		return 0, fmt.Errorf("Fee estimation disabled")
+	}
+
+	return chain.feeEstimate, nil
+}
+
+func (chain *SimulationChain) getUTXORing(ring *AbecUTXORing) (*AbecUTXORingEntry, error) {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+
+	if len(ring.BlockHashes) == 0 {
+		return nil, fmt.Errorf("ring has no block hashes")
+	}
+	simBlock, ok := chain.blocksByHash[ring.BlockHashes[0]]
+	if !ok {
+		return nil, fmt.Errorf("No information available about ring")
+	}
+
+	// Serial numbers are unique, so reporting every consumed one answers for any coin of the ring.
+	serialNumbers := make([]string, 0, len(chain.serialNumbers))
+	for serialNumber := range chain.serialNumbers {
+		serialNumbers = append(serialNumbers, serialNumber)
+	}
+	sort.Strings(serialNumbers)
+
+	return &AbecUTXORingEntry{
+		RingBlockHeight:       simBlock.block.Height,
+		ConsumedSerialNumbers: serialNumbers,
+	}, nil
+}
+
+func (chain *SimulationChain) sendRawTx(txHex string) (string, error) {
+	data, err := hex.DecodeString(txHex)
+	if err != nil {
+		return "", fmt.Errorf("tx is not valid hex: %s", err)
+	}
+
+	msgTx := &wire.MsgTxAbe{}
+	err = msgTx.DeserializeFull(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("tx cannot be deserialized: %s", err)
+	}
+
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+
+	tx, err := decodeAbecTx(msgTx, &AbecBlock{})
+	if err != nil {
+		return "", err
+	}
+	if chain.findTxLocked(tx.TxID) != nil {
+		return "", &simulationRPCError{code: RPC_ERROR_TX_REJECTED, message: fmt.Sprintf("TX rejected: already have transaction %s", tx.TxID)}
+	}
+	for _, vin := range tx.Vin {
+		if chain.serialNumbers[vin.SerialNumber] {
+			return "", &simulationRPCError{code: RPC_ERROR_TX_REJECTED,
+				message: fmt.Sprintf("TX rejected: serial number %s already spent by transaction in the memory pool", vin.SerialNumber)}
+		}
+	}
+
+	for _, vin := range tx.Vin {
+		chain.serialNumbers[vin.SerialNumber] = true
+	}
+	tx.FullSize = int64(len(data))
+	chain.mempool[tx.TxID] = &simulationMempoolTx{tx: tx, fullSize: tx.FullSize}
+	chain.mempoolTxids = append(chain.mempoolTxids, tx.TxID)
+
+	return tx.TxID, nil
+}
+
+func (chain *SimulationChain) tipHeightLocked() int64 {
+	if len(chain.blocks) == 0 {
+		return -1
+	}
+
+	return chain.blocks[len(chain.blocks)-1].block.Height
+}
+
+func (chain *SimulationChain) blockAtHeightLocked(height int64) *simulationBlock {
+	if len(chain.blocks) == 0 {
+		return nil
+	}
+
+	index := height - chain.blocks[0].block.Height
+	if index < 0 || index >= int64(len(chain.blocks)) {
+		return nil
+	}
+
+	return chain.blocks[index]
+}
+
+func (chain *SimulationChain) findTxLocked(txid string) *AbecTx {
+	txid = strings.ToLower(txid)
+	if entry, ok := chain.mempool[txid]; ok {
+		return entry.tx
+	}
+
+	tipHeight := chain.tipHeightLocked()
+	for _, simBlock := range chain.blocks {
+		for _, tx := range simBlock.block.RawTxs {
+			if tx.TxID == txid {
+				confirmedTx := *tx
+				confirmedTx.Confirmations = tipHeight - simBlock.block.Height + 1
+				return &confirmedTx
+			}
+		}
+	}
+
+	return nil
+}
+
+// Define util functions.
+// NewSimulationClient returns a client that is served by chain instead of a node. Options that configure the
+// HTTP transport, such as WithDialTimeout, have no effect on it.
+func NewSimulationClient(chain *SimulationChain, options ...AbecRPCClientOption) *AbecRPCClient {
+	client := NewAbecRPCClient(SIMULATION_ENDPOINT, "", "", options...)
+
+	// Send requests to the chain through a copy of the configured http client, keeping its other settings.
+	httpClient := *client.httpClient
+	httpClient.Transport = chain
+	client.httpClient = &httpClient
+	client.ownsTransport = false
+
+	return client
+}
+
+func unmarshalSimulationParam(params []json.RawMessage, index int, value interface{}) error {
+	if index >= len(params) {
+		return fmt.Errorf("param %d is missing", index)
+	}
+
+	err := json.Unmarshal(params[index], value)
+	if err != nil {
+		return fmt.Errorf("param %d is not valid: %s", index, err)
+	}
+
+	return nil
+}
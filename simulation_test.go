@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSimulationChainFile(t *testing.T) {
+	chain, err := LoadSimulationChainFile(filepath.Join("testdata", "simulation_chain.json"))
+	if err != nil {
+		t.Fatalf("cannot load the fixture: %s", err)
+	}
+	client := NewSimulationClient(chain)
+
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		t.Fatalf("cannot get the chain info: %s", err)
+	}
+	if chainInfo.NumBlocks != 2 || chainInfo.RelayFeeNeutrinoPerKB() != 1000 {
+		t.Errorf("got tip height %d and relay fee %d neutrino per kB, want 2 and 1000", chainInfo.NumBlocks, chainInfo.RelayFeeNeutrinoPerKB())
+	}
+
+	wantHashes := []string{
+		"8b628147fbf5a4194ea061925c4b0b9098bac87ec0a468cf7068395f1efebb4e",
+		"ed0ae9df3400f8b746b8cae272d5a1c37dc614bec76ac353a2df2efad5348532",
+		"cf64985d840265e6ae97e667d223a03d0f6868958a73ec2453b74d53a9b5ee53",
+	}
+	for height, want := range wantHashes {
+		_, block, err := client.GetBlockByHeight(int64(height))
+		if err != nil {
+			t.Fatalf("cannot get block %d: %s", height, err)
+		}
+		if block.BlockHash != want {
+			t.Errorf("got block %d hash %s, want %s", height, block.BlockHash, want)
+		}
+		if height > 0 && block.PrevBlockHash != wantHashes[height-1] {
+			t.Errorf("block %d does not follow block %d", height, height-1)
+		}
+	}
+
+	// The fixture estimates 0.001 ABEL per kB, which is 10000 neutrino per kB.
+	txSize, err := estimateTxSize(1, 2, 0)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+	fee, err := client.GetEstimatedTxFee(6)
+	if err != nil {
+		t.Fatalf("cannot estimate the fee: %s", err)
+	}
+	if want := ComputeFee(txSize, 10000); fee != want {
+		t.Errorf("got fee %d, want %d", fee, want)
+	}
+
+	chain.SetFeeEstimate(0.002)
+	fee, err = client.GetEstimatedTxFee(6)
+	if err != nil {
+		t.Fatalf("cannot estimate the fee: %s", err)
+	}
+	if want := ComputeFee(txSize, 20000); fee != want {
+		t.Errorf("got fee %d after SetFeeEstimate(0.002), want %d", fee, want)
+	}
+}
+
+func TestNewSimulationClientCopiesHTTPClient(t *testing.T) {
+	chain, err := LoadSimulationChainFile(filepath.Join("testdata", "simulation_chain.json"))
+	if err != nil {
+		t.Fatalf("cannot load the fixture: %s", err)
+	}
+	httpClient := &http.Client{Timeout: time.Minute}
+
+	client := NewSimulationClient(chain, WithHTTPClient(httpClient))
+	if httpClient.Transport != nil {
+		t.Errorf("NewSimulationClient set the transport of the given http client")
+	}
+	if client.httpClient.Timeout != time.Minute {
+		t.Errorf("got timeout %s, want the 1m of the given http client", client.httpClient.Timeout)
+	}
+
+	_, _, err = client.GetChainInfo()
+	if err != nil {
+		t.Errorf("cannot get the chain info from the simulation: %s", err)
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Define constants.
+const (
+	DEFAULT_SCAN_STATS_WINDOW = time.Minute
+)
+
+// Define the ScanStats data type.
+// ScanStats counts the blocks scanned and coins found by the scans of a FingerprintIndex it is attached to,
+// in total and over a rolling window. It is safe to update from concurrent scans and to read at any time.
+type ScanStats struct {
+	mutex         sync.Mutex
+	window        time.Duration
+	clock         Clock
+	startTime     time.Time
+	blocksScanned int64
+	coinsFound    int64
+	samples       []scanSample
+}
+
+type scanSample struct {
+	time   time.Time
+	blocks int64
+	coins  int64
+}
+
+// Define methods for ScanStats.
+func NewScanStats(window time.Duration, clock ...Clock) *ScanStats {
+	if window <= 0 {
+		window = DEFAULT_SCAN_STATS_WINDOW
+	}
+	if len(clock) == 0 {
+		clock = append(clock, SystemClock{})
+	}
+
+	return &ScanStats{
+		window:    window,
+		clock:     clock[0],
+		startTime: clock[0].Now(),
+		samples:   make([]scanSample, 0),
+	}
+}
+
+func (stats *ScanStats) Record(blocks int64, coins int64) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	now := stats.clock.Now()
+	stats.blocksScanned += blocks
+	stats.coinsFound += coins
+	stats.samples = append(stats.samples, scanSample{time: now, blocks: blocks, coins: coins})
+	stats.pruneLocked(now)
+}
+
+func (stats *ScanStats) BlocksScanned() int64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.blocksScanned
+}
+
+func (stats *ScanStats) CoinsFound() int64 {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.coinsFound
+}
+
+// Rates returns the blocks scanned and coins found per second over the window, or over the time since the
+// stats were created if that is shorter.
+func (stats *ScanStats) Rates() (blocksPerSecond float64, coinsPerSecond float64) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	now := stats.clock.Now()
+	stats.pruneLocked(now)
+
+	span := stats.window
+	if elapsed := now.Sub(stats.startTime); elapsed < span {
+		span = elapsed
+	}
+	if span <= 0 {
+		return 0, 0
+	}
+
+	blocks := int64(0)
+	coins := int64(0)
+	for _, sample := range stats.samples {
+		blocks += sample.blocks
+		coins += sample.coins
+	}
+
+	return float64(blocks) / span.Seconds(), float64(coins) / span.Seconds()
+}
+
+func (stats *ScanStats) pruneLocked(now time.Time) {
+	cutoff := now.Add(-stats.window)
+	i := 0
+	for i < len(stats.samples) && !stats.samples[i].time.After(cutoff) {
+		i++
+	}
+	stats.samples = stats.samples[i:]
+}
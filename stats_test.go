@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanStats(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	stats := NewScanStats(10*time.Second, clock)
+
+	// Each step advances the clock, records a scan if any, and checks the totals and the rates.
+	tests := []struct {
+		name                string
+		advance             time.Duration
+		blocks              int64
+		coins               int64
+		wantBlocksScanned   int64
+		wantCoinsFound      int64
+		wantBlocksPerSecond float64
+		wantCoinsPerSecond  float64
+	}{
+		{"no time elapsed", 0, 0, 0, 0, 0, 0, 0},
+		{"shorter than the window", 2 * time.Second, 4, 1, 4, 1, 2, 0.5},
+		{"second scan", 3 * time.Second, 6, 1, 10, 2, 2, 0.4},
+		{"full window", 6 * time.Second, 0, 0, 10, 2, 1, 0.2},
+		{"scans left the window", 4 * time.Second, 0, 0, 10, 2, 0, 0},
+		{"scan after the window moved", time.Second, 5, 5, 15, 7, 0.5, 0.5},
+	}
+	for _, test := range tests {
+		clock.Advance(test.advance)
+		if test.blocks > 0 || test.coins > 0 {
+			stats.Record(test.blocks, test.coins)
+		}
+
+		if stats.BlocksScanned() != test.wantBlocksScanned || stats.CoinsFound() != test.wantCoinsFound {
+			t.Errorf("%s: got %d blocks scanned and %d coins found, want %d and %d", test.name,
+				stats.BlocksScanned(), stats.CoinsFound(), test.wantBlocksScanned, test.wantCoinsFound)
+		}
+		blocksPerSecond, coinsPerSecond := stats.Rates()
+		if blocksPerSecond != test.wantBlocksPerSecond || coinsPerSecond != test.wantCoinsPerSecond {
+			t.Errorf("%s: got %v blocks and %v coins per second, want %v and %v", test.name,
+				blocksPerSecond, coinsPerSecond, test.wantBlocksPerSecond, test.wantCoinsPerSecond)
+		}
+	}
+}
+
+func TestScanStatsRecordsIndexScans(t *testing.T) {
+	keys := newTestKeys(t)
+	client := NewSimulationClient(newTestChain(t, 2, map[int64][]testPayment{1: {{keys, 5000}, {keys, 7000}}}))
+	stats := NewScanStats(time.Minute, NewFakeClock(time.Unix(1700000000, 0)))
+	index := NewFingerprintIndex()
+	index.Add(keys)
+	index.SetScanStats(stats)
+
+	for height := int64(0); height < 2; height++ {
+		blockBytes, err := client.GetBlockBytesByHeight(height)
+		if err != nil {
+			t.Fatalf("cannot get block %d: %s", height, err)
+		}
+		block, err := DecodeAbecBlock(blockBytes)
+		if err != nil {
+			t.Fatalf("cannot decode block %d: %s", height, err)
+		}
+		_, err = ScanBlockMultiWallet(block, index)
+		if err != nil {
+			t.Fatalf("cannot scan block %d: %s", height, err)
+		}
+	}
+
+	if stats.BlocksScanned() != 2 || stats.CoinsFound() != 2 {
+		t.Errorf("got %d blocks scanned and %d coins found, want 2 and 2", stats.BlocksScanned(), stats.CoinsFound())
+	}
+}
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Define constants.
+const (
+	HEX_STREAM_BUFFER_SIZE = 64 * 1024
+)
+
+// callForHexStream decodes a hex string result directly into w while it is being received,
+// so that neither the full hex string nor the full decoded result has to be held in memory.
+// Failed attempts are retried like other calls, as long as nothing has been written to w yet.
+func (client *AbecRPCClient) callForHexStream(ctx context.Context, method string, params []interface{}, w io.Writer) (int64, error) {
+	var written int64
+	err := client.retry(ctx, "abec."+method, func() (bool, error) {
+		var retryable bool
+		var err error
+		written, retryable, err = client.callForHexStreamOnce(ctx, method, params, w)
+		return retryable, err
+	})
+
+	return written, err
+}
+
+// callForHexStreamOnce makes a single streaming call and reports whether its error is transient and the call
+// may be retried. A call that has already written to w is never retried, since w cannot be rewound.
+func (client *AbecRPCClient) callForHexStreamOnce(ctx context.Context, method string, params []interface{}, w io.Writer) (int64, bool, error) {
+	id, resp, err := client.send(ctx, method, params)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		LOG.debug("Response(%s): ERROR(%s)\n", id, resp.Status)
+		return 0, false, fmt.Errorf("%w: abec.%s: %s", ErrUnauthorized, method, resp.Status)
+	}
+	retryable := resp.StatusCode >= http.StatusInternalServerError
+
+	written, rawError, err := decodeHexStreamResponse(resp.Body, w)
+	if err != nil {
+		LOG.debug("Response(%s): ERROR(%s)\n", id, err)
+		if retryable && written == 0 {
+			return 0, true, fmt.Errorf("abec.%s: %s", method, resp.Status)
+		}
+		return written, false, err
+	}
+	LOG.debug("Response(%s): streamed %d bytes\n", id, written)
+
+	if isJSONRPCError(rawError) {
+		return written, false, newJSONRPCError(method, rawError)
+	}
+
+	return written, false, nil
+}
+
+func decodeHexStreamResponse(body io.Reader, w io.Writer) (int64, json.RawMessage, error) {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return 0, nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return 0, nil, fmt.Errorf("response is not a json object")
+	}
+
+	var rawError json.RawMessage
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if key, _ := token.(string); key != "result" {
+			var value json.RawMessage
+			err = decoder.Decode(&value)
+			if err != nil {
+				return 0, nil, err
+			}
+			if key == "error" {
+				rawError = value
+			}
+			continue
+		}
+
+		// Take over the stream from the decoder to decode the result string incrementally.
+		reader := bufio.NewReaderSize(io.MultiReader(decoder.Buffered(), body), HEX_STREAM_BUFFER_SIZE)
+		written, err := decodeHexStreamValue(reader, w)
+		if err != nil {
+			return written, nil, err
+		}
+
+		// The remaining members are small, so parse them as an object of their own.
+		rest, err := io.ReadAll(reader)
+		if err != nil {
+			return written, nil, err
+		}
+		rest = bytes.TrimSpace(rest)
+		if len(rest) > 0 && rest[0] == ',' {
+			tail := &AbecJSONRPCResponse{}
+			err = json.Unmarshal(append([]byte{'{'}, rest[1:]...), tail)
+			if err != nil {
+				return written, nil, err
+			}
+			if isJSONRPCError(tail.Error) {
+				rawError = tail.Error
+			}
+		}
+
+		return written, rawError, nil
+	}
+
+	return 0, rawError, nil
+}
+
+func decodeHexStreamValue(reader *bufio.Reader, w io.Writer) (int64, error) {
+	err := skipJSONSeparator(reader, ':')
+	if err != nil {
+		return 0, err
+	}
+
+	first, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first == 'n' {
+		null := make([]byte, 3)
+		_, err = io.ReadFull(reader, null)
+		if err != nil || string(null) != "ull" {
+			return 0, fmt.Errorf("result is not a hex string")
+		}
+		return 0, nil
+	}
+	if first != '"' {
+		return 0, fmt.Errorf("result is not a hex string")
+	}
+
+	written := int64(0)
+	pending := make([]byte, 0, 1)
+	decoded := make([]byte, HEX_STREAM_BUFFER_SIZE/2+1)
+	for {
+		chunk, err := reader.ReadSlice('"')
+		done := err == nil
+		if err != nil && err != bufio.ErrBufferFull {
+			return written, err
+		}
+		if done {
+			chunk = chunk[:len(chunk)-1]
+		}
+
+		// Carry an odd trailing hex digit over to the next chunk.
+		data := append(pending, chunk...)
+		even := len(data) - len(data)%2
+		n, err := hex.Decode(decoded, data[:even])
+		if err != nil {
+			return written, err
+		}
+		pending = append(pending[:0], data[even:]...)
+
+		m, err := w.Write(decoded[:n])
+		written += int64(m)
+		if err != nil {
+			return written, err
+		}
+
+		if done {
+			if len(pending) > 0 {
+				return written, fmt.Errorf("result hex string has odd length")
+			}
+			return written, nil
+		}
+	}
+}
+
+func skipJSONSeparator(reader *bufio.Reader, separator byte) error {
+	found := false
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == separator && !found:
+			found = true
+			continue
+		case found:
+			return reader.UnreadByte()
+		default:
+			return fmt.Errorf("unexpected character %q in response", c)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newStreamServer returns a node that answers each request with the status and body given for its attempt,
+// and counts the attempts.
+func newStreamServer(t *testing.T, attempts *int32, statuses []int, bodies []string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(attempts, 1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+		fmt.Fprint(w, bodies[n])
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetBlockBytesToRetries(t *testing.T) {
+	var attempts int32
+	server := newStreamServer(t, &attempts,
+		[]int{http.StatusServiceUnavailable, http.StatusOK},
+		[]string{"node is starting", `{"result":"0a0b0c","error":null,"id":"1"}`})
+	client := NewAbecRPCClient(server.URL, "", "", WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+
+	var buf bytes.Buffer
+	written, err := client.GetBlockBytesTo(strings.Repeat("00", 32), &buf)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if written != 3 || !bytes.Equal(buf.Bytes(), []byte{0x0a, 0x0b, 0x0c}) {
+		t.Errorf("got %d bytes %x, want 0a0b0c", written, buf.Bytes())
+	}
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("made %d attempts, want 2", atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestGetBlockBytesToUnauthorized(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		var attempts int32
+		server := newStreamServer(t, &attempts, []int{status}, []string{""})
+		client := NewAbecRPCClient(server.URL, "", "", WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+
+		var buf bytes.Buffer
+		_, err := client.GetBlockBytesTo(strings.Repeat("00", 32), &buf)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("got error %v for status %d, want ErrUnauthorized", err, status)
+		}
+		if atomic.LoadInt32(&attempts) != 1 {
+			t.Errorf("made %d attempts for status %d, want 1", atomic.LoadInt32(&attempts), status)
+		}
+	}
+}
+
+func TestGetBlockBytesToMatchesHexResult(t *testing.T) {
+	t.Run("simulation chain", func(t *testing.T) {
+		client := NewSimulationClient(newTestChain(t, 2, nil))
+		for height := int64(0); height < 2; height++ {
+			_, hash, err := client.GetBlockHash(height)
+			if err != nil {
+				t.Fatalf("cannot get the hash of block %d: %s", height, err)
+			}
+
+			var data string
+			_, result, err := AbecRPCClientCallForResult(client, &data, "getblockabe", []interface{}{*hash, 0})
+			if err != nil {
+				t.Fatalf("cannot get block %d as a hex string: %s", height, err)
+			}
+			want := MakeBytesFromHexString(*result)
+			if len(want) == 0 {
+				t.Fatalf("block %d is empty", height)
+			}
+
+			var buf bytes.Buffer
+			written, err := client.GetBlockBytesTo(*hash, &buf)
+			if err != nil {
+				t.Fatalf("got error %s for block %d", err, height)
+			}
+			if written != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("block %d streamed %d bytes that differ from the %d bytes of the hex result", height, written, len(want))
+			}
+
+			blockBytes, err := client.GetBlockBytes(*hash)
+			if err != nil || !bytes.Equal(blockBytes, want) {
+				t.Errorf("GetBlockBytes of block %d differs from the hex result, error %v", height, err)
+			}
+		}
+	})
+
+	t.Run("result larger than the buffer", func(t *testing.T) {
+		want := make([]byte, 3*HEX_STREAM_BUFFER_SIZE+1)
+		for i := range want {
+			want[i] = byte(i * 31)
+		}
+
+		var attempts int32
+		body := fmt.Sprintf(`{"id":"1","result":"%x","error":null}`, want)
+		server := newStreamServer(t, &attempts, []int{http.StatusOK}, []string{body})
+		client := NewAbecRPCClient(server.URL, "", "")
+
+		var buf bytes.Buffer
+		written, err := client.GetBlockBytesTo(strings.Repeat("00", 32), &buf)
+		if err != nil {
+			t.Fatalf("got error %s", err)
+		}
+		if written != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("streamed %d bytes that differ from the %d bytes sent", written, len(want))
+		}
+	})
+
+	for _, test := range []struct {
+		name string
+		body string
+	}{
+		{"invalid hex", `{"result":"0a0bzz","error":null,"id":"1"}`},
+		{"odd length", `{"result":"0a0b0","error":null,"id":"1"}`},
+		{"error after the result", `{"result":"","error":{"code":-5,"message":"Block not found"},"id":"1"}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			server := newStreamServer(t, &attempts, []int{http.StatusOK}, []string{test.body})
+			client := NewAbecRPCClient(server.URL, "", "")
+
+			var buf bytes.Buffer
+			_, err := client.GetBlockBytesTo(strings.Repeat("00", 32), &buf)
+			if err == nil {
+				t.Errorf("got no error, want one")
+			}
+		})
+	}
+}
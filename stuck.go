@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+)
+
+// Define the StuckTxReport data type.
+type StuckTxReport struct {
+	Txid               Txid
+	InMempool          bool
+	Confirmations      int64
+	TxSize             int64
+	FeeRatePerKB       int64
+	TargetFeeRatePerKB int64
+	RecommendedFee     int64
+	RecommendedBump    int64
+}
+
+// Define methods for StuckTxReport.
+// IsUnderpriced reports whether the tx is waiting in the mempool at a fee rate below the current estimate.
+func (report *StuckTxReport) IsUnderpriced() bool {
+	return report.InMempool && report.FeeRatePerKB < report.TargetFeeRatePerKB
+}
+
+// IsDropped reports whether the tx is neither in the mempool nor known to the node, so it has to be sent again.
+func (report *StuckTxReport) IsDropped() bool {
+	return !report.InMempool && report.Confirmations == 0
+}
+
+// Define util functions.
+// DiagnoseStuckTx checks where a broadcast tx stands and recommends the fee to rebuild it with.
+//
+// The fee rate of a tx in the mempool is taken from its mempool entry, measured like the node does by the
+// tx size without witnesses. Otherwise it is computed from txDesc and its estimated size. RecommendedFee is
+// the fee at currentFeeRatePerKB for the same size, and RecommendedBump is how much it exceeds txDesc.TxFee.
+func DiagnoseStuckTx(client *AbecRPCClient, txid Txid, txDesc *TxDesc, currentFeeRatePerKB int64) (*StuckTxReport, error) {
+	report := &StuckTxReport{
+		Txid:               txid,
+		TargetFeeRatePerKB: currentFeeRatePerKB,
+	}
+
+	_, mempool, err := client.GetMempool()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := (*mempool)[txid.String()]
+	if ok && entry.Size > 0 {
+		report.InMempool = true
+		report.TxSize = entry.Size
+		report.FeeRatePerKB = mempoolFeeRate(AbelToNeutrino(entry.Fee), entry.Size)
+	} else {
+		_, tx, err := client.GetRawTx(txid.String())
+		if err == nil {
+			report.Confirmations = tx.Confirmations
+		} else if !isTxNotFound(err) {
+			return nil, err
+		}
+
+		report.TxSize, err = EstimateTxSize(txDesc)
+		if err != nil {
+			return nil, err
+		}
+		report.FeeRatePerKB = mempoolFeeRate(txDesc.TxFee, report.TxSize)
+	}
+
+	if report.Confirmations == 0 {
+		report.RecommendedFee = ComputeFee(report.TxSize, currentFeeRatePerKB)
+		if report.RecommendedFee > txDesc.TxFee {
+			report.RecommendedBump = report.RecommendedFee - txDesc.TxFee
+		}
+	}
+
+	return report, nil
+}
+
+// isTxNotFound reports whether err is the node's answer to a lookup of a tx it does not know.
+func isTxNotFound(err error) bool {
+	var rpcErr *AbecRPCError
+	return errors.As(err, &rpcErr) && rpcErr.IsTxNotFound()
+}
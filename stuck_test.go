@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDiagnoseStuckTxLookupErrors(t *testing.T) {
+	txDesc := NewTxDesc([]*TxInDesc{NewTxInDesc(nil, 5000000)}, []*TxOutDesc{NewTxOutDesc(nil, 4000000)}, 1000, nil)
+
+	tests := []struct {
+		name              string
+		tx                *AbecTx
+		lookupErr         *AbecJSONRPCError
+		wantErr           bool
+		wantConfirmations int64
+		wantDropped       bool
+	}{
+		{"confirmed", &AbecTx{Confirmations: 3}, nil, false, 3, false},
+		{"unknown to the node", nil, &AbecJSONRPCError{Code: RPC_ERROR_NO_TX_INFO, Message: "No information available about transaction"}, false, 0, true},
+		{"lookup failed", nil, &AbecJSONRPCError{Code: -1, Message: "database is closed"}, true, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newMockNode(t, map[string]mockMethod{
+				"getrawmempool": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+					return AbecMempool{}, nil
+				},
+				"getrawtransaction": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+					if test.lookupErr != nil {
+						return nil, test.lookupErr
+					}
+					return test.tx, nil
+				},
+			})
+
+			report, err := DiagnoseStuckTx(client, Txid{1}, txDesc, 10000)
+			if test.wantErr {
+				var rpcErr *AbecRPCError
+				if !errors.As(err, &rpcErr) || rpcErr.Code != test.lookupErr.Code {
+					t.Errorf("got error %v, want the lookup error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if report.Confirmations != test.wantConfirmations {
+				t.Errorf("got %d confirmations, want %d", report.Confirmations, test.wantConfirmations)
+			}
+			if report.IsDropped() != test.wantDropped {
+				t.Errorf("got dropped %t, want %t", report.IsDropped(), test.wantDropped)
+			}
+		})
+	}
+}
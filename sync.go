@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+)
+
+// Define the SyncPlan data type.
+// SyncPlan lists how to bring a wallet from its last-known block to the node's tip. The blocks in Revert are
+// the wallet's blocks that left the main chain, from the highest down, to be undone with Wallet.RevertBlock.
+// The heights from ApplyFrom to TipHeight are then to be scanned and applied in order.
+type SyncPlan struct {
+	CommonAncestorHeight int64
+	CommonAncestorHash   string
+	Revert               []*SyncBlock
+	ApplyFrom            int64
+	TipHeight            int64
+	TipHash              string
+}
+
+type SyncBlock struct {
+	Height int64
+	Hash   string
+}
+
+// Define methods for SyncPlan.
+func (plan *SyncPlan) IsReorg() bool {
+	return len(plan.Revert) > 0
+}
+
+func (plan *SyncPlan) ApplyHeights() []int64 {
+	heights := make([]int64, 0)
+	for height := plan.ApplyFrom; height <= plan.TipHeight; height++ {
+		heights = append(heights, height)
+	}
+
+	return heights
+}
+
+// Define methods for AbecRPCClient.
+// SyncPlan finds the common ancestor of the wallet's last-known block and the node's main chain by walking back
+// from the last-known block through its parents, which the node keeps even after they leave the main chain.
+// A wallet that has not synced any block passes an empty hash and a height of -1.
+func (client *AbecRPCClient) SyncPlan(lastKnownHash string, lastKnownHeight int64) (*SyncPlan, error) {
+	// Read the tip hash first and then its height, so that both are of the same block even if a block is
+	// connected in between.
+	_, tipHash, err := client.GetBestBlockHash()
+	if err != nil {
+		return nil, err
+	}
+	_, tip, err := client.GetBlock(*tipHash)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SyncPlan{
+		CommonAncestorHeight: -1,
+		Revert:               make([]*SyncBlock, 0),
+		TipHeight:            tip.Height,
+		TipHash:              *tipHash,
+	}
+
+	hash := lastKnownHash
+	height := lastKnownHeight
+	for height >= 0 {
+		if height <= plan.TipHeight {
+			_, mainChainHash, err := client.GetBlockHash(height)
+			if err != nil {
+				return nil, err
+			}
+			if *mainChainHash == hash {
+				plan.CommonAncestorHeight = height
+				plan.CommonAncestorHash = hash
+				break
+			}
+		}
+
+		plan.Revert = append(plan.Revert, &SyncBlock{Height: height, Hash: hash})
+		if height == 0 {
+			break
+		}
+
+		_, block, err := client.GetBlock(hash)
+		if err != nil {
+			return nil, fmt.Errorf("block %s at height %d that left the main chain cannot be fetched: %s", hash, height, err)
+		}
+		if block.Height != height {
+			return nil, fmt.Errorf("block %s is at height %d, not %d", hash, block.Height, height)
+		}
+		hash = block.PrevBlockHash
+		height--
+	}
+
+	if plan.CommonAncestorHeight < 0 && lastKnownHeight >= 0 {
+		return nil, fmt.Errorf("block %s at height %d has no common ancestor with the main chain", lastKnownHash, lastKnownHeight)
+	}
+	plan.ApplyFrom = plan.CommonAncestorHeight + 1
+
+	return plan, nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// newSyncNode returns a client of a node whose main chain is the blocks with the given labels, one per height
+// from 0, and which also keeps the stale blocks with their parents.
+func newSyncNode(t *testing.T, mainChain []string, stale map[string]*AbecBlock) *AbecRPCClient {
+	t.Helper()
+
+	blocks := make(map[string]*AbecBlock)
+	for hash, block := range stale {
+		blocks[hash] = block
+	}
+	for height, label := range mainChain {
+		block := &AbecBlock{Height: int64(height), BlockHash: testBlockHash(label)}
+		if height > 0 {
+			block.PrevBlockHash = testBlockHash(mainChain[height-1])
+		}
+		blocks[block.BlockHash] = block
+	}
+
+	return newMockNode(t, map[string]mockMethod{
+		"getbestblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			return testBlockHash(mainChain[len(mainChain)-1]), nil
+		},
+		"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var height int
+			json.Unmarshal(params[0], &height)
+			if height < 0 || height >= len(mainChain) {
+				return nil, &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+			}
+			return testBlockHash(mainChain[height]), nil
+		},
+		"getblockabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+			block, ok := blocks[hash]
+			if !ok {
+				return nil, &AbecJSONRPCError{Code: -5, Message: "Block not found"}
+			}
+			return block, nil
+		},
+	})
+}
+
+func TestSyncPlanExtension(t *testing.T) {
+	client := NewSimulationClient(newTestChain(t, 5, nil))
+	_, lastKnownHash, err := client.GetBlockHash(2)
+	if err != nil {
+		t.Fatalf("cannot get block 2: %s", err)
+	}
+	_, tipHash, err := client.GetBlockHash(4)
+	if err != nil {
+		t.Fatalf("cannot get block 4: %s", err)
+	}
+
+	plan, err := client.SyncPlan(*lastKnownHash, 2)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if plan.IsReorg() || plan.CommonAncestorHeight != 2 || plan.CommonAncestorHash != *lastKnownHash {
+		t.Errorf("got %d blocks to revert and common ancestor %s at %d, want the last-known block", len(plan.Revert), plan.CommonAncestorHash, plan.CommonAncestorHeight)
+	}
+	if plan.TipHeight != 4 || plan.TipHash != *tipHash || !reflect.DeepEqual(plan.ApplyHeights(), []int64{3, 4}) {
+		t.Errorf("got tip %s at %d and heights %v to apply, want %s at 4 and [3 4]", plan.TipHash, plan.TipHeight, plan.ApplyHeights(), *tipHash)
+	}
+
+	// A wallet that has not synced applies every block, and one at the tip applies none.
+	plan, err = client.SyncPlan("", -1)
+	if err != nil || plan.IsReorg() || len(plan.ApplyHeights()) != 5 {
+		t.Errorf("got plan %+v and error %v for a new wallet, want to apply every block", plan, err)
+	}
+	plan, err = client.SyncPlan(*tipHash, 4)
+	if err != nil || plan.IsReorg() || len(plan.ApplyHeights()) != 0 {
+		t.Errorf("got plan %+v and error %v at the tip, want nothing to do", plan, err)
+	}
+}
+
+func TestSyncPlanReorg(t *testing.T) {
+	// The wallet synced a0 to a4, and the node has since switched to a chain that forks after a2.
+	stale := map[string]*AbecBlock{
+		testBlockHash("a3"): {Height: 3, BlockHash: testBlockHash("a3"), PrevBlockHash: testBlockHash("a2")},
+		testBlockHash("a4"): {Height: 4, BlockHash: testBlockHash("a4"), PrevBlockHash: testBlockHash("a3")},
+	}
+	client := newSyncNode(t, []string{"a0", "a1", "a2", "b3", "b4", "b5"}, stale)
+
+	plan, err := client.SyncPlan(testBlockHash("a4"), 4)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	wantRevert := []*SyncBlock{{Height: 4, Hash: testBlockHash("a4")}, {Height: 3, Hash: testBlockHash("a3")}}
+	if !plan.IsReorg() || !reflect.DeepEqual(plan.Revert, wantRevert) {
+		t.Errorf("got %d blocks to revert, want a4 and a3", len(plan.Revert))
+	}
+	if plan.CommonAncestorHeight != 2 || plan.CommonAncestorHash != testBlockHash("a2") {
+		t.Errorf("got common ancestor %s at %d, want a2 at 2", plan.CommonAncestorHash, plan.CommonAncestorHeight)
+	}
+	if plan.TipHeight != 5 || plan.TipHash != testBlockHash("b5") || !reflect.DeepEqual(plan.ApplyHeights(), []int64{3, 4, 5}) {
+		t.Errorf("got tip %s at %d and heights %v to apply, want b5 at 5 and [3 4 5]", plan.TipHash, plan.TipHeight, plan.ApplyHeights())
+	}
+
+	// A wallet ahead of a shorter main chain reverts the blocks above the tip too.
+	client = newSyncNode(t, []string{"a0", "a1", "a2", "b3"}, map[string]*AbecBlock{
+		testBlockHash("a3"): stale[testBlockHash("a3")],
+		testBlockHash("a4"): stale[testBlockHash("a4")],
+	})
+	plan, err = client.SyncPlan(testBlockHash("a4"), 4)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if !reflect.DeepEqual(plan.Revert, wantRevert) || !reflect.DeepEqual(plan.ApplyHeights(), []int64{3}) {
+		t.Errorf("got %d blocks to revert and heights %v to apply, want a4 and a3, and [3]", len(plan.Revert), plan.ApplyHeights())
+	}
+
+	// A last-known block the node does not know cannot be walked back.
+	_, err = client.SyncPlan(testBlockHash("c4"), 4)
+	if err == nil {
+		t.Errorf("got no error for an unknown last-known block")
+	}
+}
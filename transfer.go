@@ -0,0 +1,342 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Define constants.
+type FeeMode int
+
+const (
+	SENDER_PAYS_FEE FeeMode = iota
+	RECIPIENT_PAYS_FEE
+)
+
+// ErrInputAlreadySpent is returned by RebuildAndResend when a coin the tx spends is already spent by another
+// tx in the node's mempool. Rebuilding cannot fix it, since the serial numbers do not depend on the ring.
+var ErrInputAlreadySpent = errors.New("input is already spent by another tx")
+
+func (mode FeeMode) String() string {
+	switch mode {
+	case SENDER_PAYS_FEE:
+		return "sender-pays-fee"
+	case RECIPIENT_PAYS_FEE:
+		return "recipient-pays-fee"
+	default:
+		return "unknown"
+	}
+}
+
+// Define methods for Wallet.
+func (w *Wallet) BuildTransfer(client *AbecRPCClient, keys *CryptoKeysAndAddress, recipients []*TxOutDesc, feePolicy *FeePolicy) (*TxDesc, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients given")
+	}
+
+	spendableCoins, err := w.spendableCoinsOf(client, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTransferFromCoins(client, spendableCoins, keys, recipients, feePolicy)
+}
+
+// BuildTransferFromAccount is like BuildTransfer, but only spends coins that were discovered for the given account.
+func (w *Wallet) BuildTransferFromAccount(client *AbecRPCClient, accountIndex uint32, keys *CryptoKeysAndAddress, recipients []*TxOutDesc, feePolicy *FeePolicy) (*TxDesc, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients given")
+	}
+
+	spendableCoins, err := w.spendableCoinsOf(client, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTransferFromCoins(client, FilterCoinsByAccount(spendableCoins, accountIndex), keys, recipients, feePolicy)
+}
+
+func (w *Wallet) spendableCoinsOf(client *AbecRPCClient, keys *CryptoKeysAndAddress) ([]*Coin, error) {
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only spend confirmed and mature coins owned by the given keys.
+	fingerprint := keys.CryptoAddress.Fingerprint()
+	spendableCoins := make([]*Coin, 0)
+	for _, coin := range w.UnspentCoinsMinConf(chainInfo.NumBlocks, 1) {
+		// A coin without an owner cannot be attributed to keys, and spending it with them would fail to sign.
+		if coin.OwnerShortAddress != nil && bytes.Equal(coin.OwnerShortAddress.Fingerprint(), fingerprint) {
+			spendableCoins = append(spendableCoins, coin)
+		}
+	}
+
+	return spendableCoins, nil
+}
+
+// Define util functions.
+func GetRingBlockHeightsForInputs(txInDescs []*TxInDesc) []int64 {
+	seen := make(map[int64]bool)
+	heights := make([]int64, 0, 3*len(txInDescs))
+	for _, txInDesc := range txInDescs {
+		for _, height := range GetRingBlockHeights(txInDesc.Height) {
+			if !seen[height] {
+				seen[height] = true
+				heights = append(heights, height)
+			}
+		}
+	}
+
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+
+	return heights
+}
+
+func FetchRingBlocksForInputs(client *AbecRPCClient, txInDescs []*TxInDesc) (map[int64]*TxBlockDesc, error) {
+	return FetchRingBlocksForInputsContext(context.Background(), client, txInDescs)
+}
+
+func FetchRingBlocksForInputsContext(ctx context.Context, client *AbecRPCClient, txInDescs []*TxInDesc) (map[int64]*TxBlockDesc, error) {
+	results := FetchBlocksConcurrentContext(ctx, client, GetRingBlockHeightsForInputs(txInDescs))
+	err := BlockFetchError(results)
+	if err != nil {
+		return nil, err
+	}
+
+	ringBlockDescs := make(map[int64]*TxBlockDesc)
+	for _, result := range results {
+		ringBlockDescs[result.Height] = NewTxBlockDesc(result.Bytes, result.Height)
+	}
+
+	return ringBlockDescs, nil
+}
+
+// RefreshRingBlocks re-fetches the ring blocks of txDesc's inputs and replaces them in TxRingBlockDescs,
+// so that a reorg since the tx desc was built does not leave it with blocks that are no longer on the chain.
+func RefreshRingBlocks(client *AbecRPCClient, txDesc *TxDesc) error {
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, txDesc.TxInDescs)
+	if err != nil {
+		return err
+	}
+
+	if txDesc.TxRingBlockDescs == nil {
+		txDesc.TxRingBlockDescs = make(map[int64]*TxBlockDesc)
+	}
+	for height, ringBlockDesc := range ringBlockDescs {
+		txDesc.TxRingBlockDescs[height] = ringBlockDesc
+	}
+
+	return nil
+}
+
+func EstimateRingBlockBytes(client *AbecRPCClient, txInDescs []*TxInDesc) (int64, error) {
+	total := int64(0)
+	for _, height := range GetRingBlockHeightsForInputs(txInDescs) {
+		_, block, err := client.GetBlockByHeight(height)
+		if err != nil {
+			return 0, err
+		}
+
+		// The raw ring block is downloaded with its witness, so prefer the full size.
+		if block.FullSize > 0 {
+			total += block.FullSize
+		} else {
+			total += block.Size
+		}
+	}
+
+	return total, nil
+}
+
+// IsRingRejection reports whether err is a rejection by the node that rebuilding the tx with fresh ring blocks
+// may fix. See AbecRPCError.IsRingRejection.
+func IsRingRejection(err error) bool {
+	var rpcErr *AbecRPCError
+	return errors.As(err, &rpcErr) && rpcErr.IsRingRejection()
+}
+
+// IsSerialNumberConflict reports whether err is a rejection by the node because a coin the tx spends is
+// already spent by another tx in its mempool. See AbecRPCError.IsSerialNumberConflict.
+func IsSerialNumberConflict(err error) bool {
+	var rpcErr *AbecRPCError
+	return errors.As(err, &rpcErr) && rpcErr.IsSerialNumberConflict()
+}
+
+func BuildAndSignTx(client *AbecRPCClient, txDesc *TxDesc, signerKeys []*CryptoKeysAndAddress) (*SignedRawTx, error) {
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, txDesc.TxInDescs)
+	if err != nil {
+		return nil, err
+	}
+	txDesc.TxRingBlockDescs = ringBlockDescs
+
+	unsignedRawTx, err := GenerateUnsignedRawTx(txDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	return GenerateSignedRawTx(unsignedRawTx, signerKeys)
+}
+
+func RebuildAndResend(client *AbecRPCClient, txDesc *TxDesc, signerKeys []*CryptoKeysAndAddress, signedRawTx *SignedRawTx) (*TxSubmissionResult, error) {
+	result := &TxSubmissionResult{
+		SignedRawTx:    signedRawTx,
+		SubmissionTime: client.clock.Now().Unix(),
+	}
+
+	_, _, err := client.SendSignedRawTx(signedRawTx)
+	if IsSerialNumberConflict(err) {
+		// A fresh ring does not change the serial numbers, so a rebuilt tx would spend the same coin again.
+		result.Error = err.Error()
+		return result, fmt.Errorf("%w: %s", ErrInputAlreadySpent, err)
+	}
+	if IsRingRejection(err) {
+		LOG.debug("Ring rejection for tx %s, rebuilding with fresh ring blocks: %s\n", signedRawTx.Txid.HexString(), err)
+
+		signedRawTx, err = BuildAndSignTx(client, txDesc, signerKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		result.SignedRawTx = signedRawTx
+		result.SubmissionTime = client.clock.Now().Unix()
+		_, _, err = client.SendSignedRawTx(signedRawTx)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// TransferMulti pays all recipients in one tx from the wallet's coins owned by keys, with change back to keys,
+// then signs and broadcasts it. The recipients and the change output, if the selected coins leave any, must fit
+// in the tx output limit.
+func TransferMulti(client *AbecRPCClient, wallet *Wallet, keys *CryptoKeysAndAddress, recipients []*TxOutDesc, feePolicy *FeePolicy) (*TxSubmissionResult, error) {
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) > limits.MaxTxOutputs {
+		return nil, fmt.Errorf("%d recipients exceed the limit of %d outputs", len(recipients), limits.MaxTxOutputs)
+	}
+
+	// Whether there is a change output is only known after coin selection.
+	txDesc, err := wallet.BuildTransfer(client, keys, recipients, feePolicy)
+	if err != nil {
+		return nil, err
+	}
+	err = limits.Validate(txDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every input is spent by the same keys.
+	signerKeys := make([]*CryptoKeysAndAddress, len(txDesc.TxInDescs))
+	for i := range signerKeys {
+		signerKeys[i] = keys
+	}
+
+	unsignedRawTx, err := GenerateUnsignedRawTx(txDesc)
+	if err != nil {
+		return nil, err
+	}
+	signedRawTx, err := GenerateSignedRawTx(unsignedRawTx, signerKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return RebuildAndResend(client, txDesc, signerKeys, signedRawTx)
+}
+
+func BuildTransferWithFeeMode(client *AbecRPCClient, wallet *Wallet, keys *CryptoKeysAndAddress, recipient *AbelAddress, amount int64, feePolicy *FeePolicy, mode FeeMode) (*TxDesc, error) {
+	switch mode {
+	case SENDER_PAYS_FEE:
+		return wallet.BuildTransfer(client, keys, []*TxOutDesc{NewTxOutDesc(recipient, amount)}, feePolicy)
+	case RECIPIENT_PAYS_FEE:
+		return BuildTransferRecipientPaysFee(client, wallet, keys, recipient, amount, feePolicy)
+	default:
+		return nil, fmt.Errorf("unknown fee mode %d", mode)
+	}
+}
+
+// BuildTransferRecipientPaysFee debits exactly amount from the wallet, and the recipient receives amount minus the fee.
+func BuildTransferRecipientPaysFee(client *AbecRPCClient, wallet *Wallet, keys *CryptoKeysAndAddress, recipient *AbelAddress, amount int64, feePolicy *FeePolicy) (*TxDesc, error) {
+	spendableCoins, err := wallet.spendableCoinsOf(client, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select coins for the amount alone, since the fee is taken out of it. With a zero fee rate, the returned
+	// fee is only the dust change, which is added to the fee.
+	selectedCoins, change, dust, err := selectCoins(spendableCoins, amount, 2, 0, NewFeePolicy(0))
+	if err != nil {
+		return nil, err
+	}
+
+	txSize, err := estimateTxSize(len(selectedCoins), 2, 0)
+	if err != nil {
+		return nil, err
+	}
+	fee := feePolicy.ComputeFee(txSize)
+	if amount <= fee {
+		return nil, fmt.Errorf("amount %d does not cover the fee %d", amount, fee)
+	}
+
+	recipients := []*TxOutDesc{NewTxOutDesc(recipient, amount-fee)}
+	changeAddress, err := keys.ChangeAddress(recipient.GetChainID())
+	if err != nil {
+		return nil, err
+	}
+	return buildTransferTxDesc(client, selectedCoins, recipients, fee+dust, changeAddress, change, nil)
+}
+
+func buildTransferFromCoins(client *AbecRPCClient, spendableCoins []*Coin, keys *CryptoKeysAndAddress, recipients []*TxOutDesc, feePolicy *FeePolicy) (*TxDesc, error) {
+	targetValue := int64(0)
+	for _, recipient := range recipients {
+		targetValue += recipient.CoinValue
+	}
+
+	selectedCoins, change, fee, err := selectCoins(spendableCoins, targetValue, len(recipients)+1, 0, feePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	changeAddress, err := keys.ChangeAddress(recipients[0].AbelAddress.GetChainID())
+	if err != nil {
+		return nil, err
+	}
+	return buildTransferTxDesc(client, selectedCoins, recipients, fee, changeAddress, change, nil)
+}
+
+func buildTransferTxDesc(client *AbecRPCClient, coins []*Coin, recipients []*TxOutDesc, fee int64, changeAddress *AbelAddress, change int64, memo Bytes) (*TxDesc, error) {
+	txInDescs := make([]*TxInDesc, 0, len(coins))
+	for _, coin := range coins {
+		txInDescs = append(txInDescs, coin.ToTxInDesc())
+	}
+
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, txInDescs)
+	if err != nil {
+		return nil, err
+	}
+
+	txOutDescs := make([]*TxOutDesc, len(recipients))
+	copy(txOutDescs, recipients)
+	txDesc := NewTxDescWithMemo(txInDescs, txOutDescs, fee, ringBlockDescs, memo)
+	txDesc.AddChangeOutput(changeAddress, change)
+
+	err = txDesc.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return txDesc, nil
+}
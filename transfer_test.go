@@ -0,0 +1,400 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/abesuite/abec/chainhash"
+	"github.com/abesuite/abec/wire"
+)
+
+// newTestSignedRawTx returns a tx that deserializes like a real one and spends the coin with serialNumber.
+// Its signature is not valid, which the simulation does not check.
+func newTestSignedRawTx(t *testing.T, serialNumber byte, memo string) *SignedRawTx {
+	t.Helper()
+
+	return newTestSignedRawTxSpending(t, bytes.Repeat([]byte{serialNumber}, 64), memo)
+}
+
+// newTestSignedRawTxSpending is like newTestSignedRawTx, but spends the coin with the given full serial number.
+func newTestSignedRawTxSpending(t *testing.T, serialNumber Bytes, memo string) *SignedRawTx {
+	t.Helper()
+
+	ring := wire.NewOutPointRing(wire.TxVersion,
+		[]*chainhash.Hash{{1}, {2}, {3}},
+		[]*wire.OutPointAbe{wire.NewOutPointAbe(&chainhash.Hash{1}, 0)})
+	msgTx := wire.NewMsgTxAbe(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxInAbe(serialNumber, ring))
+	msgTx.AddTxOut(wire.NewTxOutAbe(wire.TxVersion, []byte{0x01}))
+	msgTx.TxMemo = []byte(memo)
+	msgTx.TxWitness = []byte{0x01}
+
+	var buf bytes.Buffer
+	if err := msgTx.SerializeFull(&buf); err != nil {
+		t.Fatalf("cannot serialize tx: %s", err)
+	}
+	txHash := msgTx.TxHash()
+
+	return NewSignedRawTx(AsBytes(buf.Bytes()), Txid(txHash))
+}
+
+func TestSpendableCoinsOf(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+
+	chain := NewSimulationChain(0)
+	err := chain.AddBlock(newTestBlock(t, 0, chainhash.Hash{}, testPayment{keys, 1000}))
+	if err != nil {
+		t.Fatalf("cannot add the block: %s", err)
+	}
+	client := NewSimulationClient(chain)
+
+	owned := newTestCoin(1, 100, 0)
+	owned.OwnerShortAddress = NewAbelAddressFromCryptoAddress(&keys.CryptoAddress).GetShortAbelAddress()
+	other := newTestCoin(2, 200, 0)
+	other.OwnerShortAddress = NewAbelAddressFromCryptoAddress(&otherKeys.CryptoAddress).GetShortAbelAddress()
+	ownerless := newTestCoin(3, 300, 0)
+
+	w := NewWallet()
+	w.AddCoins([]*Coin{owned, other, ownerless})
+
+	coins, err := w.spendableCoinsOf(client, keys)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(coins) != 1 || !coins[0].Equal(owned) {
+		t.Errorf("got %d coins, want only the coin owned by keys", len(coins))
+	}
+}
+
+func TestIsRingRejection(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantRing     bool
+		wantConflict bool
+	}{
+		{"nil", nil, false, false},
+		{"orphan", &AbecRPCError{Method: "sendrawtransactionabe", Code: RPC_ERROR_TX_ERROR,
+			Message: "TX rejected: orphan transaction 01 references outputs of unknown or fully-spent transaction 02"}, true, false},
+		{"wrapped orphan", fmt.Errorf("send failed: %w", &AbecRPCError{Code: RPC_ERROR_TX_ERROR,
+			Message: "TX rejected: orphan transaction 01 references outputs of unknown or fully-spent transaction 02"}), true, false},
+		{"own serial number in mempool", &AbecRPCError{Code: RPC_ERROR_TX_REJECTED,
+			Message: "TX rejected: outpoint 01 already spent by transaction 02 in the memory pool"}, false, true},
+		{"orphan text with other code", &AbecRPCError{Code: RPC_ERROR_TX_REJECTED,
+			Message: "TX rejected: orphan transaction 01"}, false, false},
+		{"untyped error with the same text", errors.New("orphan transaction 01 already spent by transaction 02"), false, false},
+		{"already known", &AbecRPCError{Code: RPC_ERROR_TX_ALREADY_IN_CHAIN, Message: "transaction already exists"}, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsRingRejection(test.err); got != test.wantRing {
+				t.Errorf("IsRingRejection() = %v, want %v", got, test.wantRing)
+			}
+			if got := IsSerialNumberConflict(test.err); got != test.wantConflict {
+				t.Errorf("IsSerialNumberConflict() = %v, want %v", got, test.wantConflict)
+			}
+		})
+	}
+}
+
+func TestRebuildAndResendStopsOnSerialNumberConflict(t *testing.T) {
+	chain := NewSimulationChain(0)
+	client := NewSimulationClient(chain)
+
+	_, _, err := client.SendSignedRawTx(newTestSignedRawTx(t, 7, "first"))
+	if err != nil {
+		t.Fatalf("cannot send the first tx: %s", err)
+	}
+
+	// The tx desc is nil, so a rebuild would panic instead of returning.
+	result, err := RebuildAndResend(client, nil, nil, newTestSignedRawTx(t, 7, "second"))
+	if !errors.Is(err, ErrInputAlreadySpent) {
+		t.Fatalf("got error %v, want ErrInputAlreadySpent", err)
+	}
+	if result == nil || result.Success || result.Error == "" {
+		t.Errorf("got result %+v, want a failed submission with its error", result)
+	}
+	if sent := len(chain.SentTxs()); sent != 1 {
+		t.Errorf("simulation has %d txs, want 1", sent)
+	}
+}
+
+func TestTransferMultiWithoutChangeUsesAllOutputs(t *testing.T) {
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		t.Fatalf("cannot get the protocol limits: %s", err)
+	}
+
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 3, map[int64][]testPayment{0: {{keys, 5000 * int64(limits.MaxTxOutputs)}}})
+	client := NewSimulationClient(chain)
+	w := NewWallet()
+	err = RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	// The coinbase would not be mature for a long time.
+	for _, coin := range w.Coins() {
+		coin.IsCoinbase = false
+	}
+
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+	recipients := make([]*TxOutDesc, limits.MaxTxOutputs)
+	for i := range recipients {
+		recipients[i] = NewTxOutDesc(recipient, 5000)
+	}
+
+	// Without a fee the coin is spent exactly, so there is no change output.
+	result, err := TransferMulti(client, w, keys, recipients, NewFeePolicy(0))
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if !result.Success {
+		t.Errorf("transfer was not successful: %s", result.Error)
+	}
+
+	_, err = TransferMulti(client, w, keys, append(recipients, NewTxOutDesc(recipient, 5000)), NewFeePolicy(0))
+	if err == nil {
+		t.Errorf("got no error for more recipients than the output limit")
+	}
+}
+
+func TestRefreshRingBlocks(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+	heights := GetRingBlockHeightsForInputs(txDesc.TxInDescs)
+	builtWith := make(map[int64]Bytes)
+	for _, height := range heights {
+		builtWith[height] = txDesc.TxRingBlockDescs[height].BinData
+	}
+
+	// A reorg replaces every ring block with one of different bytes, since its coinbase pays another address.
+	reorged := NewSimulationClient(newTestChain(t, 3, nil))
+	err := RefreshRingBlocks(reorged, txDesc)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(txDesc.TxRingBlockDescs) != len(heights) {
+		t.Errorf("got %d ring blocks, want %d", len(txDesc.TxRingBlockDescs), len(heights))
+	}
+	for _, height := range heights {
+		want, err := reorged.GetBlockBytesByHeight(height)
+		if err != nil {
+			t.Fatalf("cannot get block %d: %s", height, err)
+		}
+		got := txDesc.TxRingBlockDescs[height]
+		if got.Height != height || !bytes.Equal(got.BinData, want) {
+			t.Errorf("block %d: got the ring block at height %d, want the block of the reorged chain", height, got.Height)
+		}
+		if bytes.Equal(got.BinData, builtWith[height]) {
+			t.Errorf("block %d: got the block the tx desc was built with", height)
+		}
+	}
+
+	// A required height that is no longer on the chain is an error.
+	short := NewSimulationClient(newTestChain(t, 2, nil))
+	err = RefreshRingBlocks(short, txDesc)
+	if err == nil {
+		t.Errorf("got no error with a missing ring block")
+	}
+}
+
+func TestEstimateRingBlockBytes(t *testing.T) {
+	var mutex sync.Mutex
+	lookups := make(map[int64]int)
+	client := newMockNode(t, map[string]mockMethod{
+		"getblockhash": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var height int64
+			json.Unmarshal(params[0], &height)
+			if height > 5 {
+				return nil, &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+			}
+			mutex.Lock()
+			lookups[height]++
+			mutex.Unlock()
+			return testBlockHash(fmt.Sprintf("%02x", height)), nil
+		},
+		"getblockabe": func(params []json.RawMessage) (interface{}, *AbecJSONRPCError) {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+			height, _ := strconv.ParseInt(hash[:2], 16, 64)
+
+			// The block at height 3 reports no full size, as a node without witness data would.
+			block := &AbecBlock{Height: height, BlockHash: hash, Size: 1000 + height, FullSize: 50000 + height}
+			if height == 3 {
+				block.FullSize = 0
+			}
+			return block, nil
+		},
+	})
+
+	// The inputs at heights 0 and 2 share the ring group of heights 0 to 2, and the input at 4 adds 3 to 5.
+	total, err := EstimateRingBlockBytes(client, []*TxInDesc{{Height: 0}, {Height: 2}, {Height: 4}})
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if want := int64(50000 + 50001 + 50002 + 1003 + 50004 + 50005); total != want {
+		t.Errorf("got %d bytes, want %d", total, want)
+	}
+	for height := int64(0); height <= 5; height++ {
+		if lookups[height] != 1 {
+			t.Errorf("block %d was looked up %d times, want once", height, lookups[height])
+		}
+	}
+
+	_, err = EstimateRingBlockBytes(client, []*TxInDesc{{Height: 6}})
+	if err == nil {
+		t.Errorf("got no error for a ring group beyond the tip")
+	}
+}
+
+func TestBuildTransferWithFeeMode(t *testing.T) {
+	const feeRate = 10
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 3, map[int64][]testPayment{0: {{keys, 5000000}}})
+	client := NewSimulationClient(chain)
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	// The coinbase would not be mature for a long time.
+	for _, coin := range w.Coins() {
+		coin.IsCoinbase = false
+	}
+
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+	// One input pays the recipient and the change in either mode.
+	txSize, err := estimateTxSize(1, 2, 0)
+	if err != nil {
+		t.Fatalf("cannot estimate the tx size: %s", err)
+	}
+	fee := ComputeFee(txSize, feeRate)
+
+	tests := []struct {
+		mode         FeeMode
+		wantReceived int64
+		wantDebited  int64
+		wantFee      int64
+		wantChange   int64
+	}{
+		{SENDER_PAYS_FEE, 3000000, 3000000 + fee, fee, 2000000 - fee},
+		{RECIPIENT_PAYS_FEE, 3000000 - fee, 3000000, fee, 2000000},
+	}
+	for _, test := range tests {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			txDesc, err := BuildTransferWithFeeMode(client, w, keys, recipient, 3000000, NewFeePolicy(feeRate), test.mode)
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if len(txDesc.TxOutDescs) != 2 {
+				t.Fatalf("got %d outputs, want the recipient and the change", len(txDesc.TxOutDescs))
+			}
+			if received := txDesc.TxOutDescs[0].CoinValue; received != test.wantReceived {
+				t.Errorf("recipient receives %d, want %d", received, test.wantReceived)
+			}
+			if change := txDesc.TxOutDescs[1].CoinValue; change != test.wantChange {
+				t.Errorf("got change %d, want %d", change, test.wantChange)
+			}
+			if debited := txDesc.TotalInputValue() - txDesc.TxOutDescs[1].CoinValue; debited != test.wantDebited {
+				t.Errorf("wallet is debited %d, want %d", debited, test.wantDebited)
+			}
+			if txDesc.TxFee != test.wantFee {
+				t.Errorf("got fee %d, want %d", txDesc.TxFee, test.wantFee)
+			}
+		})
+	}
+
+	_, err = BuildTransferWithFeeMode(client, w, keys, recipient, fee, NewFeePolicy(feeRate), RECIPIENT_PAYS_FEE)
+	if err == nil {
+		t.Errorf("got no error for an amount that does not cover the fee")
+	}
+	_, err = BuildTransferWithFeeMode(client, w, keys, recipient, 3000000, NewFeePolicy(feeRate), FeeMode(7))
+	if err == nil {
+		t.Errorf("got no error for an unknown fee mode")
+	}
+}
+
+func TestBuildTransferFromAccount(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	payments := []testPayment{{keys, 4000000}, {otherKeys, 2000000}, {keys, 1000000}}
+	client := NewSimulationClient(newTestChain(t, 3, map[int64][]testPayment{0: payments}))
+
+	blockBytes, err := client.GetBlockBytesByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+	index := NewFingerprintIndex()
+	index.AddAccount(1, keys)
+	index.AddAccount(2, otherKeys)
+	coins, err := ScanBlockMultiWallet(block, index)
+	if err != nil {
+		t.Fatalf("cannot scan block 0: %s", err)
+	}
+
+	// Each coin is tagged with the account of the keys it was found for.
+	w := NewWallet()
+	for _, coin := range coins {
+		wantAccount := uint32(1)
+		if coin.Value == 2000000 {
+			wantAccount = 2
+		}
+		if coin.AccountIndex != wantAccount {
+			t.Errorf("coin of %d is tagged with account %d, want %d", coin.Value, coin.AccountIndex, wantAccount)
+		}
+		// The coinbase would not be mature for a long time.
+		coin.IsCoinbase = false
+		w.AddCoin(coin)
+	}
+	for account, wantValues := range map[uint32][]int64{1: {4000000, 1000000}, 2: {2000000}, 3: {}} {
+		accountCoins := w.UnspentCoinsForAccount(account)
+		if len(accountCoins) != len(wantValues) {
+			t.Errorf("account %d has %d coins, want %d", account, len(accountCoins), len(wantValues))
+			continue
+		}
+		for i, coin := range accountCoins {
+			if coin.Value != wantValues[i] {
+				t.Errorf("coin %d of account %d has value %d, want %d", i, account, coin.Value, wantValues[i])
+			}
+		}
+	}
+
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+	recipients := []*TxOutDesc{NewTxOutDesc(recipient, 1500000)}
+
+	txDesc, err := w.BuildTransferFromAccount(client, 2, otherKeys, recipients, NewFeePolicy(0))
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(txDesc.TxInDescs) != 1 || txDesc.TxInDescs[0].CoinValue != 2000000 {
+		t.Errorf("got %d inputs, want the coin of account 2", len(txDesc.TxInDescs))
+	}
+
+	// The wallet holds enough, but the keys have no coins in account 2.
+	_, err = w.BuildTransferFromAccount(client, 2, keys, recipients, NewFeePolicy(0))
+	var insufficientFunds *InsufficientFundsError
+	if !errors.As(err, &insufficientFunds) {
+		t.Errorf("got error %v, want InsufficientFundsError", err)
+	}
+}
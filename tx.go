@@ -1,5 +1,19 @@
 package core
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abesuite/abec/abecrypto/abecryptoparam"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define constants.
+const (
+	MAX_TX_MEMO_SIZE = int(abecryptoparam.MaxAllowedTxMemoSize)
+)
+
 // Define the TxInDesc data type and methods.
 type TxInDesc struct {
 	TxOutData        Bytes
@@ -31,6 +45,15 @@ func (d *TxInDesc) GetCoinAddress() (*CoinAddress, error) {
 	return coinAddress, nil
 }
 
+func (d *TxInDesc) GetCoinID() (*CoinID, error) {
+	txHash, err := NewTxidFromDisplayBytes(d.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCoinID(txHash, d.TxOutIndex), nil
+}
+
 func (d *TxInDesc) GetFingerprint() Bytes {
 	coinAddress, err := d.GetCoinAddress()
 	if err != nil {
@@ -93,12 +116,200 @@ func NewTxDescWithMemo(txInDescs []*TxInDesc, txOutDescs []*TxOutDesc, txFee int
 	}
 }
 
+// NewTxMemoFromString encodes a text memo. UTF-8 is the canonical encoding of text memos, and the node
+// rejects memos longer than MAX_TX_MEMO_SIZE bytes, which is fewer characters for non-ASCII text.
+func NewTxMemoFromString(s string) Bytes {
+	return Bytes(s)
+}
+
+func (d *TxDesc) AddChangeOutput(changeAddress *AbelAddress, changeValue int64) {
+	if changeValue <= 0 {
+		return
+	}
+
+	d.TxOutDescs = append(d.TxOutDescs, NewTxOutDesc(changeAddress, changeValue))
+}
+
+// TotalInputValue returns the sum of the input values.
+func (d *TxDesc) TotalInputValue() int64 {
+	total := int64(0)
+	for _, txInDesc := range d.TxInDescs {
+		total += txInDesc.CoinValue
+	}
+
+	return total
+}
+
+// TotalOutputValue returns the sum of the output values, without the fee.
+func (d *TxDesc) TotalOutputValue() int64 {
+	total := int64(0)
+	for _, txOutDesc := range d.TxOutDescs {
+		total += txOutDesc.CoinValue
+	}
+
+	return total
+}
+
+// Validate checks that the tx desc is self-consistent and within DefaultProtocolLimits. If a view secret key
+// is given, the value of each input is also decoded from its TxOutData and compared with CoinValue.
+// Use ValidateWithLimits to check against limits read from the node instead.
+func (d *TxDesc) Validate(viewSecretKey ...*CryptoKey) error {
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		return err
+	}
+
+	return d.ValidateWithLimits(limits, viewSecretKey...)
+}
+
+func (d *TxDesc) ValidateWithLimits(limits *ProtocolLimits, viewSecretKey ...*CryptoKey) error {
+	if len(d.TxInDescs) == 0 {
+		return fmt.Errorf("tx desc has no inputs")
+	}
+	if len(d.TxOutDescs) == 0 {
+		return fmt.Errorf("tx desc has no outputs")
+	}
+	if d.TxFee < 0 {
+		return fmt.Errorf("tx desc fee %d is negative", d.TxFee)
+	}
+	err := limits.Validate(d)
+	if err != nil {
+		return err
+	}
+
+	totalIn := int64(0)
+	for i, txInDesc := range d.TxInDescs {
+		for _, height := range GetRingBlockHeights(txInDesc.Height) {
+			if _, ok := d.TxRingBlockDescs[height]; !ok {
+				return fmt.Errorf("tx desc input %d is missing ring block at height %d", i, height)
+			}
+		}
+		if txInDesc.CoinValue <= 0 {
+			return fmt.Errorf("tx desc input %d value %d is not positive", i, txInDesc.CoinValue)
+		}
+		if len(viewSecretKey) > 0 && viewSecretKey[0] != nil {
+			decodedValue, err := DecodeValueFromTxOutData(txInDesc.TxOutData, viewSecretKey[0])
+			if err != nil {
+				return fmt.Errorf("tx desc input %d value cannot be decoded: %s", i, err)
+			}
+			if decodedValue != txInDesc.CoinValue {
+				return fmt.Errorf("tx desc input %d value %d does not match decoded value %d", i, txInDesc.CoinValue, decodedValue)
+			}
+		}
+		totalIn += txInDesc.CoinValue
+	}
+
+	totalOut := int64(0)
+	for i, txOutDesc := range d.TxOutDescs {
+		if txOutDesc.AbelAddress == nil {
+			return fmt.Errorf("tx desc output %d has no address", i)
+		}
+		if txOutDesc.CoinValue <= 0 {
+			return fmt.Errorf("tx desc output %d value %d is not positive", i, txOutDesc.CoinValue)
+		}
+		totalOut += txOutDesc.CoinValue
+	}
+
+	if totalIn != totalOut+d.TxFee {
+		return fmt.Errorf("tx desc inputs %d do not equal outputs %d plus fee %d", totalIn, totalOut, d.TxFee)
+	}
+
+	return nil
+}
+
+// SerialNumbersToReveal computes the serial numbers the inputs will publish once the tx is signed, one serial
+// number secret key per input. The tx desc's own ring blocks are used if ringBlockDescs is nil.
+func (d *TxDesc) SerialNumbersToReveal(serialNoSecretKeys []*CryptoKey, ringBlockDescs map[int64]*TxBlockDesc) ([]Bytes, error) {
+	if len(serialNoSecretKeys) != len(d.TxInDescs) {
+		return nil, fmt.Errorf("got %d serial number secret keys for %d inputs", len(serialNoSecretKeys), len(d.TxInDescs))
+	}
+	if ringBlockDescs == nil {
+		ringBlockDescs = d.TxRingBlockDescs
+	}
+
+	coinIDs := make([]*CoinID, 0, len(d.TxInDescs))
+	for i, txInDesc := range d.TxInDescs {
+		coinID, err := txInDesc.GetCoinID()
+		if err != nil {
+			return nil, fmt.Errorf("tx desc input %d: %s", i, err)
+		}
+		coinIDs = append(coinIDs, coinID)
+	}
+
+	// Only pass the ring groups of the inputs, since the API expects every block it is given to be part of a complete group.
+	inputRingBlockDescs := make(map[int64]*TxBlockDesc)
+	for _, height := range GetRingBlockHeightsForInputs(d.TxInDescs) {
+		ringBlockDesc, ok := ringBlockDescs[height]
+		if !ok {
+			return nil, fmt.Errorf("ring block at height %d is missing", height)
+		}
+		inputRingBlockDescs[height] = ringBlockDesc
+	}
+
+	return DecodeCoinSerialNumbers(coinIDs, serialNoSecretKeys, inputRingBlockDescs)
+}
+
+// ValidateInputsUnspent asks the node whether each input's serial number has already been consumed in the
+// main chain. The ring blocks must be present to rebuild the rings. Inputs need their CoinSerialNumber, which
+// is derived from the ring blocks for inputs without one if serialNoSecretKeys, one per input, are given.
+// Spends that are only in the mempool are not detected.
+func (d *TxDesc) ValidateInputsUnspent(client *AbecRPCClient, serialNoSecretKeys ...*CryptoKey) error {
+	rings, err := FindInputRings(d.TxRingBlockDescs, d.TxInDescs)
+	if err != nil {
+		return err
+	}
+
+	serialNumbers := make([]Bytes, len(d.TxInDescs))
+	var derivedSerialNumbers []Bytes
+	for i, txInDesc := range d.TxInDescs {
+		serialNumbers[i] = txInDesc.CoinSerialNumber
+		if serialNumbers[i].Len() > 0 {
+			continue
+		}
+		if len(serialNoSecretKeys) == 0 {
+			return fmt.Errorf("tx desc input %d has no CoinSerialNumber, and no serial number secret keys are given to derive it", i)
+		}
+
+		if derivedSerialNumbers == nil {
+			derivedSerialNumbers, err = d.SerialNumbersToReveal(serialNoSecretKeys, nil)
+			if err != nil {
+				return fmt.Errorf("tx desc input %d has no CoinSerialNumber, and it cannot be derived: %s", i, err)
+			}
+		}
+		serialNumbers[i] = derivedSerialNumbers[i]
+	}
+
+	for i := range d.TxInDescs {
+		_, entry, err := client.GetUTXORing(rings[i])
+		if err != nil {
+			return fmt.Errorf("tx desc input %d: %s", i, err)
+		}
+		if entry == nil {
+			return fmt.Errorf("tx desc input %d is already spent: its whole ring is spent", i)
+		}
+
+		serialNumber := serialNumbers[i].HexString()
+		for _, consumedSerialNumber := range entry.ConsumedSerialNumbers {
+			if consumedSerialNumber == serialNumber {
+				return fmt.Errorf("tx desc input %d with serial number %s is already spent", i, serialNumber)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Define the UnsignedRawTx data type and methods.
 type UnsignedRawTx struct {
 	Bytes
 	Signers []*ShortAbelAddress
 }
 
+type unsignedRawTxJSON struct {
+	Data    Bytes               `json:"data"`
+	Signers []*ShortAbelAddress `json:"signers"`
+}
+
 func NewUnsignedRawTx(data Bytes, signers ...[]*ShortAbelAddress) *UnsignedRawTx {
 	if len(signers) == 0 {
 		signers = append(signers, []*ShortAbelAddress{})
@@ -110,19 +321,101 @@ func NewUnsignedRawTx(data Bytes, signers ...[]*ShortAbelAddress) *UnsignedRawTx
 	}
 }
 
+// MarshalJSON encodes the tx as the hex of its data and its signers. It overrides the MarshalText promoted from
+// Bytes, which would encode the data alone.
+func (tx UnsignedRawTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&unsignedRawTxJSON{Data: tx.Bytes, Signers: tx.Signers})
+}
+
+func (tx *UnsignedRawTx) UnmarshalJSON(data []byte) error {
+	encoded := &unsignedRawTxJSON{}
+	err := json.Unmarshal(data, encoded)
+	if err != nil {
+		return err
+	}
+
+	tx.Bytes, tx.Signers = encoded.Data, encoded.Signers
+	return nil
+}
+
 // Define the SignedRawTx data type and methods.
 type SignedRawTx struct {
 	Bytes
-	Txid Bytes
+	Txid    Txid
+	Signers []*ShortAbelAddress
+}
+
+type signedRawTxJSON struct {
+	Data    Bytes               `json:"data"`
+	Txid    Txid                `json:"txid"`
+	Signers []*ShortAbelAddress `json:"signers"`
 }
 
-func NewSignedRawTx(data Bytes, txid Bytes) *SignedRawTx {
+func NewSignedRawTx(data Bytes, txid Txid, signers ...[]*ShortAbelAddress) *SignedRawTx {
+	if len(signers) == 0 {
+		signers = append(signers, []*ShortAbelAddress{})
+	}
+
 	return &SignedRawTx{
-		Bytes: data,
-		Txid:  txid,
+		Bytes:   data,
+		Txid:    txid,
+		Signers: signers[0],
 	}
 }
 
+// MarshalJSON encodes the tx as the hex of its data, its txid and its signers. It overrides the MarshalText
+// promoted from Bytes, which would encode the data alone.
+func (tx SignedRawTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&signedRawTxJSON{Data: tx.Bytes, Txid: tx.Txid, Signers: tx.Signers})
+}
+
+func (tx *SignedRawTx) UnmarshalJSON(data []byte) error {
+	encoded := &signedRawTxJSON{}
+	err := json.Unmarshal(data, encoded)
+	if err != nil {
+		return err
+	}
+
+	tx.Bytes, tx.Txid, tx.Signers = encoded.Data, encoded.Txid, encoded.Signers
+	return nil
+}
+
+// Validate checks offline that the tx deserializes, that its txid matches its content, and that it has one
+// signer per input. A tx without signers fails, since the signers cannot be checked.
+func (tx *SignedRawTx) Validate() error {
+	if tx.Len() == 0 {
+		return fmt.Errorf("signed raw tx is empty")
+	}
+
+	reader := bytes.NewReader(tx.Slice())
+	msgTx := &wire.MsgTxAbe{}
+	err := msgTx.DeserializeFull(reader)
+	if err != nil {
+		return fmt.Errorf("signed raw tx cannot be deserialized: %s", err)
+	}
+	if reader.Len() != 0 {
+		return fmt.Errorf("signed raw tx has %d trailing bytes", reader.Len())
+	}
+
+	if len(msgTx.TxIns) == 0 {
+		return fmt.Errorf("signed raw tx has no inputs")
+	}
+	if !msgTx.HasWitness() {
+		return fmt.Errorf("signed raw tx has no witness")
+	}
+	if len(tx.Signers) != len(msgTx.TxIns) {
+		return fmt.Errorf("signed raw tx has %d signers for %d inputs", len(tx.Signers), len(msgTx.TxIns))
+	}
+
+	// NOTE: The txid used by the RPC/SDK/UI is a reversed version of the txid used by the API.
+	txid := msgTx.TxId()
+	if computedTxid, _ := NewTxidFromAPIBytes(txid[:]); computedTxid != tx.Txid {
+		return fmt.Errorf("signed raw tx txid does not match its content")
+	}
+
+	return nil
+}
+
 // Define the TxSubmissionResult data type and methods.
 type TxSubmissionResult struct {
 	SignedRawTx    *SignedRawTx
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/abesuite/abec/wire"
+)
+
+func TestValidateInputsUnspent(t *testing.T) {
+	keys := newTestKeys(t)
+	chain := newTestChain(t, 3, map[int64][]testPayment{0: {{keys, 5000}}})
+	client := NewSimulationClient(chain)
+	w := NewWallet()
+	err := RescanWallet(context.Background(), client, w, keys, 0, nil)
+	if err != nil {
+		t.Fatalf("cannot rescan: %s", err)
+	}
+	coins := w.Coins()
+	if len(coins) != 1 || coins[0].SerialNumber.Len() == 0 {
+		t.Fatalf("got %d coins, want one coin with a serial number", len(coins))
+	}
+	serialNumber := coins[0].SerialNumber
+
+	recipient, err := keys.ChangeAddress(0)
+	if err != nil {
+		t.Fatalf("cannot make a recipient address: %s", err)
+	}
+	txInDesc := coins[0].ToTxInDesc()
+	txInDesc.CoinSerialNumber = nil
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, []*TxInDesc{txInDesc})
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+	txDesc := NewTxDesc([]*TxInDesc{txInDesc}, []*TxOutDesc{NewTxOutDesc(recipient, 5000)}, 0, ringBlockDescs)
+
+	err = txDesc.ValidateInputsUnspent(client)
+	if err == nil || !strings.Contains(err.Error(), "CoinSerialNumber") {
+		t.Errorf("got error %v without a serial number or keys, want one naming CoinSerialNumber", err)
+	}
+	err = txDesc.ValidateInputsUnspent(client, &keys.SerialNoSecretKey)
+	if err != nil {
+		t.Errorf("got error %s for an unspent input", err)
+	}
+
+	_, _, err = client.SendSignedRawTx(newTestSignedRawTxSpending(t, serialNumber, ""))
+	if err != nil {
+		t.Fatalf("cannot send a tx spending the coin: %s", err)
+	}
+
+	err = txDesc.ValidateInputsUnspent(client, &keys.SerialNoSecretKey)
+	if err == nil || !strings.Contains(err.Error(), "already spent") {
+		t.Errorf("got error %v with a derived serial number, want the input to be spent", err)
+	}
+	txInDesc.CoinSerialNumber = serialNumber
+	err = txDesc.ValidateInputsUnspent(client)
+	if err == nil || !strings.Contains(err.Error(), "already spent") {
+		t.Errorf("got error %v with the coin's serial number, want the input to be spent", err)
+	}
+}
+
+func TestTxDescValidate(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+	inputValue := txDesc.TxInDescs[0].CoinValue
+
+	// withInputValue returns a copy of txDesc whose input has the given value and whose fee is the given fee.
+	withInputValue := func(value int64, fee int64) *TxDesc {
+		txInDesc := *txDesc.TxInDescs[0]
+		txInDesc.CoinValue = value
+		changed := *txDesc
+		changed.TxInDescs = []*TxInDesc{&txInDesc}
+		changed.TxFee = fee
+		return &changed
+	}
+	withoutRingBlocks := *txDesc
+	withoutRingBlocks.TxRingBlockDescs = map[int64]*TxBlockDesc{}
+
+	tests := []struct {
+		name          string
+		txDesc        *TxDesc
+		viewSecretKey *CryptoKey
+		wantErr       string
+	}{
+		{"valid", txDesc, nil, ""},
+		{"valid with the view key", txDesc, &keys.ViewSecretKey, ""},
+		{"unknown value sentinel", withInputValue(-1, txDesc.TxFee), nil, "value -1 is not positive"},
+		{"value mismatched with the view key", withInputValue(inputValue+1, txDesc.TxFee+1), &keys.ViewSecretKey, "does not match decoded value"},
+		{"value mismatch not checked without the view key", withInputValue(inputValue+1, txDesc.TxFee+1), nil, ""},
+		{"view key of other keys", txDesc, &otherKeys.ViewSecretKey, "value"},
+		{"missing ring blocks", &withoutRingBlocks, nil, "missing ring block"},
+		{"unbalanced", withInputValue(inputValue, txDesc.TxFee+1), nil, "do not equal"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.txDesc.Validate(test.viewSecretKey)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("got error %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestTxMemoString(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   Bytes
+		wantOK bool
+	}{
+		{"ascii", "invoice 42", Bytes("invoice 42"), true},
+		{"multibyte", "café ✓ 日本", Bytes{0x63, 0x61, 0x66, 0xc3, 0xa9, 0x20, 0xe2, 0x9c, 0x93, 0x20, 0xe6, 0x97, 0xa5, 0xe6, 0x9c, 0xac}, true},
+		{"empty", "", Bytes{}, true},
+		{"invalid bytes", string([]byte{0x61, 0xc3, 0x28, 0xff}), Bytes{0x61, 0xc3, 0x28, 0xff}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memo := NewTxMemoFromString(test.text)
+			if !bytes.Equal(memo, test.want) {
+				t.Errorf("got memo %x, want %x", []byte(memo), []byte(test.want))
+			}
+
+			// The node renders the memo of a tx as hex.
+			tx := &AbecTx{Memo: memo.HexString()}
+			text, ok := tx.MemoString()
+			if ok != test.wantOK {
+				t.Fatalf("got valid %t, want %t", ok, test.wantOK)
+			}
+			if ok && text != test.text {
+				t.Errorf("got memo string %q, want %q", text, test.text)
+			}
+		})
+	}
+
+	// The limit is in bytes, so it allows fewer characters of multibyte text.
+	limits, err := DefaultProtocolLimits()
+	if err != nil {
+		t.Fatalf("cannot get the protocol limits: %s", err)
+	}
+	text := strings.Repeat("日", MAX_TX_MEMO_SIZE/3+1)
+	err = limits.Validate(&TxDesc{TxMemo: NewTxMemoFromString(text)})
+	if err == nil {
+		t.Errorf("got no error for %d characters in %d bytes", MAX_TX_MEMO_SIZE/3+1, len(text))
+	}
+	err = limits.Validate(&TxDesc{TxMemo: NewTxMemoFromString(strings.Repeat("a", MAX_TX_MEMO_SIZE))})
+	if err != nil {
+		t.Errorf("got error %s for %d ascii characters", err, MAX_TX_MEMO_SIZE)
+	}
+}
+
+func TestSerialNumbersToReveal(t *testing.T) {
+	keys := newTestKeys(t)
+	txDesc := newTestTxDesc(t, keys)
+
+	serialNumbers, err := txDesc.SerialNumbersToReveal([]*CryptoKey{&keys.SerialNoSecretKey}, nil)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	unsignedRawTx, err := GenerateUnsignedRawTx(txDesc)
+	if err != nil {
+		t.Fatalf("cannot build the unsigned raw tx: %s", err)
+	}
+	signedRawTx, err := GenerateSignedRawTx(unsignedRawTx, []*CryptoKeysAndAddress{keys})
+	if err != nil {
+		t.Fatalf("cannot sign: %s", err)
+	}
+	msgTx := &wire.MsgTxAbe{}
+	err = msgTx.DeserializeFull(bytes.NewReader(signedRawTx.Bytes))
+	if err != nil {
+		t.Fatalf("cannot decode the signed tx: %s", err)
+	}
+	if len(msgTx.TxIns) != len(serialNumbers) {
+		t.Fatalf("got %d serial numbers for %d inputs", len(serialNumbers), len(msgTx.TxIns))
+	}
+	for i, txIn := range msgTx.TxIns {
+		if !bytes.Equal(serialNumbers[i], txIn.SerialNumber) {
+			t.Errorf("input %d: got serial number %s, the signed tx reveals %x", i, serialNumbers[i], txIn.SerialNumber)
+		}
+	}
+
+	// The ring blocks can also be given, and must cover the ring groups of the inputs.
+	got, err := txDesc.SerialNumbersToReveal([]*CryptoKey{&keys.SerialNoSecretKey}, txDesc.TxRingBlockDescs)
+	if err != nil || len(got) != 1 || !bytes.Equal(got[0], serialNumbers[0]) {
+		t.Errorf("got serial numbers %v and error %v with the ring blocks given", got, err)
+	}
+	_, err = txDesc.SerialNumbersToReveal([]*CryptoKey{&keys.SerialNoSecretKey}, map[int64]*TxBlockDesc{})
+	if err == nil {
+		t.Errorf("got no error without the ring blocks")
+	}
+	_, err = txDesc.SerialNumbersToReveal(nil, nil)
+	if err == nil {
+		t.Errorf("got no error without serial number secret keys")
+	}
+}
+
+func TestSignedRawTxValidate(t *testing.T) {
+	keys := newTestKeys(t)
+	unsignedRawTx, err := GenerateUnsignedRawTx(newTestTxDesc(t, keys))
+	if err != nil {
+		t.Fatalf("cannot build the unsigned raw tx: %s", err)
+	}
+	signedRawTx, err := GenerateSignedRawTx(unsignedRawTx, []*CryptoKeysAndAddress{keys})
+	if err != nil {
+		t.Fatalf("cannot sign: %s", err)
+	}
+
+	err = signedRawTx.Validate()
+	if err != nil {
+		t.Fatalf("got error %s for the signed tx", err)
+	}
+
+	data := signedRawTx.Bytes
+	signers := signedRawTx.Signers
+	tests := []struct {
+		name string
+		tx   *SignedRawTx
+	}{
+		{"empty", NewSignedRawTx(nil, signedRawTx.Txid, signers)},
+		{"truncated", NewSignedRawTx(data[:data.Len()-1], signedRawTx.Txid, signers)},
+		{"truncated to half", NewSignedRawTx(data[:data.Len()/2], signedRawTx.Txid, signers)},
+		{"trailing byte", NewSignedRawTx(append(append(Bytes(nil), data...), 0), signedRawTx.Txid, signers)},
+		{"txid mismatch", NewSignedRawTx(data, Txid{1}, signers)},
+		{"no signers", NewSignedRawTx(data, signedRawTx.Txid)},
+		{"too many signers", NewSignedRawTx(data, signedRawTx.Txid, append(signers, signers...))},
+	}
+	for _, test := range tests {
+		err := test.tx.Validate()
+		if err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Define constants.
+const (
+	TXID_LENGTH = 32
+)
+
+// Define the Txid data type.
+// Txid holds a transaction id in display order, i.e. the order of the hex txids rendered by the node and
+// the explorer. The api layer and wire.MsgTxAbe.TxId() use the reversed (internal) order.
+type Txid [TXID_LENGTH]byte
+
+// Define methods for Txid.
+func NewTxidFromHex(s string) (Txid, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return Txid{}, fmt.Errorf("txid %q is not valid hex: %s", s, err)
+	}
+
+	return NewTxidFromDisplayBytes(data)
+}
+
+func NewTxidFromDisplayBytes(data []byte) (Txid, error) {
+	var id Txid
+	if len(data) != TXID_LENGTH {
+		return id, fmt.Errorf("txid length %d is not %d", len(data), TXID_LENGTH)
+	}
+
+	copy(id[:], data)
+	return id, nil
+}
+
+func NewTxidFromAPIBytes(data []byte) (Txid, error) {
+	id, err := NewTxidFromDisplayBytes(data)
+	if err != nil {
+		return id, err
+	}
+
+	return id.Reverse(), nil
+}
+
+func (id Txid) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id Txid) HexString() string {
+	return id.String()
+}
+
+func (id Txid) Bytes() Bytes {
+	return AsBytes(append([]byte(nil), id[:]...))
+}
+
+func (id Txid) APIBytes() Bytes {
+	return id.Reverse().Bytes()
+}
+
+func (id Txid) Reverse() Txid {
+	var reversed Txid
+	for i := 0; i < TXID_LENGTH; i++ {
+		reversed[i] = id[TXID_LENGTH-i-1]
+	}
+
+	return reversed
+}
+
+func (id Txid) IsZero() bool {
+	return id == Txid{}
+}
+
+func (id Txid) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *Txid) UnmarshalText(text []byte) error {
+	parsed, err := NewTxidFromHex(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = parsed
+	return nil
+}
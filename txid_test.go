@@ -0,0 +1,73 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTxidWrongLength(t *testing.T) {
+	for _, length := range []int{0, 1, TXID_LENGTH - 1, TXID_LENGTH + 1} {
+		data := bytes.Repeat([]byte{0xab}, length)
+		if _, err := NewTxidFromDisplayBytes(data); err == nil {
+			t.Errorf("NewTxidFromDisplayBytes of %d bytes: got no error", length)
+		}
+		if _, err := NewTxidFromAPIBytes(data); err == nil {
+			t.Errorf("NewTxidFromAPIBytes of %d bytes: got no error", length)
+		}
+		if _, err := NewTxidFromHex(strings.Repeat("ab", length)); err == nil {
+			t.Errorf("NewTxidFromHex of %d bytes: got no error", length)
+		}
+	}
+	if _, err := NewTxidFromDisplayBytes(nil); err == nil {
+		t.Errorf("NewTxidFromDisplayBytes(nil): got no error")
+	}
+	if _, err := NewTxidFromHex(strings.Repeat("zz", TXID_LENGTH)); err == nil {
+		t.Errorf("NewTxidFromHex of invalid hex: got no error")
+	}
+	var id Txid
+	if err := json.Unmarshal([]byte(`"abcd"`), &id); err == nil {
+		t.Errorf("unmarshalled a txid of 2 bytes")
+	}
+}
+
+func TestTxidOrder(t *testing.T) {
+	display := make([]byte, TXID_LENGTH)
+	for i := range display {
+		display[i] = byte(i)
+	}
+	api := make([]byte, TXID_LENGTH)
+	for i := range api {
+		api[i] = byte(TXID_LENGTH - 1 - i)
+	}
+
+	fromDisplay, err := NewTxidFromDisplayBytes(display)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	fromAPI, err := NewTxidFromAPIBytes(api)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	fromHex, err := NewTxidFromHex(fromDisplay.HexString())
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if fromAPI != fromDisplay || fromHex != fromDisplay {
+		t.Errorf("got %s from api bytes and %s from hex, want %s", fromAPI, fromHex, fromDisplay)
+	}
+	if !bytes.Equal(fromDisplay.Bytes(), display) || !bytes.Equal(fromDisplay.APIBytes(), api) {
+		t.Errorf("got display bytes %x and api bytes %x", []byte(fromDisplay.Bytes()), []byte(fromDisplay.APIBytes()))
+	}
+	if reversed := fromDisplay.Reverse(); !bytes.Equal(reversed[:], api) || reversed.Reverse() != fromDisplay {
+		t.Errorf("got reversed txid %s, want %x", reversed, api)
+	}
+
+	// Bytes returns a copy that does not alias the txid.
+	copied := fromDisplay.Bytes()
+	copied[0] = 0xff
+	if fromDisplay[0] != 0x00 {
+		t.Errorf("changing the bytes changed the txid")
+	}
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abesuite/abec/chainhash"
+)
+
+// The vectors in testdata/address_vectors.json pin the derivation of keys and addresses, so that a change in
+// it cannot silently break compatibility with existing wallets.
+//
+// The value public key at the end of a crypto address comes from the Kyber KEM of liboqs. The seed vectors
+// therefore pin what is derived without it: the mnemonic, the crypto seed of each index, the spend and serial
+// number secret keys and the fingerprint, which is the hash of the coin address. The address vectors start
+// from the crypto address of a seed vector with its value public key replaced by fixed filler, see
+// vectorCryptoAddress, and pin the abel and short addresses derived from it for each chain id.
+type addressVectors struct {
+	Seeds []struct {
+		Seed     string `json:"seed"`
+		Mnemonic string `json:"mnemonic"`
+		Keys     []struct {
+			Index                   uint32 `json:"index"`
+			CryptoSeed              string `json:"cryptoSeed"`
+			SpendSecretKeySha256    string `json:"spendSecretKeySha256"`
+			SerialNoSecretKeySha256 string `json:"serialNoSecretKeySha256"`
+			Fingerprint             string `json:"fingerprint"`
+		} `json:"keys"`
+	} `json:"seeds"`
+	Addresses struct {
+		Seed                string `json:"seed"`
+		Index               uint32 `json:"index"`
+		CryptoAddressSha256 string `json:"cryptoAddressSha256"`
+		Fingerprint         string `json:"fingerprint"`
+		Chains              []struct {
+			ChainID           int8   `json:"chainID"`
+			AbelAddressSha256 string `json:"abelAddressSha256"`
+			Checksum          string `json:"checksum"`
+			ShortAddress      string `json:"shortAddress"`
+		} `json:"chains"`
+	} `json:"addresses"`
+	Txids []struct {
+		APIBytes string `json:"apiBytes"`
+		Display  string `json:"display"`
+	} `json:"txids"`
+}
+
+func loadAddressVectors(t *testing.T) *addressVectors {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "address_vectors.json"))
+	if err != nil {
+		t.Fatalf("cannot read the vectors: %s", err)
+	}
+	vectors := &addressVectors{}
+	err = json.Unmarshal(data, vectors)
+	if err != nil {
+		t.Fatalf("cannot parse the vectors: %s", err)
+	}
+
+	return vectors
+}
+
+func TestSeedVectors(t *testing.T) {
+	for _, vector := range loadAddressVectors(t).Seeds {
+		seed := MakeBytesFromHexString(vector.Seed)
+
+		mnemonic, err := SeedToMnemonic(seed)
+		if err != nil {
+			t.Fatalf("seed %s: cannot encode the mnemonic: %s", vector.Seed, err)
+		}
+		if mnemonic != vector.Mnemonic {
+			t.Errorf("seed %s: got mnemonic %q, want %q", vector.Seed, mnemonic, vector.Mnemonic)
+		}
+
+		for _, keyVector := range vector.Keys {
+			cryptoSeed, err := GenerateCryptoSeedFromMnemonic(strings.Fields(vector.Mnemonic), uint64(keyVector.Index))
+			if err != nil {
+				t.Fatalf("seed %s index %d: cannot derive the crypto seed: %s", vector.Seed, keyVector.Index, err)
+			}
+			if got := AsBytes(cryptoSeed).HexString(); got != keyVector.CryptoSeed {
+				t.Errorf("seed %s index %d: got crypto seed %s, want %s", vector.Seed, keyVector.Index, got, keyVector.CryptoSeed)
+			}
+
+			keys, err := DeriveCryptoKeysAndAddress(seed, keyVector.Index)
+			if err != nil {
+				t.Fatalf("seed %s index %d: cannot derive the keys: %s", vector.Seed, keyVector.Index, err)
+			}
+			if got := keys.SpendSecretKey.Bytes.Sha256().HexString(); got != keyVector.SpendSecretKeySha256 {
+				t.Errorf("seed %s index %d: got spend secret key hash %s, want %s", vector.Seed, keyVector.Index, got, keyVector.SpendSecretKeySha256)
+			}
+			if got := keys.SerialNoSecretKey.Bytes.Sha256().HexString(); got != keyVector.SerialNoSecretKeySha256 {
+				t.Errorf("seed %s index %d: got serial number secret key hash %s, want %s", vector.Seed, keyVector.Index, got, keyVector.SerialNoSecretKeySha256)
+			}
+			if got := keys.CryptoAddress.Fingerprint().HexString(); got != keyVector.Fingerprint {
+				t.Errorf("seed %s index %d: got fingerprint %s, want %s", vector.Seed, keyVector.Index, got, keyVector.Fingerprint)
+			}
+		}
+
+		// The phrase decodes back to the seed, and index 0 is the key set of the phrase itself.
+		decodedSeed, err := MnemonicToSeed(vector.Mnemonic)
+		if err != nil {
+			t.Fatalf("seed %s: cannot decode the mnemonic: %s", vector.Seed, err)
+		}
+		if got := decodedSeed.HexString(); got != vector.Seed {
+			t.Errorf("seed %s: got seed %s from the mnemonic", vector.Seed, got)
+		}
+		cryptoSeed, err := MnemonicToCryptoSeed(vector.Mnemonic)
+		if err != nil {
+			t.Fatalf("seed %s: cannot decode the mnemonic: %s", vector.Seed, err)
+		}
+		if got := cryptoSeed.HexString(); got != vector.Keys[0].CryptoSeed {
+			t.Errorf("seed %s: got crypto seed %s from the mnemonic, want %s", vector.Seed, got, vector.Keys[0].CryptoSeed)
+		}
+	}
+}
+
+func TestAddressVectors(t *testing.T) {
+	vectors := loadAddressVectors(t).Addresses
+
+	cryptoAddress := vectorCryptoAddress(t, MakeBytesFromHexString(vectors.Seed), vectors.Index)
+	if got := cryptoAddress.Hash().HexString(); got != vectors.CryptoAddressSha256 {
+		t.Fatalf("got crypto address hash %s, want %s", got, vectors.CryptoAddressSha256)
+	}
+	if got := cryptoAddress.Fingerprint().HexString(); got != vectors.Fingerprint {
+		t.Errorf("got fingerprint %s, want %s", got, vectors.Fingerprint)
+	}
+
+	for _, vector := range vectors.Chains {
+		abelAddress := NewAbelAddressFromCryptoAddress(cryptoAddress, vector.ChainID)
+		if got := abelAddress.Hash().HexString(); got != vector.AbelAddressSha256 {
+			t.Errorf("chain %d: got abel address hash %s, want %s", vector.ChainID, got, vector.AbelAddressSha256)
+		}
+		if got := abelAddress.GetChecksum().HexString(); got != vector.Checksum {
+			t.Errorf("chain %d: got checksum %s, want %s", vector.ChainID, got, vector.Checksum)
+		}
+		if got := abelAddress.GetShortAbelAddress().HexString(); got != vector.ShortAddress {
+			t.Errorf("chain %d: got short address %s, want %s", vector.ChainID, got, vector.ShortAddress)
+		}
+
+		// The address must also come back from its own encoding.
+		parsed, err := NewAbelAddressFromHex(abelAddress.HexString())
+		if err != nil {
+			t.Fatalf("chain %d: cannot parse the abel address: %s", vector.ChainID, err)
+		}
+		if parsed.GetChainID() != vector.ChainID || !parsed.GetCryptoAddress().Equal(cryptoAddress) {
+			t.Errorf("chain %d: parsed address has chain id %d and a different crypto address", vector.ChainID, parsed.GetChainID())
+		}
+	}
+}
+
+// vectorCryptoAddress returns the crypto address of the keys derived from seed at index, with the value public
+// key after the coin address replaced by fixed filler. The value public key comes from liboqs, so the filler
+// keeps the vectors the same whichever build of liboqs the tests run with.
+func vectorCryptoAddress(t *testing.T, seed Bytes, index uint32) *CryptoAddress {
+	t.Helper()
+
+	keys, err := DeriveCryptoKeysAndAddress(seed, index)
+	if err != nil {
+		t.Fatalf("cannot derive the keys: %s", err)
+	}
+	coinAddress, err := keys.CryptoAddress.GetCoinAddress()
+	if err != nil {
+		t.Fatalf("cannot get the coin address: %s", err)
+	}
+
+	// The crypto address is the 4 byte crypto scheme, the coin address and the value public key.
+	data := make([]byte, keys.CryptoAddress.Data().Len())
+	copy(data, keys.CryptoAddress.Data())
+	for i := 4 + coinAddress.Data().Len(); i < len(data); i++ {
+		data[i] = byte(i)
+	}
+	cryptoAddress := NewCryptoAddress(data)
+	err = cryptoAddress.Validate()
+	if err != nil {
+		t.Fatalf("crypto address is not valid: %s", err)
+	}
+
+	return cryptoAddress
+}
+
+func TestTxidVectors(t *testing.T) {
+	for _, vector := range loadAddressVectors(t).Txids {
+		apiBytes := MakeBytesFromHexString(vector.APIBytes)
+
+		txid, err := NewTxidFromAPIBytes(apiBytes)
+		if err != nil {
+			t.Fatalf("cannot make the txid: %s", err)
+		}
+		if got := txid.String(); got != vector.Display {
+			t.Errorf("got txid %s, want %s", got, vector.Display)
+		}
+		if got := txid.APIBytes().HexString(); got != vector.APIBytes {
+			t.Errorf("got api bytes %s, want %s", got, vector.APIBytes)
+		}
+
+		// abec renders a hash in the same reversed order.
+		hash, err := chainhash.NewHash(apiBytes)
+		if err != nil {
+			t.Fatalf("cannot make the hash: %s", err)
+		}
+		if hash.String() != vector.Display {
+			t.Errorf("abec renders the hash as %s, want %s", hash.String(), vector.Display)
+		}
+	}
+}
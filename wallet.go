@@ -0,0 +1,454 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Define constants.
+const (
+	COINBASE_MATURITY           = 200
+	WALLET_EVENT_CHANNEL_BUFFER = 64
+)
+
+type WalletEventType int
+
+const (
+	COIN_RECEIVED_EVENT WalletEventType = iota
+	COIN_SPENT_EVENT
+	BALANCE_CHANGED_EVENT
+)
+
+func (eventType WalletEventType) String() string {
+	switch eventType {
+	case COIN_RECEIVED_EVENT:
+		return "CoinReceived"
+	case COIN_SPENT_EVENT:
+		return "CoinSpent"
+	case BALANCE_CHANGED_EVENT:
+		return "BalanceChanged"
+	default:
+		return "UnknownEvent"
+	}
+}
+
+// Define the WalletEvent data type.
+type WalletEvent struct {
+	Type WalletEventType
+	Coin *Coin
+}
+
+// Define the Wallet data type.
+type Wallet struct {
+	mutex            sync.RWMutex
+	coins            []*Coin
+	unspentByValue   []*Coin
+	scannedHeight    int64
+	subscriberMutex  sync.RWMutex
+	subscribers      map[int]chan WalletEvent
+	nextSubscriberID int
+}
+
+// Define methods for Wallet.
+func NewWallet() *Wallet {
+	return &Wallet{
+		coins:          make([]*Coin, 0),
+		unspentByValue: make([]*Coin, 0),
+		scannedHeight:  -1,
+		subscribers:    make(map[int]chan WalletEvent),
+	}
+}
+
+// Subscribe returns a channel of wallet events and a function to unsubscribe. Events are delivered
+// through a buffered channel and dropped for a subscriber whose buffer is full, so a slow subscriber
+// never blocks the wallet.
+func (w *Wallet) Subscribe() (<-chan WalletEvent, func()) {
+	w.subscriberMutex.Lock()
+	defer w.subscriberMutex.Unlock()
+
+	id := w.nextSubscriberID
+	w.nextSubscriberID++
+	events := make(chan WalletEvent, WALLET_EVENT_CHANNEL_BUFFER)
+	w.subscribers[id] = events
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			w.subscriberMutex.Lock()
+			defer w.subscriberMutex.Unlock()
+
+			delete(w.subscribers, id)
+			close(events)
+		})
+	}
+
+	return events, unsubscribe
+}
+
+func (w *Wallet) emit(eventType WalletEventType, coin *Coin) {
+	w.subscriberMutex.RLock()
+	defer w.subscriberMutex.RUnlock()
+
+	for _, events := range w.subscribers {
+		select {
+		case events <- WalletEvent{Type: eventType, Coin: coin}:
+		default:
+			LOG.debug("Dropped %s event for a slow subscriber\n", eventType)
+		}
+	}
+}
+
+func (w *Wallet) AddCoin(coin *Coin) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.coins = append(w.coins, coin)
+	w.indexCoin(coin)
+	w.emit(COIN_RECEIVED_EVENT, coin)
+	w.emit(BALANCE_CHANGED_EVENT, nil)
+}
+
+func (w *Wallet) AddCoins(coins []*Coin) int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	knownIDs := make(map[string]bool, len(w.coins)+len(coins))
+	for _, coin := range w.coins {
+		knownIDs[coin.ID.String()] = true
+	}
+
+	added := 0
+	for _, coin := range coins {
+		id := coin.ID.String()
+		if knownIDs[id] {
+			continue
+		}
+
+		knownIDs[id] = true
+		w.coins = append(w.coins, coin)
+		w.indexCoin(coin)
+		w.emit(COIN_RECEIVED_EVENT, coin)
+		added++
+	}
+
+	if added > 0 {
+		w.emit(BALANCE_CHANGED_EVENT, nil)
+	}
+
+	return added
+}
+
+func (w *Wallet) Coins() []*Coin {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	coins := make([]*Coin, len(w.coins))
+	copy(coins, w.coins)
+	return coins
+}
+
+func (w *Wallet) UnspentCoins() []*Coin {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	coins := make([]*Coin, 0, len(w.coins))
+	for _, coin := range w.coins {
+		if !coin.Spent {
+			coins = append(coins, coin)
+		}
+	}
+
+	return coins
+}
+
+// CoinsSortedByValue returns the unspent coins, largest first.
+func (w *Wallet) CoinsSortedByValue() []*Coin {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	coins := make([]*Coin, len(w.unspentByValue))
+	copy(coins, w.unspentByValue)
+	return coins
+}
+
+// UnspentCoinsForAccount returns the unspent coins of the given account, largest first.
+func (w *Wallet) UnspentCoinsForAccount(accountIndex uint32) []*Coin {
+	return FilterCoinsByAccount(w.CoinsSortedByValue(), accountIndex)
+}
+
+// CoinsWithLabel returns the unspent coins with the given label, largest first. Labels are read under the
+// wallet lock, so it is safe to call while other goroutines relabel coins with SetCoinLabel.
+func (w *Wallet) CoinsWithLabel(label string) []*Coin {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return FilterCoinsByLabel(w.unspentByValue, label)
+}
+
+// SetCoinLabel labels the coin with the given id and reports whether the wallet has such a coin.
+func (w *Wallet) SetCoinLabel(id CoinID, label string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, coin := range w.coins {
+		if coin.ID.Equal(id) {
+			coin.Label = label
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnspentCoinsMinConf returns the mature unspent coins with at least minConf confirmations, largest first.
+func (w *Wallet) UnspentCoinsMinConf(currentHeight int64, minConf int64) []*Coin {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	coins := make([]*Coin, 0, len(w.unspentByValue))
+	for _, coin := range w.unspentByValue {
+		if !coin.IsMature(currentHeight) {
+			continue
+		}
+		if coin.Confirmations(currentHeight) < minConf {
+			continue
+		}
+		coins = append(coins, coin)
+	}
+
+	return coins
+}
+
+// MarkSpent marks the coin with the given serial number as spent by the tx at height. Coins whose serial number
+// is not derived yet are never matched, and neither is an empty serial number.
+func (w *Wallet) MarkSpent(serialNumber Bytes, txHash Bytes, height int64) bool {
+	if len(serialNumber) == 0 {
+		return false
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, coin := range w.coins {
+		if !coin.Spent && len(coin.SerialNumber) > 0 && bytes.Equal(coin.SerialNumber, serialNumber) {
+			coin.Spent = true
+			coin.SpentByTxHash = txHash
+			coin.SpentAtHeight = height
+			w.unindexCoin(coin)
+			w.emit(COIN_SPENT_EVENT, coin)
+			w.emit(BALANCE_CHANGED_EVENT, nil)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *Wallet) ApplyBlock(block *AbecBlock, index *FingerprintIndex) ([]*Coin, error) {
+	coins, err := ScanBlockMultiWallet(block, index)
+	if err != nil {
+		return nil, err
+	}
+	w.AddCoins(coins)
+
+	for _, tx := range block.RawTxs {
+		txHash, err := hex.DecodeString(tx.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("txid %q is not valid hex: %s", tx.TxID, err)
+		}
+
+		for _, vin := range tx.Vin {
+			serialNumber, err := hex.DecodeString(vin.SerialNumber)
+			if err != nil {
+				return nil, fmt.Errorf("serial number %q is not valid hex: %s", vin.SerialNumber, err)
+			}
+			w.MarkSpent(serialNumber, txHash, block.Height)
+		}
+	}
+
+	return coins, nil
+}
+
+// DeriveSerialNumbers derives the serial numbers of the coins found by a block scan whose ring group is
+// complete at tipHeight, fetching their ring blocks with client, so that ApplyBlock can tell when they are
+// spent. It returns how many it derived.
+func (w *Wallet) DeriveSerialNumbers(client *AbecRPCClient, tipHeight int64) (int, error) {
+	return w.DeriveSerialNumbersContext(context.Background(), client, tipHeight)
+}
+
+func (w *Wallet) DeriveSerialNumbersContext(ctx context.Context, client *AbecRPCClient, tipHeight int64) (int, error) {
+	w.mutex.RLock()
+	pending := make([]*Coin, 0)
+	txInDescs := make([]*TxInDesc, 0)
+	for _, coin := range w.coins {
+		ringBlockHeights := GetRingBlockHeights(coin.BlockHeight)
+		if coin.SerialNumber != nil || coin.serialNoSecretKey == nil || ringBlockHeights[len(ringBlockHeights)-1] > tipHeight {
+			continue
+		}
+		pending = append(pending, coin)
+		txInDescs = append(txInDescs, coin.ToTxInDesc())
+	}
+	w.mutex.RUnlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	ringBlockDescs, err := FetchRingBlocksForInputsContext(ctx, client, txInDescs)
+	if err != nil {
+		return 0, err
+	}
+
+	serialNumbers := make([]Bytes, len(pending))
+	for i, coin := range pending {
+		serialNumbers[i], err = coin.deriveSerialNumber(ringBlockDescs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for i, coin := range pending {
+		coin.SerialNumber = serialNumbers[i]
+	}
+
+	return len(pending), nil
+}
+
+// ScannedHeight returns the height up to which every block has been applied to the wallet, or -1 if none has.
+func (w *Wallet) ScannedHeight() int64 {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.scannedHeight
+}
+
+func (w *Wallet) SetScannedHeight(height int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.scannedHeight = height
+}
+
+func (w *Wallet) RevertBlock(height int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.scannedHeight >= height {
+		w.scannedHeight = height - 1
+	}
+
+	// Drop the coins created in the orphaned block and restore the coins it spent.
+	changed := false
+	coins := make([]*Coin, 0, len(w.coins))
+	for _, coin := range w.coins {
+		if coin.BlockHeight == height {
+			changed = true
+			continue
+		}
+
+		if coin.Spent && coin.SpentAtHeight == height {
+			coin.Spent = false
+			coin.SpentByTxHash = nil
+			coin.SpentAtHeight = 0
+			changed = true
+		}
+		coins = append(coins, coin)
+	}
+
+	w.coins = coins
+	if changed {
+		w.rebuildValueIndex()
+		w.emit(BALANCE_CHANGED_EVENT, nil)
+	}
+}
+
+func (w *Wallet) Balance() int64 {
+	balance := int64(0)
+	for _, coin := range w.UnspentCoins() {
+		balance += coin.Value
+	}
+
+	return balance
+}
+
+// CanAfford reports whether the mature coins with at least one confirmation cover amount plus feeEstimate,
+// and if not, by how much they fall short.
+func (w *Wallet) CanAfford(amount int64, feeEstimate int64, currentHeight int64) (bool, int64) {
+	spendable := int64(0)
+	for _, coin := range w.UnspentCoinsMinConf(currentHeight, 1) {
+		spendable += coin.Value
+	}
+
+	shortfall := amount + feeEstimate - spendable
+	if shortfall > 0 {
+		return false, shortfall
+	}
+
+	return true, 0
+}
+
+// BalanceBreakdown sums the unspent coins by bucket in one pass. Coinbase coins with at most coinbaseMaturity
+// confirmations are immature, other coins with at least confirmedThreshold confirmations are confirmed, and
+// the rest are pending.
+func (w *Wallet) BalanceBreakdown(currentHeight int64, coinbaseMaturity int64, confirmedThreshold int64) (confirmed int64, pending int64, immature int64) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	for _, coin := range w.unspentByValue {
+		confirmations := coin.Confirmations(currentHeight)
+		switch {
+		case coin.IsCoinbase && confirmations <= coinbaseMaturity:
+			immature += coin.Value
+		case confirmations >= confirmedThreshold:
+			confirmed += coin.Value
+		default:
+			pending += coin.Value
+		}
+	}
+
+	return confirmed, pending, immature
+}
+
+// The value index keeps the unspent coins sorted by descending value. It is only touched with the wallet mutex held.
+func (w *Wallet) indexCoin(coin *Coin) {
+	if coin.Spent {
+		return
+	}
+
+	// Insert after the coins of equal value to keep the order stable.
+	i := sort.Search(len(w.unspentByValue), func(i int) bool {
+		return w.unspentByValue[i].Value < coin.Value
+	})
+	w.unspentByValue = append(w.unspentByValue, nil)
+	copy(w.unspentByValue[i+1:], w.unspentByValue[i:])
+	w.unspentByValue[i] = coin
+}
+
+func (w *Wallet) unindexCoin(coin *Coin) {
+	i := sort.Search(len(w.unspentByValue), func(i int) bool {
+		return w.unspentByValue[i].Value <= coin.Value
+	})
+	for ; i < len(w.unspentByValue) && w.unspentByValue[i].Value == coin.Value; i++ {
+		if w.unspentByValue[i] == coin {
+			w.unspentByValue = append(w.unspentByValue[:i], w.unspentByValue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *Wallet) rebuildValueIndex() {
+	unspentCoins := make([]*Coin, 0, len(w.coins))
+	for _, coin := range w.coins {
+		if !coin.Spent {
+			unspentCoins = append(unspentCoins, coin)
+		}
+	}
+
+	w.unspentByValue = orderCoinsLargestFirst(unspentCoins)
+}
@@ -0,0 +1,431 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func newTestCoin(index uint8, value int64, height int64) *Coin {
+	return &Coin{
+		ID:          CoinID{TxHash: Txid{index}, Index: index},
+		Value:       value,
+		BlockHeight: height,
+	}
+}
+
+func TestUnspentCoinsMinConf(t *testing.T) {
+	coinbase := newTestCoin(1, 500, 10)
+	coinbase.IsCoinbase = true
+	spent := newTestCoin(2, 400, 10)
+	spent.Spent = true
+
+	w := NewWallet()
+	w.AddCoins([]*Coin{
+		newTestCoin(3, 100, 10),
+		newTestCoin(4, 300, 15),
+		newTestCoin(5, 200, 20),
+		coinbase,
+		spent,
+	})
+
+	tests := []struct {
+		name          string
+		currentHeight int64
+		minConf       int64
+		want          []int64
+	}{
+		{"all confirmed", 20, 1, []int64{300, 200, 100}},
+		{"excludes unconfirmed", 19, 1, []int64{300, 100}},
+		{"minimum confirmations", 20, 6, []int64{300, 100}},
+		{"none deep enough", 20, 12, []int64{}},
+		{"mature coinbase", 10 + COINBASE_MATURITY, 1, []int64{500, 300, 200, 100}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			coins := w.UnspentCoinsMinConf(test.currentHeight, test.minConf)
+			if len(coins) != len(test.want) {
+				t.Fatalf("got %d coins, want %d", len(coins), len(test.want))
+			}
+			for i, coin := range coins {
+				if coin.Value != test.want[i] {
+					t.Errorf("coin %d has value %d, want %d", i, coin.Value, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCoinsWithLabelWhileRelabelling(t *testing.T) {
+	w := NewWallet()
+	w.AddCoins([]*Coin{newTestCoin(1, 100, 10), newTestCoin(2, 200, 10)})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			w.SetCoinLabel(CoinID{TxHash: Txid{1}, Index: 1}, "savings")
+			w.SetCoinLabel(CoinID{TxHash: Txid{1}, Index: 1}, "")
+		}
+		w.SetCoinLabel(CoinID{TxHash: Txid{2}, Index: 2}, "savings")
+	}()
+	for i := 0; i < 1000; i++ {
+		for _, coin := range w.CoinsWithLabel("savings") {
+			if coin.ID.Index != 1 && coin.ID.Index != 2 {
+				t.Fatalf("got unexpected coin %s", coin.ID)
+			}
+		}
+	}
+	<-done
+
+	coins := w.CoinsWithLabel("savings")
+	if len(coins) != 1 || coins[0].Value != 200 {
+		t.Errorf("got %d coins labelled savings, want the coin of value 200", len(coins))
+	}
+}
+
+// expectEvents checks that exactly the given events are waiting in events.
+func expectEvents(t *testing.T, events <-chan WalletEvent, want ...WalletEvent) {
+	t.Helper()
+
+	for i, wantEvent := range want {
+		select {
+		case event := <-events:
+			if event.Type != wantEvent.Type || event.Coin != wantEvent.Coin {
+				t.Errorf("event %d is %s, want %s", i, event.Type, wantEvent.Type)
+			}
+		default:
+			t.Fatalf("got %d events, want %d", i, len(want))
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("got unexpected %s event", event.Type)
+	default:
+	}
+}
+
+// expectUnspentValues checks the value index of w.
+func expectUnspentValues(t *testing.T, w *Wallet, want ...int64) {
+	t.Helper()
+
+	coins := w.CoinsSortedByValue()
+	if len(coins) != len(want) {
+		t.Fatalf("got %d unspent coins, want %d", len(coins), len(want))
+	}
+	for i, coin := range coins {
+		if coin.Value != want[i] {
+			t.Errorf("unspent coin %d has value %d, want %d", i, coin.Value, want[i])
+		}
+	}
+}
+
+func TestApplyAndRevertBlockSpendingWalletCoin(t *testing.T) {
+	spent := newTestCoin(1, 300, 10)
+	spent.SerialNumber = Bytes{0xa1}
+	other := newTestCoin(2, 200, 10)
+	other.SerialNumber = Bytes{0xb2}
+	underived := newTestCoin(3, 100, 10)
+
+	w := NewWallet()
+	w.AddCoins([]*Coin{spent, other, underived})
+	events, unsubscribe := w.Subscribe()
+	defer unsubscribe()
+
+	txHash := bytes.Repeat([]byte{0x22}, 32)
+	block := &AbecBlock{
+		Height:    12,
+		BlockHash: hex.EncodeToString(bytes.Repeat([]byte{0x11}, 32)),
+		RawTxs: []*AbecTx{{
+			TxID: hex.EncodeToString(txHash),
+			// The empty serial number must not match the coin whose serial number is not derived yet.
+			Vin: []*AbecTxVin{{SerialNumber: "a1"}, {SerialNumber: ""}},
+		}},
+	}
+	_, err := w.ApplyBlock(block, NewFingerprintIndex())
+	if err != nil {
+		t.Fatalf("cannot apply the block: %s", err)
+	}
+
+	if !spent.Spent || spent.SpentAtHeight != 12 || !bytes.Equal(spent.SpentByTxHash, txHash) {
+		t.Errorf("got spent %t at height %d by %x, want spent at height 12 by %x", spent.Spent, spent.SpentAtHeight, spent.SpentByTxHash, txHash)
+	}
+	if other.Spent || underived.Spent {
+		t.Errorf("coins not spent by the block are marked spent")
+	}
+	expectUnspentValues(t, w, 200, 100)
+	expectEvents(t, events, WalletEvent{Type: COIN_SPENT_EVENT, Coin: spent}, WalletEvent{Type: BALANCE_CHANGED_EVENT})
+
+	w.RevertBlock(12)
+
+	if spent.Spent || spent.SpentAtHeight != 0 || spent.SpentByTxHash != nil {
+		t.Errorf("got spent %t at height %d by %x after the revert, want unspent", spent.Spent, spent.SpentAtHeight, spent.SpentByTxHash)
+	}
+	expectUnspentValues(t, w, 300, 200, 100)
+	expectEvents(t, events, WalletEvent{Type: BALANCE_CHANGED_EVENT})
+}
+
+func TestWalletEventsOnDiscoveryAndSpend(t *testing.T) {
+	keys := newTestKeys(t)
+	client := NewSimulationClient(newTestChain(t, 3, map[int64][]testPayment{0: {{keys, 5000}}}))
+
+	blockBytes, err := client.GetBlockBytesByHeight(0)
+	if err != nil {
+		t.Fatalf("cannot get block 0: %s", err)
+	}
+	block, err := DecodeAbecBlock(blockBytes)
+	if err != nil {
+		t.Fatalf("cannot decode block 0: %s", err)
+	}
+	coins, err := ScanBlockForCoins(block, keys)
+	if err != nil || len(coins) != 1 {
+		t.Fatalf("got %d coins and error %v, want one coin", len(coins), err)
+	}
+	coin := coins[0]
+	ringBlockDescs, err := FetchRingBlocksForInputs(client, []*TxInDesc{coin.ToTxInDesc()})
+	if err != nil {
+		t.Fatalf("cannot fetch the ring blocks: %s", err)
+	}
+	err = coin.DeriveSerialNumber(ringBlockDescs)
+	if err != nil {
+		t.Fatalf("cannot derive the serial number: %s", err)
+	}
+
+	w := NewWallet()
+	events, unsubscribe := w.Subscribe()
+	otherEvents, otherUnsubscribe := w.Subscribe()
+	defer otherUnsubscribe()
+
+	w.AddCoins(coins)
+	expectEvents(t, events, WalletEvent{Type: COIN_RECEIVED_EVENT, Coin: coin}, WalletEvent{Type: BALANCE_CHANGED_EVENT})
+
+	if !w.MarkSpent(coin.SerialNumber, Bytes{0x33}, 2) {
+		t.Fatalf("the coin is not marked spent")
+	}
+	expectEvents(t, events, WalletEvent{Type: COIN_SPENT_EVENT, Coin: coin}, WalletEvent{Type: BALANCE_CHANGED_EVENT})
+	expectEvents(t, events)
+
+	// Marking it again changes nothing and emits nothing.
+	if w.MarkSpent(coin.SerialNumber, Bytes{0x33}, 2) {
+		t.Errorf("the spent coin is marked spent again")
+	}
+	expectEvents(t, events)
+
+	// Every subscriber gets the events, and an unsubscribed one gets no more.
+	expectEvents(t, otherEvents,
+		WalletEvent{Type: COIN_RECEIVED_EVENT, Coin: coin}, WalletEvent{Type: BALANCE_CHANGED_EVENT},
+		WalletEvent{Type: COIN_SPENT_EVENT, Coin: coin}, WalletEvent{Type: BALANCE_CHANGED_EVENT})
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Errorf("got an event after unsubscribing")
+	}
+}
+
+func TestWalletEventsDropForSlowSubscriber(t *testing.T) {
+	w := NewWallet()
+	_, unsubscribe := w.Subscribe()
+	defer unsubscribe()
+
+	// The subscriber never reads, so emitting twice as many events as its buffer holds must not block.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < WALLET_EVENT_CHANNEL_BUFFER; i++ {
+			w.AddCoin(newTestCoin(uint8(i), 100, 10))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the wallet is blocked by a slow subscriber")
+	}
+}
+
+func TestBalanceBreakdown(t *testing.T) {
+	const currentHeight = 300
+	const coinbaseMaturity = 200
+	const confirmedThreshold = 6
+
+	// The coinbase coins have coinbaseMaturity-1, coinbaseMaturity and coinbaseMaturity+1 confirmations, and
+	// the others confirmedThreshold-1, confirmedThreshold and none yet.
+	young := newTestCoin(1, 1, currentHeight-coinbaseMaturity+2)
+	atMaturity := newTestCoin(2, 10, currentHeight-coinbaseMaturity+1)
+	mature := newTestCoin(3, 100, currentHeight-coinbaseMaturity)
+	for _, coin := range []*Coin{young, atMaturity, mature} {
+		coin.IsCoinbase = true
+	}
+	unconfirmed := newTestCoin(4, 1000, currentHeight-confirmedThreshold+2)
+	confirmed := newTestCoin(5, 10000, currentHeight-confirmedThreshold+1)
+	future := newTestCoin(6, 100000, currentHeight+1)
+	spent := newTestCoin(7, 1000000, 1)
+	spent.Spent = true
+
+	w := NewWallet()
+	w.AddCoins([]*Coin{young, atMaturity, mature, unconfirmed, confirmed, future, spent})
+
+	gotConfirmed, gotPending, gotImmature := w.BalanceBreakdown(currentHeight, coinbaseMaturity, confirmedThreshold)
+	if gotConfirmed != 100+10000 || gotPending != 1000+100000 || gotImmature != 1+10 {
+		t.Errorf("got confirmed %d, pending %d and immature %d, want %d, %d and %d",
+			gotConfirmed, gotPending, gotImmature, 100+10000, 1000+100000, 1+10)
+	}
+
+	// A coinbase coin is immature in the breakdown exactly when IsMature says so.
+	if atMaturity.Confirmations(currentHeight) != coinbaseMaturity || atMaturity.IsMature(currentHeight) {
+		t.Errorf("coin with %d confirmations is mature", atMaturity.Confirmations(currentHeight))
+	}
+	if !mature.IsMature(currentHeight) {
+		t.Errorf("coin with %d confirmations is immature", mature.Confirmations(currentHeight))
+	}
+
+	// Once the young coinbase matures, it moves to confirmed.
+	gotConfirmed, gotPending, gotImmature = w.BalanceBreakdown(currentHeight+2, coinbaseMaturity, confirmedThreshold)
+	if gotConfirmed != 1+10+100+1000+10000 || gotPending != 100000 || gotImmature != 0 {
+		t.Errorf("two blocks later, got confirmed %d, pending %d and immature %d", gotConfirmed, gotPending, gotImmature)
+	}
+}
+
+func TestCanAfford(t *testing.T) {
+	const currentHeight = 300
+
+	// Only the coins of 100 and 300 are spendable. The others are an immature coinbase, a coin without
+	// confirmations yet and a spent coin.
+	immature := newTestCoin(3, 1000, currentHeight-10)
+	immature.IsCoinbase = true
+	spent := newTestCoin(5, 10000, 1)
+	spent.Spent = true
+
+	w := NewWallet()
+	w.AddCoins([]*Coin{newTestCoin(1, 100, 10), newTestCoin(2, 300, currentHeight), immature, newTestCoin(4, 5000, currentHeight+1), spent})
+
+	tests := []struct {
+		name          string
+		amount        int64
+		feeEstimate   int64
+		wantOK        bool
+		wantShortfall int64
+	}{
+		{"affordable", 300, 50, true, 0},
+		{"exactly enough", 350, 50, true, 0},
+		{"short by the fee", 400, 50, false, 50},
+		{"short", 1000, 50, false, 650},
+	}
+	for _, test := range tests {
+		ok, shortfall := w.CanAfford(test.amount, test.feeEstimate, currentHeight)
+		if ok != test.wantOK || shortfall != test.wantShortfall {
+			t.Errorf("%s: got %v and shortfall %d, want %v and shortfall %d", test.name, ok, shortfall, test.wantOK, test.wantShortfall)
+		}
+	}
+}
+
+// expectValueIndex checks that CoinsSortedByValue holds exactly the unspent coins, largest first.
+func expectValueIndex(t *testing.T, w *Wallet, step string) {
+	t.Helper()
+
+	sorted := w.CoinsSortedByValue()
+	unspent := w.UnspentCoins()
+	if len(sorted) != len(unspent) {
+		t.Fatalf("%s: got %d coins by value, want the %d unspent coins", step, len(sorted), len(unspent))
+	}
+
+	indexed := make(map[*Coin]bool, len(sorted))
+	for i, coin := range sorted {
+		if coin.Spent || indexed[coin] {
+			t.Fatalf("%s: coin %s is spent or indexed twice", step, coin.ID)
+		}
+		if i > 0 && sorted[i-1].Value < coin.Value {
+			t.Fatalf("%s: coin of %d comes after a coin of %d", step, coin.Value, sorted[i-1].Value)
+		}
+		indexed[coin] = true
+	}
+	for _, coin := range unspent {
+		if !indexed[coin] {
+			t.Fatalf("%s: unspent coin %s is not indexed", step, coin.ID)
+		}
+	}
+}
+
+func TestValueIndexStaysConsistent(t *testing.T) {
+	// Values repeat, so that removals have to find the coin among others of the same value.
+	w := NewWallet()
+	coins := make([]*Coin, 0, 60)
+	for i := 0; i < 60; i++ {
+		coin := newTestCoin(uint8(i), int64(i*37%11+1)*100, int64(10+i%5))
+		coin.SerialNumber = Bytes{byte(i)}
+		coins = append(coins, coin)
+	}
+	w.AddCoins(coins[:40])
+	expectValueIndex(t, w, "add coins")
+
+	for i := 0; i < 40; i += 3 {
+		if !w.MarkSpent(Bytes{byte(i)}, Bytes{0xff}, 20+int64(i%2)) {
+			t.Fatalf("coin %d is not marked spent", i)
+		}
+	}
+	expectValueIndex(t, w, "mark spent")
+
+	for _, coin := range coins[40:] {
+		w.AddCoin(coin)
+	}
+	expectValueIndex(t, w, "add coin")
+
+	// Reverting height 20 restores the coins spent at it, and reverting height 12 drops the coins created at it.
+	w.RevertBlock(20)
+	expectValueIndex(t, w, "revert spends")
+	w.RevertBlock(12)
+	expectValueIndex(t, w, "revert coins")
+	if len(w.Coins()) != 48 {
+		t.Errorf("got %d coins after reverting height 12, want 48", len(w.Coins()))
+	}
+
+	if w.MarkSpent(Bytes{byte(57)}, Bytes{0xff}, 21) {
+		t.Errorf("the coin at reverted height 12 is marked spent")
+	}
+	if !w.MarkSpent(Bytes{byte(58)}, Bytes{0xff}, 21) {
+		t.Fatalf("coin 58 is not marked spent after the revert")
+	}
+	expectValueIndex(t, w, "mark spent after revert")
+}
+
+func TestAddCoinsSkipsDuplicates(t *testing.T) {
+	w := NewWallet()
+	if added := w.AddCoins([]*Coin{newTestCoin(1, 100, 10), newTestCoin(2, 200, 10)}); added != 2 {
+		t.Errorf("got %d coins added to an empty wallet, want 2", added)
+	}
+
+	// A rescan finds coins 1 and 2 again as new values, next to coin 3, which it also finds twice.
+	rescanned := []*Coin{newTestCoin(2, 200, 10), newTestCoin(3, 300, 11), newTestCoin(1, 100, 10), newTestCoin(3, 300, 11)}
+	if added := w.AddCoins(rescanned); added != 1 {
+		t.Errorf("got %d coins added by the overlapping set, want 1", added)
+	}
+	if added := w.AddCoins(rescanned); added != 0 {
+		t.Errorf("got %d coins added by the same set again, want 0", added)
+	}
+
+	coins := w.Coins()
+	if len(coins) != 3 || w.Balance() != 600 || len(w.CoinsSortedByValue()) != 3 {
+		t.Fatalf("got %d coins, %d indexed by value and a balance of %d, want 3 coins of 600", len(coins), len(w.CoinsSortedByValue()), w.Balance())
+	}
+	for i, coin := range coins {
+		for _, other := range coins[i+1:] {
+			if coin.Equal(other) {
+				t.Errorf("coin %s is held twice", coin.ID)
+			}
+		}
+	}
+
+	// Coins are equal by id alone, and another output of the same tx is another coin.
+	if !newTestCoin(1, 100, 10).Equal(newTestCoin(1, 999, 20)) {
+		t.Errorf("coins with the same id are not equal")
+	}
+	otherOutput := newTestCoin(1, 100, 10)
+	otherOutput.ID.Index = 2
+	if newTestCoin(1, 100, 10).Equal(otherOutput) {
+		t.Errorf("coins of different outputs of a tx are equal")
+	}
+	var nilCoin *Coin
+	if !nilCoin.Equal(nil) || nilCoin.Equal(newTestCoin(1, 100, 10)) {
+		t.Errorf("a nil coin is not equal only to nil")
+	}
+}
@@ -0,0 +1,390 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Define constants.
+const (
+	WEBSOCKET_PATH                = "/ws"
+	WEBSOCKET_MAX_MESSAGE_SIZE    = 1 << 20
+	WEBSOCKET_PING_INTERVAL       = 30 * time.Second
+	WEBSOCKET_RECONNECT_MIN_DELAY = time.Second
+	WEBSOCKET_RECONNECT_MAX_DELAY = time.Minute
+	NEW_BLOCK_CHANNEL_BUFFER      = 16
+	NEW_BLOCK_MAX_REORG_DEPTH     = 100
+)
+
+const (
+	wsBlockConnected  = "blockabeconnected"
+	wsNotifyBlocksCmd = "notifyblocks"
+)
+
+// Define the wsConn data type.
+// wsConn is a websocket connection to abec's /ws endpoint that pings the node every pingInterval. A connection
+// that receives nothing, not even a pong, for two intervals is taken for dead, so that a half-open connection
+// fails the pending read instead of blocking it forever.
+type wsConn struct {
+	conn         *websocket.Conn
+	pingInterval time.Duration
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// blockSubscription is the state of a SubscribeNewBlocks subscription. The hashes of the blocks pushed within
+// the last NEW_BLOCK_MAX_REORG_DEPTH heights are kept to find the fork height after a reorg.
+type blockSubscription struct {
+	client       *AbecRPCClient
+	blocks       chan<- *AbecBlock
+	lastHeight   int64
+	pushedHashes map[int64]string
+}
+
+type wsMessage struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Error  json.RawMessage   `json:"error"`
+}
+
+// Define methods for wsConn.
+func newWSConn(conn *websocket.Conn, pingInterval time.Duration) *wsConn {
+	ws := &wsConn{conn: conn, pingInterval: pingInterval, done: make(chan struct{})}
+	conn.SetReadLimit(WEBSOCKET_MAX_MESSAGE_SIZE)
+	ws.extendReadDeadline()
+	conn.SetPongHandler(func(string) error {
+		ws.extendReadDeadline()
+		return nil
+	})
+	go ws.keepAlive()
+
+	return ws
+}
+
+func (ws *wsConn) close() {
+	ws.closeOnce.Do(func() {
+		close(ws.done)
+		ws.conn.Close()
+	})
+}
+
+func (ws *wsConn) extendReadDeadline() {
+	ws.conn.SetReadDeadline(time.Now().Add(2 * ws.pingInterval))
+}
+
+func (ws *wsConn) keepAlive() {
+	ticker := time.NewTicker(ws.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.done:
+			return
+		case <-ticker.C:
+			// A failed ping is left to the read deadline, which fails the pending read.
+			err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(ws.pingInterval))
+			if err != nil {
+				LOG.debug("Cannot ping the websocket: %s\n", err)
+			}
+		}
+	}
+}
+
+func (ws *wsConn) writeMessage(message []byte) error {
+	return ws.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// readMessage returns the next data message. Pings are answered and pongs are handled while reading.
+func (ws *wsConn) readMessage() ([]byte, error) {
+	_, message, err := ws.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	ws.extendReadDeadline()
+
+	return message, nil
+}
+
+// Define methods for AbecRPCClient.
+// SubscribeNewBlocks pushes the blocks connected to the main chain from now on, using the block notifications
+// of the node's websocket endpoint. If the connection drops, or answers no ping for two ping intervals (see
+// WithWebSocketPingInterval), it reconnects with backoff and first pushes the blocks that were connected in the
+// meantime, so that no height is skipped. After a reorg, including one to a branch of the same or a lower
+// height, the blocks of the new branch are pushed again from the fork height, as long as it is within
+// NEW_BLOCK_MAX_REORG_DEPTH blocks. The channel is closed once ctx is done.
+func (client *AbecRPCClient) SubscribeNewBlocks(ctx context.Context) (<-chan *AbecBlock, error) {
+	_, tipHeight, err := client.GetBlockCountContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := client.connectBlockNotifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(chan *AbecBlock, NEW_BLOCK_CHANNEL_BUFFER)
+	sub := &blockSubscription{
+		client:       client,
+		blocks:       blocks,
+		lastHeight:   *tipHeight,
+		pushedHashes: make(map[int64]string),
+	}
+	go sub.run(ctx, ws)
+
+	return blocks, nil
+}
+
+func (client *AbecRPCClient) connectBlockNotifications(ctx context.Context) (*wsConn, error) {
+	ws, err := client.dialWebSocket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(client.newJSONRPCRequest(client.newRequestID(), wsNotifyBlocksCmd, nil))
+	if err != nil {
+		ws.close()
+		return nil, err
+	}
+	err = ws.writeMessage(request)
+	if err != nil {
+		ws.close()
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+func (client *AbecRPCClient) dialWebSocket(ctx context.Context) (*wsConn, error) {
+	endpoint, err := url.Parse(client.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %q is not a valid url: %s", client.endpoint, err)
+	}
+	wsURL := *endpoint
+	switch endpoint.Scheme {
+	case "http":
+		wsURL.Scheme = "ws"
+	case "https":
+		wsURL.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("endpoint scheme %q has no websocket counterpart", endpoint.Scheme)
+	}
+	wsURL.Path = WEBSOCKET_PATH
+	wsURL.RawQuery = ""
+
+	dialer, err := client.webSocketDialer()
+	if err != nil {
+		return nil, err
+	}
+	authReq := &http.Request{Header: make(http.Header)}
+	client.setAuth(authReq)
+
+	conn, resp, err := dialer.DialContext(ctx, wsURL.String(), authReq.Header)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return nil, fmt.Errorf("%w: websocket: %s", ErrUnauthorized, resp.Status)
+		}
+		if resp != nil {
+			return nil, fmt.Errorf("websocket upgrade of %s failed: %s", wsURL.Redacted(), resp.Status)
+		}
+		return nil, err
+	}
+
+	return newWSConn(conn, client.wsPingInterval), nil
+}
+
+// webSocketDialer returns a dialer with the proxy, dialer and TLS settings of the client's transport, so that
+// options like WithDialTimeout apply to the websocket too. The upgrade cannot go through a custom
+// round-tripper given by WithHTTPClient, so it is refused for one rather than made around it.
+func (client *AbecRPCClient) webSocketDialer() (*websocket.Dialer, error) {
+	transport := http.DefaultTransport.(*http.Transport)
+	switch roundTripper := client.httpClient.Transport.(type) {
+	case nil:
+	case *http.Transport:
+		transport = roundTripper
+	default:
+		return nil, fmt.Errorf("websocket cannot be dialed through a %T round-tripper", roundTripper)
+	}
+
+	return &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		NetDialContext:   transport.DialContext,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: client.httpClient.Timeout,
+	}, nil
+}
+
+// Define methods for blockSubscription.
+func (sub *blockSubscription) run(ctx context.Context, ws *wsConn) {
+	defer close(sub.blocks)
+
+	for {
+		err := sub.receive(ctx, ws)
+		ws.close()
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := WEBSOCKET_RECONNECT_MIN_DELAY
+		for {
+			LOG.debug("Block subscription dropped, reconnecting in %s: %s\n", delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.client.clock.After(delay):
+			}
+
+			ws, err = sub.client.connectBlockNotifications(ctx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			delay *= 2
+			if delay > WEBSOCKET_RECONNECT_MAX_DELAY {
+				delay = WEBSOCKET_RECONNECT_MAX_DELAY
+			}
+		}
+	}
+}
+
+// receive pushes the blocks of the main chain above the fork height up to the tip, then the blocks notified on
+// ws, until the connection fails.
+func (sub *blockSubscription) receive(ctx context.Context, ws *wsConn) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// Closing the connection unblocks the pending read.
+		select {
+		case <-ctx.Done():
+			ws.close()
+		case <-done:
+		}
+	}()
+
+	// The chain may have been reorganized while the subscription was not connected.
+	_, tipHeight, err := sub.client.GetBlockCountContext(ctx)
+	if err != nil {
+		return err
+	}
+	err = sub.rewindToFork(ctx, *tipHeight)
+	if err != nil {
+		return err
+	}
+	err = sub.pushBlocksUpTo(ctx, *tipHeight)
+	if err != nil {
+		return err
+	}
+
+	for {
+		data, err := ws.readMessage()
+		if err != nil {
+			return err
+		}
+
+		message := &wsMessage{}
+		if err := json.Unmarshal(data, message); err != nil {
+			LOG.debug("Ignored websocket message that is not json-rpc: %s\n", err)
+			continue
+		}
+		if isJSONRPCError(message.Error) {
+			return newJSONRPCError(wsNotifyBlocksCmd, message.Error)
+		}
+		if message.Method != wsBlockConnected || len(message.Params) < 2 {
+			continue
+		}
+
+		var hash string
+		var height int64
+		if json.Unmarshal(message.Params[0], &hash) != nil || json.Unmarshal(message.Params[1], &height) != nil {
+			LOG.debug("Ignored malformed %s notification\n", wsBlockConnected)
+			continue
+		}
+
+		_, block, err := sub.client.GetBlockContext(ctx, hash)
+		if err != nil {
+			return err
+		}
+		// A block that does not extend the last pushed one comes from a reorg. The blocks of the new branch
+		// below it, and the heights that were not notified, are fetched by height.
+		if height != sub.lastHeight+1 || block.PrevBlockHash != sub.pushedHashes[sub.lastHeight] {
+			err = sub.rewindToFork(ctx, height-1)
+			if err != nil {
+				return err
+			}
+		}
+		err = sub.pushBlocksUpTo(ctx, height-1)
+		if err != nil {
+			return err
+		}
+		err = sub.pushBlock(ctx, block)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rewindToFork moves lastHeight back to the highest pushed block at or below height that is still in the main
+// chain, so that the blocks of a new branch are pushed again from the fork height. Blocks older than the
+// pushed hashes that are kept are taken to be in the main chain.
+func (sub *blockSubscription) rewindToFork(ctx context.Context, height int64) error {
+	for sub.lastHeight > height {
+		delete(sub.pushedHashes, sub.lastHeight)
+		sub.lastHeight--
+	}
+
+	for {
+		pushedHash, ok := sub.pushedHashes[sub.lastHeight]
+		if !ok {
+			return nil
+		}
+
+		_, hash, err := sub.client.GetBlockHashContext(ctx, sub.lastHeight)
+		if err != nil {
+			return err
+		}
+		if *hash == pushedHash {
+			return nil
+		}
+
+		LOG.debug("Block %s at height %d left the main chain\n", pushedHash, sub.lastHeight)
+		delete(sub.pushedHashes, sub.lastHeight)
+		sub.lastHeight--
+	}
+}
+
+func (sub *blockSubscription) pushBlocksUpTo(ctx context.Context, height int64) error {
+	for sub.lastHeight < height {
+		_, block, err := sub.client.GetBlockByHeightContext(ctx, sub.lastHeight+1)
+		if err != nil {
+			return err
+		}
+		err = sub.pushBlock(ctx, block)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sub *blockSubscription) pushBlock(ctx context.Context, block *AbecBlock) error {
+	select {
+	case sub.blocks <- block:
+		sub.lastHeight = block.Height
+		sub.pushedHashes[block.Height] = block.BlockHash
+		delete(sub.pushedHashes, block.Height-NEW_BLOCK_MAX_REORG_DEPTH)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
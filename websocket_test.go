@@ -0,0 +1,354 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testWSNode is a node with a JSON-RPC endpoint for block lookups and a websocket endpoint for block
+// notifications, whose main chain the test can reorganize.
+type testWSNode struct {
+	t        *testing.T
+	mutex    sync.Mutex
+	hashes   []string
+	blocks   map[string]*AbecBlock
+	ws       *websocket.Conn
+	upgrades int
+	refuse   int
+	// silent makes the node stop reading after the notification request, so that it answers no pings.
+	silent bool
+	server *httptest.Server
+}
+
+func newTestWSNode(t *testing.T, labels ...string) *testWSNode {
+	t.Helper()
+
+	node := &testWSNode{t: t, blocks: make(map[string]*AbecBlock)}
+	node.setChain(labels...)
+	node.server = httptest.NewServer(node)
+	t.Cleanup(func() {
+		node.dropConnection()
+		node.server.Close()
+	})
+
+	return node
+}
+
+func testBlockHash(label string) string {
+	return strings.Repeat(label, 32)
+}
+
+// setChain makes the blocks with the given labels, one per height from 0, the main chain.
+func (node *testWSNode) setChain(labels ...string) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.hashes = node.hashes[:0]
+	for height, label := range labels {
+		hash := testBlockHash(label)
+		block := &AbecBlock{Height: int64(height), BlockHash: hash}
+		if height > 0 {
+			block.PrevBlockHash = node.hashes[height-1]
+		}
+		node.blocks[hash] = block
+		node.hashes = append(node.hashes, hash)
+	}
+}
+
+func (node *testWSNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == WEBSOCKET_PATH {
+		node.serveWebSocket(w, r)
+		return
+	}
+
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+		ID     string            `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	resp := map[string]interface{}{"id": req.ID, "result": nil, "error": nil}
+	switch req.Method {
+	case "getblockcount":
+		resp["result"] = len(node.hashes) - 1
+	case "getblockhash":
+		var height int
+		json.Unmarshal(req.Params[0], &height)
+		if height < 0 || height >= len(node.hashes) {
+			resp["error"] = &AbecJSONRPCError{Code: RPC_ERROR_OUT_OF_RANGE, Message: "Block number out of range"}
+		} else {
+			resp["result"] = node.hashes[height]
+		}
+	case "getblockabe":
+		var hash string
+		json.Unmarshal(req.Params[0], &hash)
+		if block, ok := node.blocks[hash]; ok {
+			resp["result"] = block
+		} else {
+			resp["error"] = &AbecJSONRPCError{Code: -5, Message: "Block not found"}
+		}
+	default:
+		resp["error"] = &AbecJSONRPCError{Code: -32601, Message: "Method not found"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (node *testWSNode) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	node.mutex.Lock()
+	node.upgrades++
+	if node.refuse > 0 {
+		node.refuse--
+		node.mutex.Unlock()
+		http.Error(w, "node is starting", http.StatusServiceUnavailable)
+		return
+	}
+	node.mutex.Unlock()
+
+	ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		node.t.Errorf("cannot upgrade the connection: %s", err)
+		return
+	}
+	if _, _, err := ws.ReadMessage(); err != nil {
+		ws.Close()
+		return
+	}
+
+	node.mutex.Lock()
+	node.ws = ws
+	silent := node.silent
+	node.mutex.Unlock()
+
+	// Pings are only answered while reading.
+	for !silent {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// notify sends a block connected notification for the block with the given label.
+func (node *testWSNode) notify(label string) {
+	node.t.Helper()
+
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	block := node.blocks[testBlockHash(label)]
+	payload := fmt.Sprintf(`{"jsonrpc":"1.0","method":"%s","params":["%s",%d,0]}`, wsBlockConnected, block.BlockHash, block.Height)
+	if err := node.ws.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+		node.t.Fatalf("cannot send the notification: %s", err)
+	}
+}
+
+func (node *testWSNode) dropConnection() {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if node.ws != nil {
+		node.ws.Close()
+		node.ws = nil
+	}
+}
+
+func (node *testWSNode) upgradeCount() int {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	return node.upgrades
+}
+
+// waitFor polls condition until it holds or the test times out.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectBlock(t *testing.T, blocks <-chan *AbecBlock, label string, height int64) {
+	t.Helper()
+
+	select {
+	case block, ok := <-blocks:
+		if !ok {
+			t.Fatalf("subscription closed, want block %s", label)
+		}
+		if block.BlockHash != testBlockHash(label) || block.Height != height {
+			t.Fatalf("got block %s at height %d, want block %s at height %d", block.BlockHash[:2], block.Height, label, height)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for block %s", label)
+	}
+}
+
+func TestSubscribeNewBlocksReconnectsAndFollowsReorgs(t *testing.T) {
+	node := newTestWSNode(t, "a0")
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	client := NewAbecRPCClient(node.server.URL, "", "", WithClock(clock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocks, err := client.SubscribeNewBlocks(ctx)
+	if err != nil {
+		t.Fatalf("cannot subscribe: %s", err)
+	}
+	waitFor(t, "the notification request", func() bool {
+		node.mutex.Lock()
+		defer node.mutex.Unlock()
+		return node.ws != nil
+	})
+
+	// The heights that were not notified are fetched by height.
+	node.setChain("a0", "a1", "a2", "a3")
+	node.notify("a3")
+	expectBlock(t, blocks, "a1", 1)
+	expectBlock(t, blocks, "a2", 2)
+	expectBlock(t, blocks, "a3", 3)
+
+	// While the subscription is disconnected, a3 is replaced by b3 at the same height.
+	node.mutex.Lock()
+	node.refuse = 1
+	node.mutex.Unlock()
+	node.dropConnection()
+	node.setChain("a0", "a1", "a2", "b3")
+
+	// The first reconnect after 1s is refused, and the next one is tried after 2s.
+	waitFor(t, "the first reconnect delay", func() bool { return clock.Waiters() == 1 })
+	clock.Advance(WEBSOCKET_RECONNECT_MIN_DELAY)
+	waitFor(t, "the second reconnect delay", func() bool { return node.upgradeCount() == 2 && clock.Waiters() == 1 })
+	clock.Advance(WEBSOCKET_RECONNECT_MIN_DELAY)
+	time.Sleep(10 * time.Millisecond)
+	if node.upgradeCount() != 2 {
+		t.Fatalf("reconnected after %s, want the delay to double", WEBSOCKET_RECONNECT_MIN_DELAY)
+	}
+	clock.Advance(WEBSOCKET_RECONNECT_MIN_DELAY)
+	expectBlock(t, blocks, "b3", 3)
+
+	// A reorg to a shorter branch that forks below the notified block is pushed from the fork height.
+	waitFor(t, "the reconnection", func() bool {
+		node.mutex.Lock()
+		defer node.mutex.Unlock()
+		return node.ws != nil
+	})
+	node.setChain("a0", "c1", "c2")
+	node.notify("c2")
+	expectBlock(t, blocks, "c1", 1)
+	expectBlock(t, blocks, "c2", 2)
+
+	cancel()
+	for range blocks {
+	}
+}
+
+// waitForConnection waits until the node has received the notification request of a connection.
+func (node *testWSNode) waitForConnection(t *testing.T) {
+	t.Helper()
+
+	waitFor(t, "the notification request", func() bool {
+		node.mutex.Lock()
+		defer node.mutex.Unlock()
+		return node.ws != nil
+	})
+}
+
+func TestSubscribeNewBlocksReconnectsHalfOpenConnections(t *testing.T) {
+	node := newTestWSNode(t, "a0")
+	node.silent = true
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	client := NewAbecRPCClient(node.server.URL, "", "", WithClock(clock), WithWebSocketPingInterval(20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocks, err := client.SubscribeNewBlocks(ctx)
+	if err != nil {
+		t.Fatalf("cannot subscribe: %s", err)
+	}
+	node.waitForConnection(t)
+
+	// The node keeps the connection open, but answers no pings, so the subscription drops it and reconnects.
+	waitFor(t, "the reconnect delay", func() bool { return clock.Waiters() == 1 })
+	node.mutex.Lock()
+	node.silent = false
+	node.ws = nil
+	node.mutex.Unlock()
+	clock.Advance(WEBSOCKET_RECONNECT_MIN_DELAY)
+	node.waitForConnection(t)
+	if node.upgradeCount() != 2 {
+		t.Fatalf("got %d connections, want 2", node.upgradeCount())
+	}
+
+	// The new connection answers pings, so it is kept.
+	time.Sleep(100 * time.Millisecond)
+	if clock.Waiters() != 0 || node.upgradeCount() != 2 {
+		t.Fatalf("dropped a connection that answers pings")
+	}
+	node.setChain("a0", "a1")
+	node.notify("a1")
+	expectBlock(t, blocks, "a1", 1)
+
+	cancel()
+	for range blocks {
+	}
+}
+
+func TestSubscribeNewBlocksUsesTheClientTransport(t *testing.T) {
+	node := newTestWSNode(t, "a0")
+
+	var dialsMutex sync.Mutex
+	dials := 0
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialsMutex.Lock()
+		dials++
+		dialsMutex.Unlock()
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}}
+	// Keep-alive is disabled so that every request, and the websocket, dial a new connection.
+	transport.DisableKeepAlives = true
+	client := NewAbecRPCClient(node.server.URL, "", "", WithHTTPClient(&http.Client{Transport: transport}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocks, err := client.SubscribeNewBlocks(ctx)
+	if err != nil {
+		t.Fatalf("cannot subscribe: %s", err)
+	}
+	node.waitForConnection(t)
+	dialsMutex.Lock()
+	if dials < 2 {
+		t.Errorf("got %d dials through the transport, want the getblockcount and websocket dials", dials)
+	}
+	dialsMutex.Unlock()
+	cancel()
+	for range blocks {
+	}
+
+	// A custom round-tripper cannot carry the upgrade, so the subscription is refused.
+	client = NewAbecRPCClient(node.server.URL, "", "", WithHTTPClient(&http.Client{Transport: &countingRoundTripper{}}))
+	_, err = client.SubscribeNewBlocks(context.Background())
+	if err == nil {
+		t.Errorf("got no error for a custom round-tripper")
+	}
+}